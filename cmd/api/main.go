@@ -4,19 +4,44 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/sudo-sturbia/bfr/v2/internal/api"
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+	"github.com/sudo-sturbia/bfr/v2/internal/certs"
 	"github.com/sudo-sturbia/bfr/v2/internal/config"
 	"github.com/sudo-sturbia/bfr/v2/internal/datastore"
+	"github.com/sudo-sturbia/bfr/v2/internal/graceful"
 )
 
+// version is the version of the build, set using
+// -ldflags "-X main.version=<version>".
+var version = "dev"
+
 var (
 	port    = flag.String("port", "6060", "Specify a port to run the server on.")
 	dataset = flag.String("dataset", "", "Load a new csv dataset from specified path.")
+	private = flag.Bool("private", false, "Require an API key for searching.")
+	rate    = flag.Float64("rate", 0, "Requests per second allowed per client, requests aren't limited if 0.")
+	burst   = flag.Int("burst", 20, "Maximum number of requests a client can make at once.")
+	origins = flag.String("cors", "", "Comma separated origins allowed to make cross-origin requests, or * for any.")
+
+	cert     = flag.String("cert", "", "Serve HTTPS using the PEM encoded certificate in given file.")
+	key      = flag.String("key", "", "Path of the certificate's PEM encoded private key.")
+	clientCA = flag.String("client-ca", "", "Require clients to present certificates signed by a CA in given PEM file.")
+	redirect = flag.String("redirect", "", "Redirect plain HTTP requests on given port to HTTPS.")
+
+	deprecated = make(deprecations)
 )
 
 func main() {
+	flag.Var(deprecated, "deprecate", "Deprecate a version of the API, as version=since[/sunset], e.g. v1=2026-10-01/2027-04-01.")
 	flag.Usage = usage
 	flag.Parse()
 
@@ -33,18 +58,162 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
+	keys, err := auth.New(datastore, cfg.Datastore.KeyTable)
+	if err != nil {
+		log.Fatalf("Failed to load API keys: %s.", err.Error())
+	}
+
+	if flag.Arg(0) == "keys" {
+		if err := manageKeys(keys, flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s.\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	var cors *api.CORS
+	if *origins != "" {
+		cors = &api.CORS{
+			Origins: strings.Split(*origins, ","),
+			MaxAge:  cfg.CORSMaxAge,
+		}
+	}
+
+	var tls *certs.ServerConfig
+	if *cert != "" {
+		tls = &certs.ServerConfig{
+			CertFile:     *cert,
+			KeyFile:      *key,
+			ClientCAFile: *clientCA,
+			RedirectPort: *redirect,
+		}
+	}
+
 	server := api.New(
 		&api.Config{
 			Host: cfg.Host,
 			Port: cfg.Port,
+
+			Version: version,
+
+			ReadTimeout:     cfg.ReadTimeout,
+			WriteTimeout:    cfg.WriteTimeout,
+			IdleTimeout:     cfg.IdleTimeout,
+			ShutdownTimeout: cfg.ShutdownTimeout,
+
+			TLS: tls,
+
+			Keys:         keys,
+			PrivateReads: *private,
+
+			CORS: cors,
+
+			Deprecations: deprecated,
+
+			RateLimit: *rate,
+			RateBurst: *burst,
+
+			CacheMaxAge: cfg.CacheMaxAge,
+
+			SearchCacheSize: cfg.SearchCacheSize,
+			SearchCacheTTL:  cfg.SearchCacheTTL,
+
+			GraphQLMaxDepth:      cfg.GraphQLMaxDepth,
+			GraphQLMaxComplexity: cfg.GraphQLMaxComplexity,
 		},
 		&api.SearchIn{
-			Datastore: datastore,
-			BookTable: cfg.Datastore.BookTable,
+			Datastore:     datastore,
+			BookTable:     cfg.Datastore.BookTable,
+			MetadataTable: cfg.Datastore.MetadataTable,
 		},
 	)
 
-	server.Run()
+	ctx, stop := graceful.SignalContext()
+	err = server.Run(ctx)
+	stop()
+	datastore.Close()
+	if err != nil {
+		log.Fatalf("Server failed: %s.", err.Error())
+	}
+}
+
+// manageKeys runs a keys subcommand with given arguments.
+func manageKeys(keys *auth.Keys, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected one of add, list, or revoke")
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: keys add <name> <%s|%s>", auth.ReadOnly, auth.Admin)
+		}
+
+		role, err := auth.ParseRole(args[2])
+		if err != nil {
+			return err
+		}
+
+		secret, key, err := keys.Add(args[1], role)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added %s key %d for %s. Store it now, it can't be shown again:\n%s\n", key.Role, key.ID, key.Name, secret)
+	case "list":
+		list, err := keys.List()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tROLE\tCREATED")
+		for _, key := range list {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", key.ID, key.Name, key.Role, key.Created.Format(time.RFC3339))
+		}
+		return w.Flush()
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: keys revoke <id>")
+		}
+
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid id %q", args[1])
+		}
+
+		if err := keys.Revoke(id); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked key %d.\n", id)
+	default:
+		return fmt.Errorf("unknown keys command %q", args[0])
+	}
+
+	return nil
+}
+
+// deprecations is a flag of deprecated versions of the API, which can be
+// specified multiple times.
+type deprecations map[string]*api.Deprecation
+
+// String returns the deprecated versions.
+func (d deprecations) String() string {
+	versions := make([]string, 0, len(d))
+	for version := range d {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ",")
+}
+
+// Set adds a deprecation of the form version=since[/sunset].
+func (d deprecations) Set(s string) error {
+	version, deprecation, err := api.ParseDeprecation(s)
+	if err != nil {
+		return err
+	}
+	d[version] = deprecation
+	return nil
 }
 
 // usage prints a help message.
@@ -52,10 +221,20 @@ func usage() {
 	fmt.Println(
 		"A REST API that enables searching for books using a set of parameters.\n",
 		"Usage:\n",
-		"    go run ./cmd/api                 Run a backend server at localhost:6060.\n",
-		"    go run ./cmd/api -dataset path   Load a new csv dataset to use as a datastore, then run the server.\n",
-		"    go run ./cmd/api -port <number>  Use the specified port to run the server.\n",
-		"    go run ./cmd/api -h              Print a help message.\n",
+		"    go run ./cmd/api                           Run a backend server at localhost:6060.\n",
+		"    go run ./cmd/api -dataset path             Load a new csv dataset to use as a datastore, then run the server.\n",
+		"    go run ./cmd/api -port <number>            Use the specified port to run the server.\n",
+		"    go run ./cmd/api -private                  Require an API key for searching, not only for writing.\n",
+		"    go run ./cmd/api -rate <n> -burst <n>      Allow n requests per second, and n at once, per client (default off, 20).\n",
+		"    go run ./cmd/api -cors <origins>           Allow browsers to call the API from comma separated origins, or * for any.\n",
+		"    go run ./cmd/api -cert <file> -key <file>  Serve HTTPS using given certificate and key, reloaded on SIGHUP.\n",
+		"    go run ./cmd/api -client-ca <file>         Require clients to present certificates signed by given CAs.\n",
+		"    go run ./cmd/api -redirect <port>          Redirect plain HTTP requests on given port to HTTPS.\n",
+		"    go run ./cmd/api -deprecate <v>=<since>    Deprecate version v since a date, optionally followed by /<sunset date>.\n",
+		"    go run ./cmd/api keys add <name> <role>    Create an API key with role read-only or admin.\n",
+		"    go run ./cmd/api keys list                 List API keys.\n",
+		"    go run ./cmd/api keys revoke <id>          Revoke an API key.\n",
+		"    go run ./cmd/api -h                        Print a help message.\n",
 		"\n",
 		"See github.com/sudo-sturbia/bfr.",
 	)
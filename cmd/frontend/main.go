@@ -5,15 +5,26 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 
+	"github.com/sudo-sturbia/bfr/v2/internal/certs"
 	"github.com/sudo-sturbia/bfr/v2/internal/config"
 	"github.com/sudo-sturbia/bfr/v2/internal/frontend"
+	"github.com/sudo-sturbia/bfr/v2/internal/graceful"
 )
 
 var (
 	api    = flag.String("api", "http://localhost:6060", "URL to use for API calls.")
+	token  = flag.String("api-token", os.Getenv("BFR_API_KEY"), "API key sent with requests to the API, $BFR_API_KEY by default.")
 	port   = flag.String("port", "5050", "Port number to run the server on.")
 	static = flag.String("static", "content/static", "Path to static files.")
+
+	cert     = flag.String("cert", "", "Serve HTTPS using the PEM encoded certificate in given file.")
+	key      = flag.String("key", "", "Path of the certificate's PEM encoded private key.")
+	redirect = flag.String("redirect", "", "Redirect plain HTTP requests on given port to HTTPS.")
+	apiCA    = flag.String("api-ca", "", "Trust API certificates signed by a CA in given PEM file.")
+	apiCert  = flag.String("api-cert", "", "Present the PEM encoded certificate in given file to the API.")
+	apiKey   = flag.String("api-key", "", "Path of the PEM encoded private key of the certificate presented to the API.")
 )
 
 func main() {
@@ -21,11 +32,42 @@ func main() {
 	flag.Parse()
 
 	cfg := config.NewOnPort(*port)
+
+	var tls *certs.ServerConfig
+	if *cert != "" {
+		tls = &certs.ServerConfig{
+			CertFile:     *cert,
+			KeyFile:      *key,
+			RedirectPort: *redirect,
+		}
+	}
+	var apiTLS *certs.ClientConfig
+	if *apiCA != "" || *apiCert != "" {
+		apiTLS = &certs.ClientConfig{
+			CAFile:   *apiCA,
+			CertFile: *apiCert,
+			KeyFile:  *apiKey,
+		}
+	}
+
 	server, err := frontend.New(
 		&frontend.Config{
 			Host:   cfg.Host,
 			Port:   cfg.Port,
 			Static: *static,
+
+			ReadTimeout:     cfg.ReadTimeout,
+			WriteTimeout:    cfg.WriteTimeout,
+			IdleTimeout:     cfg.IdleTimeout,
+			ShutdownTimeout: cfg.ShutdownTimeout,
+
+			TLS:    tls,
+			APITLS: apiTLS,
+			APIKey: *token,
+
+			APITimeout: cfg.APITimeout,
+			APIRetries: cfg.APIRetries,
+			APIBackoff: cfg.APIBackoff,
 		},
 		*api,
 	)
@@ -33,7 +75,11 @@ func main() {
 		log.Fatalf("failed to create server: %s", err.Error())
 	}
 
-	server.Run()
+	ctx, stop := graceful.SignalContext()
+	defer stop()
+	if err := server.Run(ctx); err != nil {
+		log.Fatalf("server failed: %s", err.Error())
+	}
 }
 
 // usage prints a help message.
@@ -41,11 +87,16 @@ func usage() {
 	fmt.Println(
 		"A frontend web server that utilizes bfr API to search for and find books.\n",
 		"Usage:\n",
-		"    go run ./cmd/frontend                 Run a frontend server at localhost:5050.\n",
-		"    go run ./cmd/frontend -api <url>      Use given URL for API calls, default is localhost:6060.\n",
-		"    go run ./cmd/frontend -port <number>  Run server at specified port.\n",
-		"    go run ./cmd/frontend -static <path>  Use given path for static files.\n",
-		"    go run ./cmd/frontend -h              Print this help message.\n",
+		"    go run ./cmd/frontend                                   Run a frontend server at localhost:5050.\n",
+		"    go run ./cmd/frontend -api <url>                        Use given URL for API calls, default is localhost:6060.\n",
+		"    go run ./cmd/frontend -api-token <key>                  Send given API key with API calls, default is $BFR_API_KEY.\n",
+		"    go run ./cmd/frontend -port <number>                    Run server at specified port.\n",
+		"    go run ./cmd/frontend -static <path>                    Use given path for static files.\n",
+		"    go run ./cmd/frontend -cert <file> -key <file>          Serve HTTPS using given certificate and key, reloaded on SIGHUP.\n",
+		"    go run ./cmd/frontend -redirect <port>                  Redirect plain HTTP requests on given port to HTTPS.\n",
+		"    go run ./cmd/frontend -api-ca <file>                    Trust API certificates signed by given CAs.\n",
+		"    go run ./cmd/frontend -api-cert <file> -api-key <file>  Present given certificate to the API.\n",
+		"    go run ./cmd/frontend -h                                Print this help message.\n",
 		"\n",
 		"See github.com/sudo-sturbia/bfr.",
 	)
@@ -0,0 +1,53 @@
+/*
+ * Suggestions for the search form's title and author fields. The form works
+ * without this script, which only adds a list of completions to the fields.
+ */
+(function () {
+    "use strict";
+
+    var delay = 200; // Milliseconds to wait after typing before fetching.
+    var limit = 8;   // Number of suggestions to show.
+
+    // attach adds suggestions of the given field ("title" or "author") to input.
+    function attach(input, field) {
+        if (!input || !window.fetch) {
+            return;
+        }
+
+        var list = document.createElement("datalist");
+        list.id = input.name + "-suggestions";
+        input.parentNode.appendChild(list);
+        input.setAttribute("list", list.id);
+        input.setAttribute("autocomplete", "off");
+
+        var timer = null;
+        input.addEventListener("input", function () {
+            clearTimeout(timer);
+            var prefix = input.value.trim();
+            if (prefix.length < 2) {
+                list.innerHTML = "";
+                return;
+            }
+
+            timer = setTimeout(function () {
+                var url = "/suggest?field=" + field + "&limit=" + limit +
+                    "&prefix=" + encodeURIComponent(prefix);
+                fetch(url).then(function (response) {
+                    return response.ok ? response.json() : [];
+                }).then(function (suggestions) {
+                    list.innerHTML = "";
+                    suggestions.forEach(function (suggestion) {
+                        var option = document.createElement("option");
+                        option.value = suggestion.Text;
+                        list.appendChild(option);
+                    });
+                }).catch(function () {
+                    list.innerHTML = "";
+                });
+            }, delay);
+        });
+    }
+
+    attach(document.querySelector("input[name=TitleHas]"), "title");
+    attach(document.querySelector("input[name=Authors]"), "author");
+})();
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+)
+
+// authorize wraps a handler so that it only runs for requests authenticated
+// with an API key that has the required role. The key is read from either an
+// "Authorization: Bearer" or an "X-API-Key" header, and is added to the
+// request's context.
+// Read-only requests don't require a key unless the server is configured with
+// PrivateReads. If the server has no keys, read-only requests are allowed and
+// all others are rejected. If requests are rate limited, addresses that fail
+// to authenticate too often are rejected before their keys are looked up.
+func (s *Server) authorize(required auth.Role, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg == nil || s.cfg.Keys == nil {
+			if required != auth.ReadOnly {
+				writeError(w, r, "Writes are disabled.", http.StatusForbidden)
+				return
+			}
+			handler(w, r)
+			return
+		}
+
+		secret := requestKey(r)
+		if secret == "" && required == auth.ReadOnly && !s.cfg.PrivateReads {
+			handler(w, r)
+			return
+		}
+
+		if s.failures != nil {
+			if ok, wait := s.failures.available(address(r)); !ok {
+				tooManyRequests(w, r, wait)
+				return
+			}
+		}
+
+		if secret == "" {
+			s.unauthorized(w, r, "An API key is required.")
+			return
+		}
+
+		key, err := s.cfg.Keys.Authenticate(secret)
+		switch {
+		case err == auth.ErrInvalidKey:
+			s.unauthorized(w, r, "Invalid API key.")
+			return
+		case err != nil:
+			writeError(w, r, "Authentication failed.", http.StatusInternalServerError)
+			return
+		case !key.Role.Allows(required):
+			writeError(w, r, "API key isn't allowed to perform this request.", http.StatusForbidden)
+			return
+		}
+
+		handler(w, r.WithContext(auth.NewContext(r.Context(), key)))
+	}
+}
+
+// unauthorized rejects a request that failed to authenticate, which takes a
+// token from the failed authentications' bucket of its address, so that
+// clients that keep failing, e.g. guessing keys, are rejected before their
+// keys are looked up.
+func (s *Server) unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	if s.failures != nil {
+		s.failures.take(address(r))
+	}
+
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeError(w, r, message, http.StatusUnauthorized)
+}
+
+// requestKey returns the API key carried by a request, or an empty string.
+func requestKey(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestAuthorize tests authenticating requests with API keys of different roles.
+func TestAuthorize(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	keys, err := auth.New(datastore, "keys")
+	if err != nil {
+		t.Fatalf("failed to create keys: %s", err.Error())
+	}
+	reader, _, err := keys.Add("reader", auth.ReadOnly)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+	admin, revoked := addAdminKeys(t, keys)
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+	public := New(&Config{Keys: keys}, searchIn)
+	private := New(&Config{Keys: keys, PrivateReads: true}, searchIn)
+	disabled := New(nil, searchIn)
+
+	for i, test := range []struct {
+		server *Server
+		method string
+		url    string
+		header string
+		key    string
+		status int
+	}{
+		{server: public, method: "GET", url: "/book/1", status: http.StatusOK},
+		{server: public, method: "GET", url: "/book/1", header: "X-API-Key", key: "invalid", status: http.StatusUnauthorized},
+		{server: private, method: "GET", url: "/book/1", status: http.StatusUnauthorized},
+		{server: private, method: "GET", url: "/book/1", header: "Authorization", key: reader, status: http.StatusOK},
+		{server: private, method: "GET", url: "/book/1", header: "X-API-Key", key: reader, status: http.StatusOK},
+		{server: private, method: "GET", url: "/book/1", header: "X-API-Key", key: admin, status: http.StatusOK},
+		{server: private, method: "GET", url: "/book/1", header: "X-API-Key", key: revoked, status: http.StatusUnauthorized},
+		{server: public, method: "GET", url: "/graphql/schema", status: http.StatusOK},
+		{server: private, method: "GET", url: "/graphql/schema", status: http.StatusUnauthorized},
+		{server: private, method: "GET", url: "/graphql/schema", header: "X-API-Key", key: reader, status: http.StatusOK},
+		{server: public, method: "DELETE", url: "/book/2", status: http.StatusUnauthorized},
+		{server: public, method: "DELETE", url: "/book/2", header: "Authorization", key: reader, status: http.StatusForbidden},
+		{server: public, method: "DELETE", url: "/book/2", header: "Authorization", key: admin, status: http.StatusNoContent},
+		{server: disabled, method: "GET", url: "/book/1", status: http.StatusOK},
+		{server: disabled, method: "DELETE", url: "/book/1", header: "Authorization", key: admin, status: http.StatusForbidden},
+	} {
+		t.Run(
+			fmt.Sprintf("test: %d", i),
+			func(t *testing.T) {
+				request, err := http.NewRequest(test.method, test.url, nil)
+				if err != nil {
+					t.Fatalf("failed to create request: %s", err.Error())
+				}
+
+				switch test.header {
+				case "Authorization":
+					request.Header.Set("Authorization", "Bearer "+test.key)
+				case "X-API-Key":
+					request.Header.Set("X-API-Key", test.key)
+				}
+
+				recorder := serveRecorded(test.server, request)
+				if recorder.Code != test.status {
+					t.Errorf("expected status: %d, got: %d, body: %s", test.status, recorder.Code, recorder.Body.String())
+				}
+			},
+		)
+	}
+}
+
+// addAdminKeys adds two admin keys, revokes the second, and returns both.
+func addAdminKeys(t *testing.T, keys *auth.Keys) (string, string) {
+	t.Helper()
+	admin, _, err := keys.Add("admin", auth.Admin)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+
+	revoked, key, err := keys.Add("revoked", auth.Admin)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+	if err := keys.Revoke(key.ID); err != nil {
+		t.Fatalf("failed to revoke key: %s", err.Error())
+	}
+	return admin, revoked
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// batchRequest is the body of POST /books/batch.
+type batchRequest struct {
+	IDs   []int    // IDs of books to look up.
+	ISBNs []string // 10 or 13 digit ISBNs of books to look up.
+}
+
+// batch is a handler for POST /books/batch endpoint.
+func (s *Server) batch(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := batchResponse(http.MaxBytesReader(w, r.Body, maxBodySize), s.searchIn)
+	if ok {
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// batchResponse looks up books with IDs and ISBNs given in body and returns a
+// response, a status code, and bool indicating if the operation was performed
+// successfully. It should be used by Server.batch.
+func batchResponse(body io.Reader, searchIn *SearchIn) (interface{}, int, bool) {
+	request := new(batchRequest)
+	if err := decodeJSON(body, request); err != nil {
+		return fmt.Sprintf("Unable to decode batch: %s.", err.Error()), http.StatusBadRequest, false
+	}
+
+	batch, err := books.BatchGet(searchIn.booksIn(), request.IDs, request.ISBNs)
+	switch {
+	case err == books.ErrBatchTooLarge:
+		return fmt.Sprintf("Invalid batch: %s.", err.Error()), http.StatusBadRequest, false
+	case err != nil:
+		return "Search failed.", http.StatusBadRequest, false
+	}
+	return batch, http.StatusOK, true
+}
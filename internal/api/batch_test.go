@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// TestBatch tests looking up books by IDs and ISBNs.
+func TestBatch(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	book, err := books.SearchByID(server.searchIn.booksIn(), 5)
+	if err != nil {
+		t.Fatalf("failed to find book: %s", err.Error())
+	}
+
+	encoded, _ := json.MarshalIndent(book, "", "\t")
+	tooMany := strings.TrimSuffix(strings.Repeat("1, ", books.MaxBatch+1), ", ")
+	for _, test := range []struct {
+		body     string
+		response string
+		status   int
+	}{
+		{
+			body:     `{}`,
+			response: "{\n\t\"Books\": [],\n\t\"MissingIDs\": [],\n\t\"MissingISBNs\": []\n}",
+			status:   http.StatusOK,
+		},
+		{
+			body: `{"IDs": [5, 40], "ISBNs": ["043965548X", "0000000000"]}`,
+			response: fmt.Sprintf(
+				"{\n\t\"Books\": [\n\t\t%s\n\t],\n\t\"MissingIDs\": [\n\t\t40\n\t],\n\t\"MissingISBNs\": [\n\t\t\"0000000000\"\n\t]\n}",
+				strings.ReplaceAll(string(encoded), "\n", "\n\t\t"),
+			),
+			status: http.StatusOK,
+		},
+		{
+			body:     `{"IDs": [` + tooMany + `]}`,
+			response: fmt.Sprintf("Invalid batch: can't look up more than %d books at once.\n", books.MaxBatch),
+			status:   http.StatusBadRequest,
+		},
+		{
+			body:     `{"IDs": [5`,
+			response: "Unable to decode batch: unexpected EOF.\n",
+			status:   http.StatusBadRequest,
+		},
+		{
+			body:     `{"Titles": ["Emma"]}`,
+			response: "Unable to decode batch: json: unknown field \"Titles\".\n",
+			status:   http.StatusBadRequest,
+		},
+	} {
+		recorder := serveRequest(t, server, "POST", "/books/batch", "", test.body)
+		if recorder.Code != test.status || recorder.Body.String() != test.response {
+			t.Errorf("%.40s: expected: %d %s, got: %d %s", test.body, test.status, test.response, recorder.Code, recorder.Body.String())
+		}
+	}
+}
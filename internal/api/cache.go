@@ -0,0 +1,143 @@
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/datastore"
+)
+
+// cache wraps a read route's handler so that its responses can be cached by
+// clients and shared caches. Successful responses are sent with an ETag, which
+// identifies the dataset's version and the request, the time books were last
+// modified, and a Cache-Control header. Responses vary by Accept header, and
+// by API key if reads are private. Conditional requests for a response
+// that is still current are answered with 304 Not Modified without searching.
+func (s *Server) cache(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept") // Responses are negotiated, see negotiate.
+		if s.cfg != nil && s.cfg.PrivateReads {
+			w.Header().Add("Vary", "Authorization, X-API-Key") // Responses depend on the API key, see authorize.
+		}
+
+		if s.searchIn == nil {
+			handler(w, r)
+			return
+		}
+
+		metadata, err := datastore.ReadMetadata(s.searchIn.Datastore, s.searchIn.MetadataTable)
+		if err != nil { // Responses of datastores without metadata can't be validated.
+			handler(w, r)
+			return
+		}
+
+		etag := entityTag(metadata, r)
+		modified := metadata.ModifiedAt.Truncate(time.Second)
+		setHeaders := func(header http.Header) {
+			header.Set("ETag", etag)
+			header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+			header.Set("Cache-Control", s.cacheControl())
+		}
+
+		if notModified(r, etag, modified) {
+			setHeaders(w.Header())
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		handler(&cacheWriter{ResponseWriter: w, setHeaders: setHeaders}, r)
+	}
+}
+
+// entityTag returns the ETag of the response to a request made while the
+// datastore has given metadata. Requests for the same path, with the same query
+// parameters, in any order, and Accept header get the same ETag until books are
+// imported or edited.
+func entityTag(metadata *datastore.Metadata, r *http.Request) string {
+	hash := sha256.New()
+	fmt.Fprintf(
+		hash,
+		"%s\n%s\n%s\n%s",
+		datasetVersion(metadata),
+		r.URL.Path,
+		r.URL.Query().Encode(), // Sorted by key.
+		r.Header.Get("Accept"),
+	)
+	return fmt.Sprintf("\"%x\"", hash.Sum(nil)[:16])
+}
+
+// datasetVersion returns a string identifying the version of the dataset
+// described by metadata, which changes whenever books are imported or edited.
+func datasetVersion(metadata *datastore.Metadata) string {
+	return fmt.Sprintf(
+		"%s/%s/%d",
+		metadata.ImportedAt.Format(time.RFC3339Nano),
+		metadata.ModifiedAt.Format(time.RFC3339Nano),
+		metadata.Revision,
+	)
+}
+
+// notModified returns true if a conditional request's copy of the response,
+// identified by If-None-Match, or by If-Modified-Since if the former isn't
+// specified, is still current.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := strings.Join(r.Header.Values("If-None-Match"), ","); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.After(since)
+}
+
+// cacheControl returns the Cache-Control header of cacheable responses. Shared
+// caches may only store responses if reads don't require an API key.
+func (s *Server) cacheControl() string {
+	scope, maxAge := "public", time.Duration(0)
+	if s.cfg != nil {
+		if s.cfg.PrivateReads {
+			scope = "private"
+		}
+		maxAge = s.cfg.CacheMaxAge
+	}
+
+	if maxAge <= 0 {
+		return scope + ", no-cache"
+	}
+	return fmt.Sprintf("%s, max-age=%d", scope, int(maxAge.Seconds()))
+}
+
+// cacheWriter is a ResponseWriter that sets caching headers of a response if
+// it succeeds, so that errors aren't cached.
+type cacheWriter struct {
+	http.ResponseWriter
+	setHeaders func(http.Header)
+
+	written bool // If true, the response's header was written.
+}
+
+// WriteHeader sets caching headers if status is 200 OK, then writes the header.
+func (w *cacheWriter) WriteHeader(status int) {
+	if !w.written {
+		w.written = true
+		if status == http.StatusOK {
+			w.setHeaders(w.Header())
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the header with status 200 OK if it wasn't written, then writes
+// data as part of the response's body.
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
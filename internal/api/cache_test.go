@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestCache tests caching headers of read responses, and answering conditional
+// requests. Requests are performed in order, and may use validators of the
+// previous cached response.
+func TestCache(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	keys, err := auth.New(datastore, "keys")
+	if err != nil {
+		t.Fatalf("failed to create keys: %s", err.Error())
+	}
+	admin, _, err := keys.Add("admin", auth.Admin)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+
+	server := New(
+		&Config{Keys: keys, CacheMaxAge: time.Minute},
+		&SearchIn{
+			Datastore: datastore,
+			BookTable: bookTable,
+		},
+	)
+
+	var etag, modified string // Validators of the previous cached response.
+	for i, test := range []struct {
+		url         string
+		header      map[string]string // Headers of the request, "etag" and "modified" are replaced by validators.
+		status      int
+		sameETag    bool // If true, the response has the ETag of the previous cached response.
+		cached      bool // If true, the response has caching headers.
+		invalidates bool // If true, a book is deleted before the request.
+	}{
+		{url: "/books?Authors=Douglas&SortBy=ID", status: http.StatusOK, cached: true},
+		{url: "/books?SortBy=ID&Authors=Douglas", status: http.StatusOK, sameETag: true, cached: true},
+		{url: "/books?SortBy=ID&Authors=Douglas", header: map[string]string{"If-None-Match": "etag"}, status: http.StatusNotModified, sameETag: true, cached: true},
+		{url: "/books?SortBy=ID&Authors=Douglas", header: map[string]string{"If-None-Match": `"other", W/etag`}, status: http.StatusNotModified, sameETag: true, cached: true},
+		{url: "/books?SortBy=ID&Authors=Douglas", header: map[string]string{"If-None-Match": `"other"`}, status: http.StatusOK, sameETag: true, cached: true},
+		{url: "/books?SortBy=ID&Authors=Douglas", header: map[string]string{"If-Modified-Since": "modified"}, status: http.StatusNotModified, sameETag: true, cached: true},
+		{url: "/books?SortBy=ID&Authors=Douglas", header: map[string]string{"If-Modified-Since": "Sat, 01 Jan 2000 00:00:00 GMT"}, status: http.StatusOK, sameETag: true, cached: true},
+		{url: "/books?SortBy=ID&Authors=Douglas", header: map[string]string{"Accept": "text/csv"}, status: http.StatusOK, cached: true},
+		{url: "/books?Authors=Bryson", header: map[string]string{"If-None-Match": "etag"}, status: http.StatusOK, cached: true},
+		{url: "/book/22", status: http.StatusOK, cached: true},
+		{url: "/book/x", status: http.StatusBadRequest},
+		{url: "/book/22", status: http.StatusOK, sameETag: true, cached: true},
+		{url: "/book/22", header: map[string]string{"If-None-Match": "etag"}, status: http.StatusOK, cached: true, invalidates: true},
+		{url: "/book/22", header: map[string]string{"If-None-Match": "etag"}, status: http.StatusNotModified, sameETag: true, cached: true},
+	} {
+		if test.invalidates {
+			if recorder := serveRequest(t, server, "DELETE", "/book/25", admin, ""); recorder.Code != http.StatusNoContent {
+				t.Fatalf("%d: failed to delete book: %d %s", i, recorder.Code, recorder.Body.String())
+			}
+		}
+
+		request, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err.Error())
+		}
+		for key, value := range test.header {
+			switch value {
+			case "etag":
+				value = etag
+			case `"other", W/etag`:
+				value = `"other", W/` + etag
+			case "modified":
+				value = modified
+			}
+			request.Header.Set(key, value)
+		}
+
+		recorder := serveRecorded(server, request)
+		if recorder.Code != test.status {
+			t.Errorf("%d: expected status: %d, got: %d %s", i, test.status, recorder.Code, recorder.Body.String())
+		}
+		if test.status == http.StatusNotModified && recorder.Body.Len() != 0 {
+			t.Errorf("%d: expected an empty body, got: %s", i, recorder.Body.String())
+		}
+
+		header := recorder.Header()
+		if test.cached {
+			if header.Get("ETag") == "" || header.Get("Last-Modified") == "" {
+				t.Errorf("%d: expected validators, got: %v", i, header)
+			}
+			if header.Get("Cache-Control") != "public, max-age=60" {
+				t.Errorf("%d: expected Cache-Control: public, max-age=60, got: %s", i, header.Get("Cache-Control"))
+			}
+			if (header.Get("ETag") == etag) != test.sameETag {
+				t.Errorf("%d: expected same ETag: %t, previous: %s, got: %s", i, test.sameETag, etag, header.Get("ETag"))
+			}
+			etag, modified = header.Get("ETag"), header.Get("Last-Modified")
+		} else if header.Get("ETag") != "" || header.Get("Cache-Control") != "" {
+			t.Errorf("%d: expected no caching headers, got: %v", i, header)
+		}
+		if header.Get("Vary") != "Accept" {
+			t.Errorf("%d: expected Vary: Accept, got: %s", i, header.Get("Vary"))
+		}
+	}
+}
+
+// TestCacheControl tests Cache-Control headers of different configurations.
+func TestCacheControl(t *testing.T) {
+	for _, test := range []struct {
+		cfg      *Config
+		expected string
+	}{
+		{cfg: nil, expected: "public, no-cache"},
+		{cfg: &Config{}, expected: "public, no-cache"},
+		{cfg: &Config{CacheMaxAge: 5 * time.Minute}, expected: "public, max-age=300"},
+		{cfg: &Config{CacheMaxAge: 5 * time.Minute, PrivateReads: true}, expected: "private, max-age=300"},
+	} {
+		server := &Server{cfg: test.cfg}
+		if got := server.cacheControl(); got != test.expected {
+			t.Errorf("%v: expected: %s, got: %s", test.cfg, test.expected, got)
+		}
+	}
+}
+
+// TestCacheVary tests that responses vary by API key only if reads are
+// private.
+func TestCacheVary(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	keys, err := auth.New(datastore, "keys")
+	if err != nil {
+		t.Fatalf("failed to create keys: %s", err.Error())
+	}
+	reader, _, err := keys.Add("reader", auth.ReadOnly)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+
+	for private, expected := range map[bool][]string{
+		false: {"Accept"},
+		true:  {"Accept", "Authorization, X-API-Key"},
+	} {
+		server := New(&Config{Keys: keys, PrivateReads: private}, &SearchIn{
+			Datastore: datastore,
+			BookTable: bookTable,
+		})
+
+		recorder := serveRequest(t, server, "GET", "/book/1", reader, "")
+		if vary := recorder.Header().Values("Vary"); recorder.Code != http.StatusOK || !reflect.DeepEqual(vary, expected) {
+			t.Errorf("private reads %t: expected Vary: %v, got: %d %v", private, expected, recorder.Code, vary)
+		}
+	}
+}
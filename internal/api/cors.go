@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sudo-sturbia/bfr/v2/internal/logging"
+)
+
+// CORS holds options of cross-origin requests, which browsers make when pages
+// served from other origins call the API.
+type CORS struct {
+	Origins []string      // Origins allowed to make requests, e.g. "https://example.com", or "*" to allow any.
+	Methods []string      // Methods allowed in requests, any method routes are served using if empty.
+	Headers []string      // Headers allowed in requests, corsHeaders if empty.
+	MaxAge  time.Duration // Maximum time browsers can cache preflight responses, browsers' default if 0.
+}
+
+// corsHeaders are the headers allowed in cross-origin requests by default.
+var corsHeaders = []string{"Authorization", "Content-Type", "X-API-Key", logging.Header}
+
+// corsExposedHeaders are headers of responses, besides those browsers always
+// expose, that pages can read.
+var corsExposedHeaders = []string{"ETag", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", logging.Header}
+
+// cors is a mux middleware that allows cross-origin requests from allowed
+// origins to read responses. Preflight requests are answered by preflight,
+// which is routed for every path if cross-origin requests are allowed.
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isPreflight(r) {
+			s.allowOrigin(w, r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight is a handler of preflight requests, which ask whether a cross-origin
+// request with a method and headers is allowed before it's made. Responds with
+// 204 No Content if the request is allowed, or 403 Forbidden otherwise.
+func (s *Server) preflight(w http.ResponseWriter, r *http.Request) {
+	allowed := s.allowedMethods(r)
+	if !isPreflight(r) {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, r, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	headers := s.cfg.CORS.Headers
+	if len(headers) == 0 {
+		headers = corsHeaders
+	}
+
+	if !s.allowedOrigin(r.Header.Get("Origin")) {
+		writeError(w, r, "Origin isn't allowed.", http.StatusForbidden)
+		return
+	}
+	if method := r.Header.Get("Access-Control-Request-Method"); !contains(allowed, method) {
+		writeError(w, r, fmt.Sprintf("Method \"%s\" isn't allowed.", method), http.StatusForbidden)
+		return
+	}
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header != "" && !contains(headers, header) {
+			writeError(w, r, fmt.Sprintf("Header \"%s\" isn't allowed.", header), http.StatusForbidden)
+			return
+		}
+	}
+
+	s.allowOrigin(w, r)
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	if s.cfg.CORS.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(s.cfg.CORS.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowedMethods returns methods allowed in cross-origin requests to the path
+// of r, which are methods of routes that match the path and are allowed by the
+// server's configuration.
+func (s *Server) allowedMethods(r *http.Request) []string {
+	methods := s.cfg.CORS.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for _, method := range methods {
+		request := r.Clone(r.Context())
+		request.Method = method
+
+		var match mux.RouteMatch
+		if s.router.Match(request, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// allowOrigin allows the origin of a request, if it's allowed, to read the
+// response.
+func (s *Server) allowOrigin(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	allowed := origin
+	if contains(s.cfg.CORS.Origins, "*") {
+		allowed = "*"
+	} else {
+		w.Header().Add("Vary", "Origin") // Responses differ by origin.
+	}
+
+	if s.allowedOrigin(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+	}
+}
+
+// allowedOrigin returns true if requests from origin are allowed.
+func (s *Server) allowedOrigin(origin string) bool {
+	return origin != "" && (contains(s.cfg.CORS.Origins, "*") || contains(s.cfg.CORS.Origins, origin))
+}
+
+// isPreflight returns true if r is a preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// contains returns true if values contains value, ignoring case.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestCORS tests answering preflight requests, and allowing origins to read
+// responses.
+func TestCORS(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	const allowed = "https://books.example.com"
+	for i, test := range []struct {
+		cors    *CORS
+		method  string
+		url     string
+		header  map[string]string
+		status  int
+		origin  string // Expected Access-Control-Allow-Origin, none if empty.
+		methods string // Expected Access-Control-Allow-Methods, none if empty.
+		maxAge  string // Expected Access-Control-Max-Age, none if empty.
+	}{
+		{
+			cors:    &CORS{Origins: []string{allowed}, MaxAge: 10 * time.Minute},
+			method:  "OPTIONS",
+			url:     "/book/1",
+			header:  map[string]string{"Origin": allowed, "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "authorization, x-api-key"},
+			status:  http.StatusNoContent,
+			origin:  allowed,
+			methods: "GET, PUT, PATCH, DELETE",
+			maxAge:  "600",
+		},
+		{
+			cors:    &CORS{Origins: []string{allowed}},
+			method:  "OPTIONS",
+			url:     "/books/search",
+			header:  map[string]string{"Origin": allowed, "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "Content-Type"},
+			status:  http.StatusNoContent,
+			origin:  allowed,
+			methods: "GET, POST",
+		},
+		{
+			cors:   &CORS{Origins: []string{allowed}, Methods: []string{"GET"}},
+			method: "OPTIONS",
+			url:    "/books",
+			header: map[string]string{"Origin": allowed, "Access-Control-Request-Method": "POST"},
+			status: http.StatusForbidden,
+		},
+		{
+			cors:   &CORS{Origins: []string{allowed}},
+			method: "OPTIONS",
+			url:    "/book/1",
+			header: map[string]string{"Origin": "https://other.example.com", "Access-Control-Request-Method": "GET"},
+			status: http.StatusForbidden,
+		},
+		{
+			cors:   &CORS{Origins: []string{allowed}},
+			method: "OPTIONS",
+			url:    "/book/1",
+			header: map[string]string{"Origin": allowed, "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "X-Secret"},
+			status: http.StatusForbidden,
+		},
+		{
+			cors:   &CORS{Origins: []string{allowed}},
+			method: "OPTIONS",
+			url:    "/book/1",
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			cors:   nil,
+			method: "OPTIONS",
+			url:    "/book/1",
+			header: map[string]string{"Origin": allowed, "Access-Control-Request-Method": "GET"},
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			cors:   &CORS{Origins: []string{allowed}},
+			method: "GET",
+			url:    "/book/1",
+			header: map[string]string{"Origin": allowed},
+			status: http.StatusOK,
+			origin: allowed,
+		},
+		{
+			cors:   &CORS{Origins: []string{allowed}},
+			method: "GET",
+			url:    "/book/1",
+			header: map[string]string{"Origin": "https://other.example.com"},
+			status: http.StatusOK,
+		},
+		{
+			cors:   &CORS{Origins: []string{"*"}},
+			method: "GET",
+			url:    "/book/1",
+			header: map[string]string{"Origin": "https://other.example.com"},
+			status: http.StatusOK,
+			origin: "*",
+		},
+	} {
+		server := New(&Config{CORS: test.cors}, searchIn)
+
+		request, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err.Error())
+		}
+		for key, value := range test.header {
+			request.Header.Set(key, value)
+		}
+
+		recorder := serveRecorded(server, request)
+		if recorder.Code != test.status {
+			t.Errorf("%d: expected status: %d, got: %d %s", i, test.status, recorder.Code, recorder.Body.String())
+		}
+
+		header := recorder.Header()
+		for name, expected := range map[string]string{
+			"Access-Control-Allow-Origin":  test.origin,
+			"Access-Control-Allow-Methods": test.methods,
+			"Access-Control-Max-Age":       test.maxAge,
+		} {
+			if header.Get(name) != expected {
+				t.Errorf("%d: expected %s: %q, got: %q", i, name, expected, header.Get(name))
+			}
+		}
+		if test.origin != "" && header.Get("Access-Control-Expose-Headers") == "" {
+			t.Errorf("%d: expected exposed headers", i)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package api
+
+// docsPage is served by /docs. It renders the API's OpenAPI document, served
+// by /openapi.json, and allows trying its operations from a browser. It has
+// no external dependencies.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>bfr API</title>
+<style>
+	body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; color: #222; }
+	details { border: 1px solid #ddd; border-radius: 4px; margin: 0.5em 0; padding: 0.5em; }
+	summary { cursor: pointer; }
+	.method { display: inline-block; width: 5em; font-weight: bold; font-family: monospace; }
+	.path { font-family: monospace; }
+	table { border-collapse: collapse; margin: 0.5em 0; }
+	td, th { border-bottom: 1px solid #eee; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+	input, textarea, select { font-family: monospace; }
+	textarea { width: 100%; height: 8em; }
+	pre { background: #f6f6f6; padding: 0.5em; overflow: auto; max-height: 30em; }
+</style>
+</head>
+<body>
+<h1 id="title">bfr API</h1>
+<p id="description"></p>
+<p><label>API key <input id="key" type="password" size="60"></label></p>
+<div id="operations"></div>
+<script>
+"use strict";
+
+function element(tag, text) {
+	const e = document.createElement(tag);
+	if (text !== undefined) {
+		e.textContent = text;
+	}
+	return e;
+}
+
+function schemaName(schema) {
+	if (!schema) {
+		return "";
+	}
+	if (schema.$ref) {
+		return schema.$ref.split("/").pop();
+	}
+	if (schema.oneOf) {
+		return schema.oneOf.map(schemaName).join(" | ");
+	}
+	if (schema.type === "array") {
+		return schemaName(schema.items) + "[]";
+	}
+	return schema.type;
+}
+
+function parameterInput(parameter) {
+	let input;
+	if (parameter.schema.enum) {
+		input = element("select");
+		input.appendChild(element("option", ""));
+		for (const value of parameter.schema.enum) {
+			input.appendChild(element("option", value));
+		}
+	} else {
+		input = element("input");
+		input.placeholder = schemaName(parameter.schema);
+	}
+	input.name = parameter.name;
+	return input;
+}
+
+async function send(method, path, operation, inputs, body, output) {
+	const query = new URLSearchParams();
+	for (const parameter of operation.parameters || []) {
+		const value = inputs[parameter.name].value;
+		if (value === "") {
+			continue;
+		}
+		if (parameter.in === "path") {
+			path = path.replace("{" + parameter.name + "}", encodeURIComponent(value));
+		} else if (parameter.schema.type === "array") {
+			value.split(",").forEach(v => query.append(parameter.name, v.trim()));
+		} else {
+			query.append(parameter.name, value);
+		}
+	}
+
+	const url = path + (query.toString() ? "?" + query.toString() : "");
+	const headers = {};
+	const key = document.getElementById("key").value;
+	if (key !== "") {
+		headers["Authorization"] = "Bearer " + key;
+	}
+	const request = { method: method.toUpperCase(), headers: headers };
+	if (body) {
+		headers["Content-Type"] = "application/json";
+		request.body = body.value;
+	}
+
+	output.textContent = request.method + " " + url + "\n...";
+	try {
+		const response = await fetch(url, request);
+		const text = await response.text();
+		output.textContent = request.method + " " + url + "\n" + response.status + " " + response.statusText + "\n\n" + text;
+	} catch (error) {
+		output.textContent = request.method + " " + url + "\n" + error;
+	}
+}
+
+function render(method, path, operation, schemas) {
+	const details = element("details");
+	const summary = element("summary");
+	const methodSpan = element("span", method.toUpperCase());
+	methodSpan.className = "method";
+	const pathSpan = element("span", path);
+	pathSpan.className = "path";
+	summary.append(methodSpan, pathSpan, " " + operation.summary);
+	details.appendChild(summary);
+
+	if (operation.description) {
+		details.appendChild(element("p", operation.description));
+	}
+	if (operation.security) {
+		const optional = operation.security.some(s => Object.keys(s).length === 0);
+		details.appendChild(element("p", optional ? "An API key is optional, unless the server is private." : "Requires an admin API key."));
+	}
+
+	const inputs = {};
+	if (operation.parameters) {
+		const table = element("table");
+		const header = element("tr");
+		["Name", "In", "Type", "Description", "Value"].forEach(h => header.appendChild(element("th", h)));
+		table.appendChild(header);
+		for (const parameter of operation.parameters) {
+			const row = element("tr");
+			row.appendChild(element("td", parameter.name + (parameter.required ? " *" : "")));
+			row.appendChild(element("td", parameter.in));
+			row.appendChild(element("td", schemaName(parameter.schema)));
+			row.appendChild(element("td", parameter.description));
+			const cell = element("td");
+			inputs[parameter.name] = parameterInput(parameter);
+			cell.appendChild(inputs[parameter.name]);
+			row.appendChild(cell);
+			table.appendChild(row);
+		}
+		details.appendChild(table);
+	}
+
+	let body = null;
+	if (operation.requestBody) {
+		const schema = operation.requestBody.content["application/json"].schema;
+		details.appendChild(element("p", "Body:"));
+		body = element("textarea");
+		const example = {};
+		for (const name of Object.keys(schema.properties)) {
+			example[name] = schema.properties[name].type === "string" ? "" : 0;
+		}
+		body.value = JSON.stringify(example, null, "\t");
+		details.appendChild(body);
+	}
+
+	for (const status of Object.keys(operation.responses)) {
+		const response = operation.responses[status];
+		let text = status + ": " + response.description;
+		if (response.content) {
+			const type = Object.keys(response.content)[0];
+			text += ", " + type + " " + schemaName(response.content[type].schema);
+		}
+		details.appendChild(element("p", text));
+	}
+
+	const output = element("pre");
+	const button = element("button", "Send");
+	button.onclick = () => send(method, path, operation, inputs, body, output);
+	details.append(button, output);
+	return details;
+}
+
+function renderSchemas(schemas) {
+	const details = element("details");
+	details.appendChild(element("summary", "Schemas"));
+	for (const name of Object.keys(schemas).sort()) {
+		details.appendChild(element("h3", name));
+		const table = element("table");
+		for (const property of Object.keys(schemas[name].properties)) {
+			const row = element("tr");
+			row.appendChild(element("td", property));
+			row.appendChild(element("td", schemaName(schemas[name].properties[property])));
+			table.appendChild(row);
+		}
+		details.appendChild(table);
+	}
+	return details;
+}
+
+fetch("openapi.json").then(response => response.json()).then(spec => {
+	document.getElementById("title").textContent = spec.info.title + " API " + spec.info.version;
+	document.getElementById("description").textContent = spec.info.description;
+
+	const operations = document.getElementById("operations");
+	for (const path of Object.keys(spec.paths).sort()) {
+		for (const method of Object.keys(spec.paths[path])) {
+			operations.appendChild(render(method, path, spec.paths[path][method], spec.components.schemas));
+		}
+	}
+	operations.appendChild(renderSchemas(spec.components.schemas));
+});
+</script>
+</body>
+</html>
+`
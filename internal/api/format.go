@@ -0,0 +1,391 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sudo-sturbia/bfr/v2/internal/logging"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// format is an encoding of responses.
+type format struct {
+	name        string   // Name used in format query parameter.
+	contentType string   // Content-Type of responses.
+	mediaTypes  []string // Media types in Accept header that select the format.
+
+	encoder func(w io.Writer, list bool) encoder
+}
+
+// encoder encodes a response. A list is encoded item by item, so that it can
+// be written while it's produced; a single value is encoded as the only item.
+type encoder interface {
+	open() error                   // Begins a response.
+	encode(item interface{}) error // Encodes an item of a response.
+	close() error                  // Ends a response.
+}
+
+// Formats responses can be encoded in, the first is used by default.
+var formats = []*format{
+	{
+		name:        "pretty",
+		contentType: "application/json",
+		mediaTypes:  []string{"application/json"},
+		encoder: func(w io.Writer, list bool) encoder {
+			return &jsonEncoder{w: w, list: list, indent: true}
+		},
+	},
+	{
+		name:        "json",
+		contentType: "application/json",
+		encoder: func(w io.Writer, list bool) encoder {
+			return &jsonEncoder{w: w, list: list}
+		},
+	},
+	{
+		name:        "csv",
+		contentType: "text/csv; charset=utf-8",
+		mediaTypes:  []string{"text/csv"},
+		encoder: func(w io.Writer, list bool) encoder {
+			return &csvEncoder{w: csv.NewWriter(w)}
+		},
+	},
+	{
+		name:        "ndjson",
+		contentType: "application/x-ndjson",
+		mediaTypes:  []string{"application/x-ndjson", "application/ndjson"},
+		encoder: func(w io.Writer, list bool) encoder {
+			return &ndjsonEncoder{w: w}
+		},
+	},
+}
+
+// formatNames returns names of all formats.
+func formatNames() []string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.name
+	}
+	return names
+}
+
+// negotiate returns the format a request asks for using either the format
+// query parameter, or the Accept header. The default format is used if the
+// request specifies neither. If the requested format isn't supported returns
+// nil, a message and a status code.
+func negotiate(r *http.Request) (*format, string, int) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, f := range formats {
+			if f.name == name {
+				return f, "", http.StatusOK
+			}
+		}
+		return nil, fmt.Sprintf("Invalid format \"%s\".", name), http.StatusBadRequest
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return formats[0], "", http.StatusOK
+	}
+
+	var best *format
+	bestQuality := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if quality <= bestQuality {
+			continue
+		}
+
+		for _, f := range formats {
+			if f.accepts(mediaType) {
+				best, bestQuality = f, quality
+				break
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Sprintf("Can't respond with \"%s\".", accept), http.StatusNotAcceptable
+	}
+	return best, "", http.StatusOK
+}
+
+// accepts returns true if f is selected by a media type, which may contain
+// wildcards, of an Accept header.
+func (f *format) accepts(mediaType string) bool {
+	for _, own := range f.mediaTypes {
+		if mediaType == "*/*" || mediaType == own {
+			return true
+		}
+		if strings.HasSuffix(mediaType, "/*") && strings.HasPrefix(own, strings.TrimSuffix(mediaType, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAs writes a response to a request encoded in given format. Lists are
+// encoded and written item by item, instead of being buffered.
+func writeAs(w http.ResponseWriter, r *http.Request, f *format, response interface{}, status int) {
+	value := reflect.ValueOf(response)
+	if value.Kind() == reflect.Slice {
+		list := newListWriter(w, r, f, status)
+		for i := 0; i < value.Len(); i++ {
+			if list.write(value.Index(i).Interface()) != nil {
+				break
+			}
+		}
+		list.close()
+		return
+	}
+
+	w.Header().Set("Content-Type", f.contentType)
+	w.WriteHeader(status)
+
+	encoder := f.encoder(w, false)
+	err := encoder.open()
+	if err == nil {
+		err = encoder.encode(response)
+	}
+	if err == nil {
+		err = encoder.close()
+	}
+	if err != nil {
+		log.WithFields(logging.Fields(r)).Warnf("Failed to write response: %s.", err.Error())
+	}
+}
+
+// listWriter writes a list response item by item as items are produced. The
+// response's header is written with the first item, so a request can still
+// fail with an error message if no item was written.
+type listWriter struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	format *format
+	status int
+
+	encoder encoder // Encoder of the list, nil until the header is written.
+	err     error   // First error encountered while writing.
+}
+
+// newListWriter returns a listWriter that writes a list to a request with given
+// format and status code.
+func newListWriter(w http.ResponseWriter, r *http.Request, f *format, status int) *listWriter {
+	return &listWriter{
+		w:      w,
+		r:      r,
+		format: f,
+		status: status,
+	}
+}
+
+// started returns true if the response's header was written.
+func (l *listWriter) started() bool {
+	return l.encoder != nil
+}
+
+// start writes the response's header and the beginning of the list.
+func (l *listWriter) start() {
+	l.w.Header().Set("Content-Type", l.format.contentType)
+	l.w.WriteHeader(l.status)
+
+	l.encoder = l.format.encoder(l.w, true)
+	l.err = l.encoder.open()
+}
+
+// write writes an item of the list, and returns an error if writing failed,
+// after which the response is abandoned.
+func (l *listWriter) write(item interface{}) error {
+	if !l.started() {
+		l.start()
+	}
+	if l.err == nil {
+		l.err = l.encoder.encode(item)
+	}
+	return l.err
+}
+
+// close ends the list, and logs the error if writing failed.
+func (l *listWriter) close() {
+	if !l.started() {
+		l.start()
+	}
+	if l.err == nil {
+		l.err = l.encoder.close()
+	}
+	if l.err != nil {
+		log.WithFields(logging.Fields(l.r)).Warnf("Failed to write response: %s.", l.err.Error())
+	}
+}
+
+// fail abandons the list after an error, and logs the error. The response's
+// status was already sent, so fail aborts the connection instead of ending the
+// list, so that clients see an error rather than a truncated list that looks
+// complete. fail doesn't return.
+func (l *listWriter) fail(err error) {
+	log.WithFields(logging.Fields(l.r)).Warnf("Failed to write response: %s.", err.Error())
+	panic(http.ErrAbortHandler)
+}
+
+// jsonEncoder encodes a response as JSON, indented using tabs if indent is true.
+type jsonEncoder struct {
+	w      io.Writer
+	list   bool // If true, items are encoded in an array.
+	indent bool
+	items  int // Number of encoded items.
+}
+
+func (e *jsonEncoder) open() error {
+	if e.list {
+		_, err := io.WriteString(e.w, "[")
+		return err
+	}
+	return nil
+}
+
+func (e *jsonEncoder) encode(item interface{}) error {
+	prefix, separator := "", ""
+	if e.list {
+		if e.items > 0 {
+			separator = ","
+		}
+		if e.indent {
+			prefix = "\t"
+			separator += "\n\t"
+		}
+	}
+
+	var encoded []byte
+	var err error
+	if e.indent {
+		encoded, err = json.MarshalIndent(item, prefix, "\t")
+	} else {
+		encoded, err = json.Marshal(item)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.items++
+	_, err = io.WriteString(e.w, separator+string(encoded))
+	return err
+}
+
+func (e *jsonEncoder) close() error {
+	if !e.list {
+		return nil
+	}
+
+	end := "]"
+	if e.indent && e.items > 0 {
+		end = "\n]"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+// ndjsonEncoder encodes a response as newline delimited JSON, an item per line.
+type ndjsonEncoder struct {
+	w io.Writer
+}
+
+func (e *ndjsonEncoder) open() error {
+	return nil
+}
+
+func (e *ndjsonEncoder) encode(item interface{}) error {
+	return json.NewEncoder(e.w).Encode(item)
+}
+
+func (e *ndjsonEncoder) close() error {
+	return nil
+}
+
+// csvEncoder encodes a response as CSV with a header row. Books are encoded
+// using the columns of datasets, in the same order, and works also include
+// their number of editions.
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool // If true, the header row was written.
+}
+
+// bookHeader holds names of books' columns in CSV responses.
+var bookHeader = []string{
+	"ID",
+	"Title",
+	"Authors",
+	"AverageRating",
+	"ISBN",
+	"ISBN13",
+	"LanguageCode",
+	"Pages",
+	"RatingsCount",
+	"ReviewsCount",
+}
+
+func (e *csvEncoder) open() error {
+	return nil
+}
+
+func (e *csvEncoder) encode(item interface{}) error {
+	var header, record []string
+	switch item := item.(type) {
+	case *books.Book:
+		header, record = bookHeader, bookRecord(item)
+	case *books.Work:
+		header = append(append([]string{}, bookHeader...), "Editions")
+		record = append(bookRecord(&item.Book), strconv.Itoa(item.Editions))
+	case string:
+		header, record = []string{"Title"}, []string{item}
+	default:
+		return fmt.Errorf("can't encode %T as CSV", item)
+	}
+
+	if !e.header {
+		e.header = true
+		if err := e.w.Write(header); err != nil {
+			return err
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// bookRecord returns a CSV record of a book, see bookHeader.
+func bookRecord(book *books.Book) []string {
+	return []string{
+		strconv.Itoa(book.ID),
+		book.Title,
+		book.Authors,
+		strconv.FormatFloat(float64(book.AverageRating), 'f', -1, 32),
+		book.ISBN,
+		book.ISBN13,
+		book.LanguageCode,
+		strconv.Itoa(book.Pages),
+		strconv.Itoa(book.RatingsCount),
+		strconv.Itoa(book.ReviewsCount),
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// TestNegotiate tests choosing formats using format parameter and Accept header.
+func TestNegotiate(t *testing.T) {
+	for _, test := range []struct {
+		url    string
+		accept string
+		format string // Empty if no format is acceptable.
+		status int
+	}{
+		{url: "/books", format: "pretty", status: http.StatusOK},
+		{url: "/books?format=json", format: "json", status: http.StatusOK},
+		{url: "/books?format=csv", accept: "application/json", format: "csv", status: http.StatusOK},
+		{url: "/books?format=xml", status: http.StatusBadRequest},
+		{url: "/books", accept: "application/json", format: "pretty", status: http.StatusOK},
+		{url: "/books", accept: "text/csv", format: "csv", status: http.StatusOK},
+		{url: "/books", accept: "text/*", format: "csv", status: http.StatusOK},
+		{url: "/books", accept: "application/ndjson", format: "ndjson", status: http.StatusOK},
+		{url: "/books", accept: "text/html, application/x-ndjson;q=0.9, */*;q=0.8", format: "ndjson", status: http.StatusOK},
+		{url: "/books", accept: "application/json;q=0.5, text/csv", format: "csv", status: http.StatusOK},
+		{url: "/books", accept: "text/html, */*", format: "pretty", status: http.StatusOK},
+		{url: "/books", accept: "text/csv;q=0", format: "", status: http.StatusNotAcceptable},
+		{url: "/books", accept: "application/xml", format: "", status: http.StatusNotAcceptable},
+	} {
+		request, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err.Error())
+		}
+		request.Header.Set("Accept", test.accept)
+
+		format, _, status := negotiate(request)
+		name := ""
+		if format != nil {
+			name = format.name
+		}
+		if name != test.format || status != test.status {
+			t.Errorf("%s, Accept: %s: expected: %q %d, got: %q %d", test.url, test.accept, test.format, test.status, name, status)
+		}
+	}
+}
+
+// TestFormats tests encoding of responses in each format.
+func TestFormats(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	book, err := books.SearchByID(server.searchIn.booksIn(), 1)
+	if err != nil {
+		t.Fatalf("failed to find book: %s", err.Error())
+	}
+	pretty, _ := json.MarshalIndent(book, "", "\t")
+	compact, _ := json.Marshal(book)
+	const row = "1,Harry Potter and the Half-Blood Prince (Harry Potter  #6),J.K. Rowling-Mary GrandPré,4.56,439785960,9780439785969,eng,652,1944099,26249\n"
+	const header = "ID,Title,Authors,AverageRating,ISBN,ISBN13,LanguageCode,Pages,RatingsCount,ReviewsCount\n"
+
+	for _, test := range []struct {
+		url         string
+		contentType string
+		body        string
+	}{
+		{
+			url:         "/book/1",
+			contentType: "application/json",
+			body:        string(pretty),
+		},
+		{
+			url:         "/book/1?format=json",
+			contentType: "application/json",
+			body:        string(compact),
+		},
+		{
+			url:         "/book/1?format=ndjson",
+			contentType: "application/x-ndjson",
+			body:        string(compact) + "\n",
+		},
+		{
+			url:         "/book/1?format=csv",
+			contentType: "text/csv; charset=utf-8",
+			body:        header + row,
+		},
+		{
+			url:         "/books/" + url.PathEscape(book.Title) + "?format=csv",
+			contentType: "text/csv; charset=utf-8",
+			body:        header + row,
+		},
+		{
+			url:         "/books?ISBN=439785960&format=pretty",
+			contentType: "application/json",
+			body:        "[\n\t" + strings.ReplaceAll(string(pretty), "\n", "\n\t") + "\n]",
+		},
+		{
+			url:         "/books?ISBN=439785960&format=json",
+			contentType: "application/json",
+			body:        "[" + string(compact) + "]",
+		},
+		{
+			url:         "/books?ISBN=0&format=json",
+			contentType: "application/json",
+			body:        "[]",
+		},
+		{
+			url:         "/books?ISBN=0&format=csv",
+			contentType: "text/csv; charset=utf-8",
+			body:        "",
+		},
+		{
+			url:         "/books?Authors=J.K.%20Rowling&SortBy=ID&TitlesOnly=true&format=ndjson",
+			contentType: "application/x-ndjson",
+			body: "\"Harry Potter and the Half-Blood Prince (Harry Potter  #6)\"\n" +
+				"\"Harry Potter and the Order of the Phoenix (Harry Potter  #5)\"\n" +
+				"\"Harry Potter and the Sorcerer's Stone (Harry Potter  #1)\"\n" +
+				"\"Harry Potter and the Chamber of Secrets (Harry Potter  #2)\"\n" +
+				"\"Harry Potter and the Prisoner of Azkaban (Harry Potter  #3)\"\n" +
+				"\"Harry Potter Boxed Set  Books 1-5 (Harry Potter  #1-5)\"\n" +
+				"\"Harry Potter Collection (Harry Potter  #1-6)\"\n",
+		},
+		{
+			url:         "/books?ISBN=439785960&GroupEditions=true&format=csv",
+			contentType: "text/csv; charset=utf-8",
+			body:        strings.TrimSuffix(header, "\n") + ",Editions\n" + strings.TrimSuffix(row, "\n") + ",1\n",
+		},
+	} {
+		recorder := serveRequest(t, server, "GET", test.url, "", "")
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%s: expected status: %d, got: %d %s", test.url, http.StatusOK, recorder.Code, recorder.Body.String())
+			continue
+		}
+
+		if contentType := recorder.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("%s: expected Content-Type: %s, got: %s", test.url, test.contentType, contentType)
+		}
+		if body := recorder.Body.String(); body != test.body {
+			t.Errorf("%s: expected body:\n%s\ngot:\n%s", test.url, test.body, body)
+		}
+	}
+}
+
+// TestStreamFailure tests aborting a streamed response if writing it fails
+// after it was started.
+func TestStreamFailure(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	request, err := http.NewRequest("GET", "/books?format=json", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	writer := &failingWriter{ResponseRecorder: httptest.NewRecorder(), writes: 2}
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("expected handler to abort, got: %v", recovered)
+		}
+		if writer.Code != http.StatusOK || writer.Body.String() != "[" {
+			t.Errorf("expected an unfinished list, got: %d %q", writer.Code, writer.Body.String())
+		}
+	}()
+	server.ServeHTTP(writer, request)
+}
+
+// failingWriter is a ResponseWriter whose writes fail after given number of
+// writes, e.g. because the client disconnected.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+	writes int // Number of writes left before writes fail.
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if w.writes--; w.writes < 1 {
+		return 0, errors.New("connection reset")
+	}
+	return w.ResponseRecorder.Write(b)
+}
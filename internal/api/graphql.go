@@ -0,0 +1,500 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/graphql"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// Number of books returned by list fields of the GraphQL schema by default,
+// and maximum number that can be requested.
+const (
+	defaultGraphQLFirst = 20
+	maxGraphQLFirst     = 100
+)
+
+// Errors of GraphQL fields.
+var (
+	errEnough       = errors.New("enough books found") // Stops streaming search results once enough books are found.
+	errSearchFailed = errors.New("search failed")
+)
+
+// graphQLAuthor is the value of an Author in the GraphQL schema.
+type graphQLAuthor struct {
+	Name string
+}
+
+// graphQLResponseDoc documents responses of POST /graphql, which are
+// graphql.Responses.
+type graphQLResponseDoc struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []*graphql.Error       `json:"errors"`
+}
+
+// graphQL is a handler for POST /graphql endpoint.
+func (s *Server) graphQL(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := graphQLResponse(r.Context(), http.MaxBytesReader(w, r.Body, maxBodySize), s.graphQLSchema, s.graphQLLimits)
+	if ok {
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// graphQLSchemaText is a handler for GET /graphql/schema endpoint.
+func (s *Server) graphQLSchemaText(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(s.graphQLSchema.String()))
+}
+
+// graphQLResponse executes the GraphQL request in body and returns a response,
+// a status code, and bool indicating if the request was executed. Invalid
+// queries are responded to with GraphQL errors, with a 200 status code. It
+// should be used by Server.graphQL.
+func graphQLResponse(ctx context.Context, body io.Reader, schema *graphql.Schema, limits *graphql.Limits) (interface{}, int, bool) {
+	request := new(graphql.Request)
+	if err := json.NewDecoder(body).Decode(request); err != nil { // Unknown members, e.g. extensions, are ignored.
+		return fmt.Sprintf("Unable to decode GraphQL request: %s.", err.Error()), http.StatusBadRequest, false
+	}
+	if request.Query == "" {
+		return "GraphQL request has no query.", http.StatusBadRequest, false
+	}
+
+	return schema.Execute(ctx, request, limits), http.StatusOK, true
+}
+
+// newGraphQLSchema returns the GraphQL schema served by /graphql, whose fields
+// search in searchIn.
+func newGraphQLSchema(searchIn *SearchIn) *graphql.Schema {
+	book := &graphql.Object{
+		Name:        "Book",
+		Description: "An edition of a book.",
+	}
+	author := &graphql.Object{
+		Name:        "Author",
+		Description: "An author of books.",
+	}
+	bookList := nonNull(&graphql.List{OfType: nonNull(book)})
+
+	book.Fields = []*graphql.Field{
+		{Name: "id", Type: nonNull(graphql.Int)},
+		{Name: "title", Type: nonNull(graphql.String)},
+		{
+			Name:        "authors",
+			Description: "Authors of the book, in order of credit.",
+			Type:        nonNull(&graphql.List{OfType: nonNull(author)}),
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				names := source.(*books.Book).AuthorNames()
+				authors := make([]*graphQLAuthor, len(names))
+				for i, name := range names {
+					authors[i] = &graphQLAuthor{Name: name}
+				}
+				return authors, nil
+			},
+		},
+		{Name: "averageRating", Description: "Average rating, out of 5.", Type: nonNull(graphql.Float)},
+		{Name: "weightedRating", Description: "Average rating weighted by number of ratings, out of 5.", Type: nonNull(graphql.Float)},
+		{Name: "isbn", Description: "10 digit ISBN.", Type: nonNull(graphql.String)},
+		{Name: "isbn13", Description: "13 digit ISBN.", Type: nonNull(graphql.String)},
+		{Name: "languageCode", Description: "3-character language code.", Type: nonNull(graphql.String)},
+		{Name: "pages", Type: nonNull(graphql.Int)},
+		{Name: "ratingsCount", Type: nonNull(graphql.Int)},
+		{Name: "reviewsCount", Type: nonNull(graphql.Int)},
+		{
+			Name:        "series",
+			Description: "Name of the series the book belongs to, if any.",
+			Type:        graphql.String,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return nullIfEmpty(source.(*books.Book).Series), nil
+			},
+		},
+		{
+			Name:        "seriesIndex",
+			Description: "Volume number(s) of the book in its series, e.g. \"6\" or \"1-5\".",
+			Type:        graphql.String,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return nullIfEmpty(source.(*books.Book).SeriesIndex), nil
+			},
+		},
+		{
+			Name:        "workID",
+			Description: "ID shared by all editions of the same work.",
+			Type:        graphql.Int,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				if workID := source.(*books.Book).WorkID; workID != 0 {
+					return workID, nil
+				}
+				return nil, nil
+			},
+		},
+		{
+			Name:        "editions",
+			Description: "Editions of the book's work, including the book, most rated first.",
+			Type:        bookList,
+			Args:        []*graphql.Argument{firstArgument},
+			Size:        firstSize,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				b := source.(*books.Book)
+				if b.WorkID == 0 {
+					return []*books.Book{b}, nil
+				}
+
+				editions, err := books.SearchEditions(searchIn.booksIn(), b.WorkID)
+				if err != nil {
+					return nil, errSearchFailed
+				}
+				return firstOf(editions, args)
+			},
+		},
+		{
+			Name:        "similar",
+			Description: "Books similar to the book, most similar first.",
+			Type:        bookList,
+			Args: []*graphql.Argument{{
+				Name:        "limit",
+				Description: fmt.Sprintf("Number of books, between 1 and %d.", maxSimilarLimit),
+				Type:        graphql.Int,
+				Default:     defaultSimilarLimit,
+			}},
+			Size: func(args map[string]interface{}) int {
+				return args["limit"].(int)
+			},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				limit := args["limit"].(int)
+				if limit < 1 || limit > maxSimilarLimit {
+					return nil, fmt.Errorf("invalid limit %d", limit)
+				}
+
+				similar, err := books.Similar(searchIn.booksIn(), source.(*books.Book).ID, limit)
+				if err != nil {
+					return nil, errSearchFailed
+				}
+				return similar, nil
+			},
+		},
+	}
+
+	author.Fields = []*graphql.Field{
+		{Name: "name", Type: nonNull(graphql.String)},
+		{
+			Name:        "books",
+			Description: "Books the author is credited for, matching the name exactly but ignoring case.",
+			Type:        bookList,
+			Args:        []*graphql.Argument{firstArgument},
+			Size:        firstSize,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				return authorBooks(searchIn, source.(*graphQLAuthor).Name, args)
+			},
+		},
+	}
+
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: []*graphql.Field{
+			{
+				Name:        "book",
+				Description: "Finds a book by ID.",
+				Type:        book,
+				Args:        []*graphql.Argument{{Name: "id", Type: nonNull(graphql.Int)}},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					b, err := books.SearchByID(searchIn.booksIn(), args["id"].(int))
+					switch {
+					case err == books.ErrNotFound:
+						return nil, nil
+					case err != nil:
+						return nil, errSearchFailed
+					}
+					return b, nil
+				},
+			},
+			{
+				Name:        "books",
+				Description: "Lists books with a title.",
+				Type:        bookList,
+				Args:        []*graphql.Argument{{Name: "title", Description: "Exact title of the books.", Type: nonNull(graphql.String)}, firstArgument},
+				Size:        firstSize,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					found, err := books.SearchByTitle(searchIn.booksIn(), args["title"].(string))
+					if err != nil {
+						return nil, errSearchFailed
+					}
+					return firstOf(found, args)
+				},
+			},
+			{
+				Name:        "search",
+				Description: "Searches for books that match all given parameters.",
+				Type:        bookList,
+				Args: []*graphql.Argument{
+					{Name: "by", Description: "Parameters to search by, all books match if omitted.", Type: graphQLSearchBy},
+					firstArgument,
+					{Name: "offset", Description: "Number of matching books to skip.", Type: graphql.Int, Default: 0},
+				},
+				Size: firstSize,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					return search(searchIn, args)
+				},
+			},
+			{
+				Name:        "series",
+				Description: "Lists books in a series in reading order.",
+				Type:        bookList,
+				Args:        []*graphql.Argument{{Name: "name", Description: "Name of the series, ignoring case.", Type: nonNull(graphql.String)}, firstArgument},
+				Size:        firstSize,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					found, err := books.SearchBySeries(searchIn.booksIn(), args["name"].(string))
+					if err != nil {
+						return nil, errSearchFailed
+					}
+					return firstOf(found, args)
+				},
+			},
+			{
+				Name:        "author",
+				Description: "Finds an author by name, ignoring case. Null if the author has no books.",
+				Type:        author,
+				Args:        []*graphql.Argument{{Name: "name", Type: nonNull(graphql.String)}},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					name := args["name"].(string)
+					found, err := authorBooks(searchIn, name, map[string]interface{}{"first": 1})
+					if err != nil || len(found) == 0 {
+						return nil, err
+					}
+
+					for _, n := range found[0].AuthorNames() {
+						if strings.EqualFold(n, name) {
+							name = n // As written in the dataset.
+						}
+					}
+					return &graphQLAuthor{Name: name}, nil
+				},
+			},
+		},
+	}
+
+	schema, err := graphql.NewSchema(query)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// firstArgument is the argument of list fields that limits their number of
+// books.
+var firstArgument = &graphql.Argument{
+	Name:        "first",
+	Description: fmt.Sprintf("Number of books to return, at most %d.", maxGraphQLFirst),
+	Type:        graphql.Int,
+	Default:     defaultGraphQLFirst,
+}
+
+// firstSize is the size of list fields with firstArgument.
+func firstSize(args map[string]interface{}) int {
+	return args["first"].(int)
+}
+
+// first returns the number of books requested by a field's first argument.
+func first(args map[string]interface{}) (int, error) {
+	first := args["first"].(int)
+	if first < 0 || first > maxGraphQLFirst {
+		return 0, fmt.Errorf("invalid first %d, must be between 0 and %d", first, maxGraphQLFirst)
+	}
+	return first, nil
+}
+
+// firstOf returns the books requested by a field's first argument out of found.
+func firstOf(found []*books.Book, args map[string]interface{}) ([]*books.Book, error) {
+	n, err := first(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) > n {
+		found = found[:n]
+	}
+	return found, nil
+}
+
+// search returns books that match the search parameters of a search field's
+// arguments. Results are streamed, and the search stops once enough books are
+// found.
+func search(searchIn *SearchIn, args map[string]interface{}) ([]*books.Book, error) {
+	n, err := first(args)
+	if err != nil {
+		return nil, err
+	}
+	offset := args["offset"].(int)
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset %d", offset)
+	}
+
+	by := books.NewSearchBy()
+	if fields, ok := args["by"].(map[string]interface{}); ok {
+		setSearchBy(by, fields)
+	}
+	if err := by.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid search: %s", err.Error())
+	}
+
+	found := make([]*books.Book, 0)
+	skipped := 0
+	err = books.SearchEach(searchIn.booksIn(), by, func(book *books.Book) error {
+		if len(found) == n {
+			return errEnough
+		}
+		if skipped < offset {
+			skipped++
+			return nil
+		}
+		found = append(found, book)
+		return nil
+	})
+	if err != nil && err != errEnough {
+		return nil, errSearchFailed
+	}
+	return found, nil
+}
+
+// authorBooks returns books credited to the author with given name, ignoring
+// case, limited by a field's first argument.
+func authorBooks(searchIn *SearchIn, name string, args map[string]interface{}) ([]*books.Book, error) {
+	n, err := first(args)
+	if err != nil {
+		return nil, err
+	}
+
+	by := books.NewSearchBy()
+	by.Authors = []string{name}
+
+	found := make([]*books.Book, 0)
+	err = books.SearchEach(searchIn.booksIn(), by, func(book *books.Book) error {
+		if len(found) == n {
+			return errEnough
+		}
+		for _, author := range book.AuthorNames() { // Authors are matched by sub-string.
+			if strings.EqualFold(author, name) {
+				found = append(found, book)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errEnough {
+		return nil, errSearchFailed
+	}
+	return found, nil
+}
+
+// graphQLSearchBy is the input type of books.SearchBy's fields. Fields are
+// named in camel case, e.g. TitleHas is titleHas, and ISBN13 is isbn13.
+var graphQLSearchBy = newGraphQLSearchBy()
+
+// newGraphQLSearchBy returns an input type with a field of each of
+// books.SearchBy's fields.
+func newGraphQLSearchBy() *graphql.InputObject {
+	t := reflect.TypeOf(books.SearchBy{})
+	input := &graphql.InputObject{
+		Name:        "SearchBy",
+		Description: "Parameters to search for books by. Omitted parameters are ignored.",
+		Fields:      make([]*graphql.Argument, t.NumField()),
+	}
+	for i := range input.Fields {
+		field := t.Field(i)
+
+		var fieldType graphql.Type
+		switch field.Type.Kind() {
+		case reflect.String:
+			fieldType = graphql.String
+		case reflect.Slice:
+			fieldType = &graphql.List{OfType: nonNull(graphql.String)}
+		case reflect.Float32:
+			fieldType = graphql.Float
+		case reflect.Int:
+			fieldType = graphql.Int
+		case reflect.Bool:
+			fieldType = graphql.Boolean
+		}
+
+		description := searchDescriptions[field.Name]
+		if field.Name == "SortBy" {
+			description = fmt.Sprintf("%s One of %s.", description, strings.Join(books.SortFields(), ", "))
+		}
+		input.Fields[i] = &graphql.Argument{
+			Name:        camelCase(field.Name),
+			Description: description,
+			Type:        fieldType,
+		}
+	}
+	return input
+}
+
+// setSearchBy sets fields of by to coerced fields of a SearchBy input value.
+func setSearchBy(by *books.SearchBy, fields map[string]interface{}) {
+	value := reflect.ValueOf(by).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		given, ok := fields[camelCase(value.Type().Field(i).Name)]
+		if !ok || given == nil {
+			continue
+		}
+
+		switch field := value.Field(i); field.Kind() {
+		case reflect.String:
+			field.SetString(given.(string))
+		case reflect.Slice:
+			list := given.([]interface{})
+			strs := make([]string, len(list))
+			for j, s := range list {
+				strs[j] = s.(string)
+			}
+			field.Set(reflect.ValueOf(strs))
+		case reflect.Float32:
+			field.SetFloat(given.(float64))
+		case reflect.Int:
+			field.SetInt(int64(given.(int)))
+		case reflect.Bool:
+			field.SetBool(given.(bool))
+		}
+	}
+}
+
+// camelCase returns the camel case GraphQL name of a Go field, e.g. titleHas
+// of TitleHas, and isbn13 of ISBN13.
+func camelCase(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper-- // The last capital starts the next word, e.g. "Has" of "URLHas".
+	}
+
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// nonNull returns the non-null type of t.
+func nonNull(t graphql.Type) graphql.Type {
+	return &graphql.NonNull{OfType: t}
+}
+
+// nullIfEmpty returns nil if s is empty, and s otherwise.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
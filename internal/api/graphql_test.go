@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestGraphQL tests querying books using GraphQL.
+func TestGraphQL(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for _, test := range []struct {
+		query     string
+		variables string
+		expected  string
+	}{
+		{
+			query:    `{ book(id: 14) { id authors { name } series seriesIndex workID averageRating editions { id } similar(limit: 2) { id } } }`,
+			expected: `{"data": {"book": {"id": 14, "authors": [{"name": "Douglas Adams"}], "series": "Hitchhiker's Guide to the Galaxy", "seriesIndex": "1", "workID": 14, "averageRating": 4.22, "editions": [{"id": 14}, {"id": 16}], "similar": [{"id": 13}, {"id": 18}]}}}`,
+		},
+		{
+			query:    `{ book(id: 30) { id } }`,
+			expected: `{"data": {"book": null}}`,
+		},
+		{
+			query:    `{ book(id: 22) { series seriesIndex } }`,
+			expected: `{"data": {"book": {"series": null, "seriesIndex": null}}}`,
+		},
+		{
+			query:     `query($by: SearchBy) { search(by: $by, first: 2, offset: 1) { id } }`,
+			variables: `{"by": {"authors": ["Douglas Adams"], "ratingFloor": 4.3, "sortBy": "ID"}}`,
+			expected:  `{"data": {"search": [{"id": 13}, {"id": 18}]}}`,
+		},
+		{
+			query:    `{ search(by: {titleHas: "Bryson", pagesCeil: 300}) { id } all: search(first: 3) { id } }`,
+			expected: `{"data": {"search": [{"id": 22}, {"id": 23}], "all": [{"id": 1}, {"id": 2}, {"id": 3}]}}`,
+		},
+		{
+			query:    `{ author(name: "douglas adams") { name books(first: 4) { id } } partial: author(name: "Douglas") { name } }`,
+			expected: `{"data": {"author": {"name": "Douglas Adams", "books": [{"id": 12}, {"id": 13}, {"id": 14}, {"id": 16}]}, "partial": null}}`,
+		},
+		{
+			query:    `{ series(name: "harry potter", first: 2) { id } books(title: "The Ultimate Hitchhiker's Guide to the Galaxy") { id } }`,
+			expected: `{"data": {"series": [{"id": 3}, {"id": 8}], "books": [{"id": 13}]}}`,
+		},
+		{
+			query:    `{ book(id: 14) { id similar(limit: 51) { id } } }`,
+			expected: `{"data": {"book": null}, "errors": [{"message": "Invalid limit 51.", "locations": [{"line": 1, "column": 21}], "path": ["book", "similar"]}]}`,
+		},
+		{
+			query:    `{ search(by: {sortBy: "Authors"}) { id } }`,
+			expected: `{"data": null, "errors": [{"message": "Invalid search: can't sort by \"Authors\".", "locations": [{"line": 1, "column": 3}], "path": ["search"]}]}`,
+		},
+		{
+			query:    `{ books(title: "Emma", first: 101) { id } }`,
+			expected: `{"data": null, "errors": [{"message": "Invalid first 101, must be between 0 and 100.", "locations": [{"line": 1, "column": 3}], "path": ["books"]}]}`,
+		},
+		{
+			query:    `{ book(id: 14) { isbn10 } }`,
+			expected: `{"errors": [{"message": "Cannot query field \"isbn10\" on type \"Book\".", "locations": [{"line": 1, "column": 18}]}]}`,
+		},
+	} {
+		body := `{"query": ` + quoteJSON(t, test.query) + `}`
+		if test.variables != "" {
+			body = `{"query": ` + quoteJSON(t, test.query) + `, "variables": ` + test.variables + `}`
+		}
+
+		recorder := serveRequest(t, server, "POST", "/graphql", "", body)
+		if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: expected a JSON response with status: %d, got: %d %s", test.query, http.StatusOK, recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+		if !equalJSON(t, recorder.Body.String(), test.expected) {
+			t.Errorf("%s: expected response: %s, got: %s", test.query, test.expected, recorder.Body.String())
+		}
+	}
+}
+
+// TestGraphQLLimits tests rejecting GraphQL queries that exceed the configured
+// depth and complexity.
+func TestGraphQLLimits(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(&Config{GraphQLMaxDepth: 3, GraphQLMaxComplexity: 100}, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for query, message := range map[string]string{
+		`{ book(id: 14) { authors { name } } }`:                                            "",
+		`{ search(first: 10) { similar(limit: 8) { id } } }`:                               "", // 1 + 10 * (1 + 8 * 1)
+		`{ book(id: 14) { authors { books { id } } } }`:                                    "Query exceeds the maximum depth of 3.",
+		`{ search(first: 10) { similar(limit: 9) { id } } }`:                               "Query has a complexity of 101, which exceeds the maximum complexity of 100.",
+		`{ search { title } author(name: "Bill Bryson") { books(first: 100) { title } } }`: "Query has a complexity of 123, which exceeds the maximum complexity of 100.",
+	} {
+		recorder := serveRequest(t, server, "POST", "/graphql", "", `{"query": `+quoteJSON(t, query)+`}`)
+
+		var response struct {
+			Data   json.RawMessage
+			Errors []struct{ Message string }
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: failed to decode response: %s", query, err.Error())
+		}
+
+		if message == "" && (response.Data == nil || len(response.Errors) != 0) {
+			t.Errorf("%s: expected query to be executed, got: %s", query, recorder.Body.String())
+		}
+		if message != "" && (response.Data != nil || len(response.Errors) != 1 || response.Errors[0].Message != message) {
+			t.Errorf("%s: expected query to be rejected with: %s, got: %s", query, message, recorder.Body.String())
+		}
+	}
+}
+
+// TestGraphQLRequests tests rejecting malformed GraphQL requests, and serving
+// the schema.
+func TestGraphQLRequests(t *testing.T) {
+	server := New(nil, nil)
+	for body, expected := range map[string]string{
+		`{"query": "{ book(id: 1) { id }`: "Unable to decode GraphQL request: unexpected EOF.\n",
+		`{"variables": {}}`:               "GraphQL request has no query.\n",
+		`{"query": 1}`:                    "Unable to decode GraphQL request: json: cannot unmarshal number into Go struct field Request.query of type string.\n",
+	} {
+		recorder := serveRequest(t, server, "POST", "/graphql", "", body)
+		if recorder.Code != http.StatusBadRequest || recorder.Body.String() != expected {
+			t.Errorf("%s: expected response: %d %q, got: %d %q", body, http.StatusBadRequest, expected, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	recorder := serveRequest(t, server, "GET", "/graphql/schema", "", "")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "type Book {") || !strings.Contains(recorder.Body.String(), "input SearchBy {") {
+		t.Errorf("expected the schema's definition, got: %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+// TestCamelCase tests naming fields of the GraphQL schema.
+func TestCamelCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"TitleHas":            "titleHas",
+		"ISBN":                "isbn",
+		"ISBN13":              "isbn13",
+		"WeightedRatingFloor": "weightedRatingFloor",
+		"URLPath":             "urlPath",
+		"ID":                  "id",
+	} {
+		if camel := camelCase(name); camel != expected {
+			t.Errorf("%s: expected: %s, got: %s", name, expected, camel)
+		}
+	}
+}
+
+// quoteJSON returns s encoded as a JSON string.
+func quoteJSON(t *testing.T, s string) string {
+	t.Helper()
+	quoted, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("failed to encode string: %s", err.Error())
+	}
+	return string(quoted)
+}
+
+// equalJSON returns true if the JSON values a and b are equal.
+func equalJSON(t *testing.T, a, b string) bool {
+	t.Helper()
+	var decodedA, decodedB interface{}
+	if err := json.Unmarshal([]byte(a), &decodedA); err != nil {
+		t.Fatalf("failed to decode %s: %s", a, err.Error())
+	}
+	if err := json.Unmarshal([]byte(b), &decodedB); err != nil {
+		t.Fatalf("failed to decode %s: %s", b, err.Error())
+	}
+	return reflect.DeepEqual(decodedA, decodedB)
+}
@@ -1,15 +1,17 @@
 package api
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 	log "github.com/sirupsen/logrus"
+	"github.com/sudo-sturbia/bfr/v2/internal/datastore"
+	"github.com/sudo-sturbia/bfr/v2/internal/logging"
 	"github.com/sudo-sturbia/bfr/v2/pkg/books"
 )
 
@@ -18,11 +20,27 @@ var (
 	decoder = schema.NewDecoder()
 )
 
+// Number of similar books returned by /book/{id}/similar, and suggestions
+// returned by /suggest by default, and maximum number that can be requested.
+const (
+	defaultSimilarLimit = 10
+	maxSimilarLimit     = 50
+
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
 // searchByID is a handler for /book/{id} endpoint.
 func (s *Server) searchByID(w http.ResponseWriter, r *http.Request) {
+	format, message, status := negotiate(r)
+	if format == nil {
+		writeError(w, r, message, status)
+		return
+	}
+
 	response, status, ok := searchByIDResponse(s.searchIn, mux.Vars(r)["id"])
 	if ok {
-		write(w, r, response, status)
+		writeAs(w, r, format, response, status)
 	} else {
 		message, ok := response.(string)
 		if ok {
@@ -33,7 +51,39 @@ func (s *Server) searchByID(w http.ResponseWriter, r *http.Request) {
 
 // searchByTitle is a handler for /books/{title} endpoint.
 func (s *Server) searchByTitle(w http.ResponseWriter, r *http.Request) {
+	format, message, status := negotiate(r)
+	if format == nil {
+		writeError(w, r, message, status)
+		return
+	}
+
 	response, status, ok := searchByTitleResponse(s.searchIn, mux.Vars(r)["title"])
+	if ok {
+		writeAs(w, r, format, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// searchBySeries is a handler for /series/{name} endpoint.
+func (s *Server) searchBySeries(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := searchBySeriesResponse(s.searchIn, mux.Vars(r)["name"])
+	if ok {
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// searchEditions is a handler for /work/{id}/editions endpoint.
+func (s *Server) searchEditions(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := searchEditionsResponse(s.searchIn, mux.Vars(r)["id"])
 	if ok {
 		write(w, r, response, status)
 	} else {
@@ -44,27 +94,160 @@ func (s *Server) searchByTitle(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// search is a handler for /books endpoint.
+// search is a handler for GET /books endpoint.
 func (s *Server) search(w http.ResponseWriter, r *http.Request) {
-	response, status, ok := searchResponse(
-		r.URL.Query(),
-		s.searchIn,
-		&books.SearchBy{
-			TitleHas:          "",
-			Authors:           nil,
-			LanguageCode:      nil,
-			ISBN:              "",
-			ISBN13:            "",
-			RatingCeil:        -1,
-			RatingFloor:       -1,
-			PagesCeil:         -1,
-			PagesFloor:        -1,
-			RatingsCountCeil:  -1,
-			RatingsCountFloor: -1,
-			ReviewsCountCeil:  -1,
-			ReviewsCountFloor: -1,
-		},
-	)
+	format, message, status := negotiate(r)
+	if format == nil {
+		writeError(w, r, message, status)
+		return
+	}
+
+	query := r.URL.Query()
+	query.Del("format")
+
+	request, message, ok := queryRequest(query)
+	if !ok {
+		writeError(w, r, message, http.StatusBadRequest)
+		return
+	}
+	s.writeSearch(w, r, format, request)
+}
+
+// searchByBody is a handler for POST /books/search endpoint.
+func (s *Server) searchByBody(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	format, message, status := negotiate(r)
+	if format == nil {
+		writeError(w, r, message, status)
+		return
+	}
+
+	request, message, ok := bodyRequest(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if !ok {
+		writeError(w, r, message, http.StatusBadRequest)
+		return
+	}
+	s.writeSearch(w, r, format, request)
+}
+
+// writeSearch validates a search request, then searchs for and writes books,
+// works, or titles that match it. Listed books are streamed, see searchStream.
+// Results are written from and added to the server's cache, if any.
+func (s *Server) writeSearch(w http.ResponseWriter, r *http.Request, format *format, request *searchRequest) {
+	if err := request.Validate(); err != nil {
+		writeError(w, r, fmt.Sprintf("Invalid search query: %s.", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	key := request.cacheKey()
+	version, cacheable := s.resultsVersion()
+	if cacheable {
+		if results, ok := s.results.get(version, key); ok {
+			writeAs(w, r, format, results, http.StatusOK)
+			return
+		}
+	}
+
+	if !request.TitlesOnly && !request.GroupEditions {
+		if found := s.searchStream(w, r, format, &request.SearchBy); found != nil && cacheable {
+			s.results.add(version, key, found)
+		}
+		return
+	}
+
+	response, status, ok := searchResponse(s.searchIn, request)
+	if ok {
+		if cacheable && reflect.ValueOf(response).Len() <= maxCachedResults {
+			s.results.add(version, key, response)
+		}
+		writeAs(w, r, format, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// searchStream writes books that match searchBy to a request as they're read
+// from the datastore, instead of collecting all books first. Returns the books
+// written if the search succeeded and found at most maxCachedResults books, so
+// that they can be cached, and nil otherwise.
+func (s *Server) searchStream(w http.ResponseWriter, r *http.Request, format *format, searchBy *books.SearchBy) []*books.Book {
+	found, tooMany := make([]*books.Book, 0), false
+	list := newListWriter(w, r, format, http.StatusOK)
+	err := books.SearchEach(s.searchIn.booksIn(), searchBy, func(book *books.Book) error {
+		if len(found) < maxCachedResults {
+			found = append(found, book)
+		} else {
+			tooMany = true
+		}
+		return list.write(book)
+	})
+	switch {
+	case err == nil:
+		list.close()
+	case !list.started():
+		writeError(w, r, "Search failed.", http.StatusBadRequest)
+	default:
+		list.fail(err)
+	}
+
+	if err != nil || tooMany {
+		return nil
+	}
+	return found
+}
+
+// resultsVersion returns the version of the dataset that search results are
+// cached for, and false if results can't be cached.
+func (s *Server) resultsVersion() (string, bool) {
+	if s.results == nil {
+		return "", false
+	}
+
+	metadata, err := datastore.ReadMetadata(s.searchIn.Datastore, s.searchIn.MetadataTable)
+	if err != nil { // Results of datastores without metadata can't be invalidated.
+		return "", false
+	}
+	return datasetVersion(metadata), true
+}
+
+// similar is a handler for /book/{id}/similar endpoint.
+func (s *Server) similar(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := similarResponse(r.URL.Query(), s.searchIn, mux.Vars(r)["id"])
+	if ok {
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// suggest is a handler for /suggest endpoint.
+func (s *Server) suggest(w http.ResponseWriter, r *http.Request) {
+	index, err := s.prefixIndex()
+	if err != nil {
+		writeError(w, r, "Failed to build suggestions index.", http.StatusInternalServerError)
+		return
+	}
+
+	response, status, ok := suggestResponse(r.URL.Query(), index)
+	if ok {
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// stats is a handler for /stats endpoint.
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := statsResponse(r.URL.Query(), s.searchIn, books.NewSearchBy())
 	if ok {
 		write(w, r, response, status)
 	} else {
@@ -84,13 +267,7 @@ func searchByIDResponse(searchIn *SearchIn, idString string) (interface{}, int,
 		return fmt.Sprintf("Invalid id \"%s\".", idString), http.StatusBadRequest, false
 	}
 
-	book, err := books.SearchByID(
-		&books.SearchIn{
-			Datastore: searchIn.Datastore,
-			BookTable: searchIn.BookTable,
-		},
-		id,
-	)
+	book, err := books.SearchByID(searchIn.booksIn(), id)
 	if err != nil {
 		return "Search failed.", http.StatusBadRequest, false
 	}
@@ -101,13 +278,7 @@ func searchByIDResponse(searchIn *SearchIn, idString string) (interface{}, int,
 // returns a response, a status code, and bool indicating if the operation was performed
 // successfully. It should be used by Server.searchByTitle.
 func searchByTitleResponse(searchIn *SearchIn, title string) (interface{}, int, bool) {
-	books, err := books.SearchByTitle(
-		&books.SearchIn{
-			Datastore: searchIn.Datastore,
-			BookTable: searchIn.BookTable,
-		},
-		title,
-	)
+	books, err := books.SearchByTitle(searchIn.booksIn(), title)
 
 	if err != nil {
 		return "Search failed.", http.StatusBadRequest, false
@@ -115,67 +286,162 @@ func searchByTitleResponse(searchIn *SearchIn, title string) (interface{}, int,
 	return books, http.StatusOK, true
 }
 
-// searchResponse searchs the database for books based on given parameters and
-// returns a response, a status code, and bool indicating if the operation was performed
-// successfully. It should be used by Server.search.
-func searchResponse(query url.Values, searchIn *SearchIn, searchBy *books.SearchBy) (interface{}, int, bool) {
-	titlesOnly, parameters := isTitlesOnly(query)
-	err := decoder.Decode(searchBy, parameters)
+// searchBySeriesResponse searchs the database for books in a series and returns
+// a response, a status code, and bool indicating if the operation was performed
+// successfully. It should be used by Server.searchBySeries.
+func searchBySeriesResponse(searchIn *SearchIn, series string) (interface{}, int, bool) {
+	books, err := books.SearchBySeries(searchIn.booksIn(), series)
+
 	if err != nil {
-		return "Unable to decode search query.", http.StatusBadRequest, false
+		return "Search failed.", http.StatusBadRequest, false
+	}
+	return books, http.StatusOK, true
+}
+
+// searchEditionsResponse searchs the database for editions of a work and returns
+// a response, a status code, and bool indicating if the operation was performed
+// successfully. It should be used by Server.searchEditions.
+func searchEditionsResponse(searchIn *SearchIn, idString string) (interface{}, int, bool) {
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return fmt.Sprintf("Invalid id \"%s\".", idString), http.StatusBadRequest, false
 	}
 
-	in := &books.SearchIn{
-		Datastore: searchIn.Datastore,
-		BookTable: searchIn.BookTable,
+	books, err := books.SearchEditions(searchIn.booksIn(), id)
+	if err != nil {
+		return "Search failed.", http.StatusBadRequest, false
+	}
+	return books, http.StatusOK, true
+}
+
+// searchResponse searchs the database for books based on given request and
+// returns a response, a status code, and bool indicating if the operation was performed
+// successfully. It should be used by Server.writeSearch.
+func searchResponse(searchIn *SearchIn, request *searchRequest) (interface{}, int, bool) {
+	in := searchIn.booksIn()
+
+	if request.GroupEditions {
+		works, err := books.SearchWorks(in, &request.SearchBy)
+		if err != nil {
+			return "Search failed.", http.StatusBadRequest, false
+		}
+
+		if request.TitlesOnly {
+			titles := make([]string, len(works))
+			for i, work := range works {
+				titles[i] = work.Title
+			}
+			return titles, http.StatusOK, true
+		}
+		return works, http.StatusOK, true
 	}
 
-	if titlesOnly {
-		titles, err := books.SearchForTitles(in, searchBy)
+	if request.TitlesOnly {
+		titles, err := books.SearchForTitles(in, &request.SearchBy)
 		if err != nil {
 			return "Search failed.", http.StatusBadRequest, false
 		}
 		return titles, http.StatusOK, true
 	}
 
-	books, err := books.Search(in, searchBy)
+	books, err := books.Search(in, &request.SearchBy)
 	if err != nil {
 		return "Search failed.", http.StatusBadRequest, false
 	}
 	return books, http.StatusOK, true
 }
 
-// write writes a JSON response to a request.
-func write(w http.ResponseWriter, r *http.Request, response interface{}, status int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+// similarResponse searchs the database for books similar to the book with given
+// id and returns a response, a status code, and bool indicating if the operation was
+// performed successfully. It should be used by Server.similar.
+func similarResponse(query url.Values, searchIn *SearchIn, idString string) (interface{}, int, bool) {
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return fmt.Sprintf("Invalid id \"%s\".", idString), http.StatusBadRequest, false
+	}
+
+	limit := defaultSimilarLimit
+	if limitString := query.Get("Limit"); limitString != "" {
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit < 1 || limit > maxSimilarLimit {
+			return fmt.Sprintf("Invalid limit \"%s\".", limitString), http.StatusBadRequest, false
+		}
+	}
+
+	similar, err := books.Similar(
+		searchIn.booksIn(),
+		id,
+		limit,
+	)
+	if err != nil {
+		return "Search failed.", http.StatusBadRequest, false
+	}
+	return similar, http.StatusOK, true
+}
+
+// suggestResponse finds completions of a prefix of a title or an author's name
+// and returns a response, a status code, and bool indicating if the operation was
+// performed successfully. It should be used by Server.suggest.
+func suggestResponse(query url.Values, index *books.PrefixIndex) (interface{}, int, bool) {
+	prefix := query.Get("prefix")
+	if prefix == "" {
+		return "Missing prefix.", http.StatusBadRequest, false
+	}
+
+	limit := defaultSuggestLimit
+	if limitString := query.Get("limit"); limitString != "" {
+		var err error
+		limit, err = strconv.Atoi(limitString)
+		if err != nil || limit < 1 || limit > maxSuggestLimit {
+			return fmt.Sprintf("Invalid limit \"%s\".", limitString), http.StatusBadRequest, false
+		}
+	}
 
-	jsonRes, err := json.MarshalIndent(response, "", "\t")
-	if err == nil { // Just for safety as there are no cyclic structures.
-		fmt.Fprint(w, string(jsonRes))
+	switch field := query.Get("field"); field {
+	case "", "title":
+		return index.SuggestTitles(prefix, limit), http.StatusOK, true
+	case "author":
+		return index.SuggestAuthors(prefix, limit), http.StatusOK, true
+	default:
+		return fmt.Sprintf("Invalid field \"%s\".", field), http.StatusBadRequest, false
 	}
 }
 
+// statsResponse computes statistics of books that match given parameters and
+// returns a response, a status code, and bool indicating if the operation was performed
+// successfully. It should be used by Server.stats.
+func statsResponse(query url.Values, searchIn *SearchIn, searchBy *books.SearchBy) (interface{}, int, bool) {
+	err := decoder.Decode(searchBy, query)
+	if err != nil {
+		return "Unable to decode search query.", http.StatusBadRequest, false
+	}
+
+	stats, err := books.Stats(searchIn.booksIn(), searchBy)
+	if err != nil {
+		return "Failed to compute statistics.", http.StatusBadRequest, false
+	}
+	return stats, http.StatusOK, true
+}
+
+// write writes a JSON response to a request.
+func write(w http.ResponseWriter, r *http.Request, response interface{}, status int) {
+	writeAs(w, r, formats[0], response, status)
+}
+
 // writeError logs the error and writes it to request.
 func writeError(w http.ResponseWriter, r *http.Request, message string, status int) {
-	log.WithFields(
-		log.Fields{
-			"Address": r.RemoteAddr,
-			"Method":  r.Method,
-			"URL":     r.URL.String(),
-		},
-	).Info(message)
+	log.WithFields(logging.Fields(r)).Info(message)
 	http.Error(w, message, status)
 }
 
-// isTitlesOnly checks query parameters to see if TitlesOnly was specified. If
-// so returns true and the query parameters with "TitlesOnly" key removed, else
-// returns false and parameters without change.
-func isTitlesOnly(query url.Values) (bool, url.Values) {
-	titlesOnly := query.Get("TitlesOnly") == "true" || query.Get("TitlesOnly") == "True"
-	if titlesOnly {
-		query.Del("TitlesOnly")
+// isFlagSet checks query parameters to see if the boolean flag with given name
+// was specified. If so returns true and the query parameters with the flag's key
+// removed, else returns false and parameters without change.
+func isFlagSet(query url.Values, name string) (bool, url.Values) {
+	set := query.Get(name) == "true" || query.Get(name) == "True"
+	if set {
+		query.Del(name)
 	}
 
-	return titlesOnly, query
+	return set, query
 }
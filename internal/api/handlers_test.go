@@ -1,13 +1,17 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
 	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
 )
 
 // TestSearchByID tests searching for a book using id.
@@ -48,7 +52,11 @@ func TestSearchByID(t *testing.T) {
 				"\t\"LanguageCode\": \"eng\",\n",
 				"\t\"Pages\": 652,\n",
 				"\t\"RatingsCount\": 1944099,\n",
-				"\t\"ReviewsCount\": 26249\n",
+				"\t\"ReviewsCount\": 26249,\n",
+				"\t\"Series\": \"Harry Potter\",\n",
+				"\t\"SeriesIndex\": \"6\",\n",
+				"\t\"WorkID\": 1,\n",
+				"\t\"WeightedRating\": 4.559849\n",
 				"}",
 			),
 			status: 200,
@@ -108,7 +116,11 @@ func TestSearchByTitle(t *testing.T) {
 				"\t\t\"LanguageCode\": \"eng\",\n",
 				"\t\t\"Pages\": 336,\n",
 				"\t\t\"RatingsCount\": 811,\n",
-				"\t\t\"ReviewsCount\": 86\n",
+				"\t\t\"ReviewsCount\": 86,\n",
+				"\t\t\"Series\": \"\",\n",
+				"\t\t\"SeriesIndex\": \"\",\n",
+				"\t\t\"WorkID\": 3588,\n",
+				"\t\t\"WeightedRating\": 4.285588\n",
 				"\t}\n",
 				"]",
 			),
@@ -130,6 +142,115 @@ func TestSearchByTitle(t *testing.T) {
 	}
 }
 
+// TestSearchBySeries tests searching for books in a series.
+func TestSearchBySeries(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for _, test := range []struct {
+		name   string
+		ids    []int
+		status int
+	}{
+		{
+			name:   "Discworld",
+			ids:    []int{},
+			status: 200,
+		},
+		{
+			name:   "Hitchhiker's%20Guide%20to%20the%20Galaxy",
+			ids:    []int{14, 16, 12, 18},
+			status: 200,
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("name:%s", test.name),
+			func(*testing.T) {
+				recorder := recordResponse(t, fmt.Sprintf("/series/%s", test.name), "/series/{name}", server.searchBySeries)
+				if recorder.Code != test.status {
+					t.Errorf("incorrect status, want: %d, got: %d", test.status, recorder.Code)
+				}
+
+				var result []*books.Book
+				if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+					t.Fatalf("invalid response: %s", err.Error())
+				}
+				if len(result) != len(test.ids) {
+					t.Fatalf("incorrect number of books, want: %d, got: %d", len(test.ids), len(result))
+				}
+				for i, book := range result {
+					if book.ID != test.ids[i] {
+						t.Errorf("incorrect book at %d, want: %d, got: %d", i, test.ids[i], book.ID)
+					}
+				}
+			},
+		)
+	}
+}
+
+// TestSearchEditions tests searching for editions of a work.
+func TestSearchEditions(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for _, test := range []struct {
+		id     string
+		ids    []int
+		status int
+	}{
+		{
+			id:     "a",
+			status: 400,
+		},
+		{
+			id:     "16",
+			ids:    []int{},
+			status: 200,
+		},
+		{
+			id:     "14",
+			ids:    []int{14, 16},
+			status: 200,
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("id:%s", test.id),
+			func(*testing.T) {
+				recorder := recordResponse(t, fmt.Sprintf("/work/%s/editions", test.id), "/work/{id}/editions", server.searchEditions)
+				if recorder.Code != test.status {
+					t.Fatalf("incorrect status, want: %d, got: %d", test.status, recorder.Code)
+				}
+				if test.status != 200 {
+					return
+				}
+
+				var result []*books.Book
+				if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+					t.Fatalf("invalid response: %s", err.Error())
+				}
+				if len(result) != len(test.ids) {
+					t.Fatalf("incorrect number of books, want: %d, got: %d", len(test.ids), len(result))
+				}
+				for i, book := range result {
+					if book.ID != test.ids[i] {
+						t.Errorf("incorrect book at %d, want: %d, got: %d", i, test.ids[i], book.ID)
+					}
+				}
+			},
+		)
+	}
+}
+
 // TestSearch tests searching for a book using a set of parameters.
 func TestSearch(t *testing.T) {
 	datastore, bookTable, deferFn := testhelper.SearchIn(t)
@@ -150,6 +271,33 @@ func TestSearch(t *testing.T) {
 			response:    "Unable to decode search query.\n",
 			status:      400,
 		},
+		{
+			queryParams: "TitlesOnly=true&GroupEditions=true&Authors=Douglas&SortBy=ID",
+			response: fmt.Sprint(
+				"[\n",
+				"\t\"The Ultimate Hitchhiker's Guide: Five Complete Novels and One Story (Hitchhiker's Guide to the Galaxy  #1-5)\",\n",
+				"\t\"The Ultimate Hitchhiker's Guide to the Galaxy\",\n",
+				"\t\"The Hitchhiker's Guide to the Galaxy (Hitchhiker's Guide to the Galaxy  #1)\"\n",
+				"]",
+			),
+			status: 200,
+		},
+		{
+			queryParams: "SortBy=Wrong",
+			response:    "Invalid search query: can't sort by \"Wrong\".\n",
+			status:      400,
+		},
+		{
+			queryParams: "TitlesOnly=true&Authors=Bryson&WeightedRatingFloor=4&SortBy=WeightedRating&Descending=true",
+			response: fmt.Sprint(
+				"[\n",
+				"\t\"A Short History of Nearly Everything\",\n",
+				"\t\"In a Sunburned Country\",\n",
+				"\t\"Bryson's Dictionary of Troublesome Words: A Writer's Guide to Getting It Right\"\n",
+				"]",
+			),
+			status: 200,
+		},
 		{
 			queryParams: "Authors=Arthur&RatingFloor=4.3",
 			response: fmt.Sprint(
@@ -164,7 +312,11 @@ func TestSearch(t *testing.T) {
 				"\t\t\"LanguageCode\": \"eng\",\n",
 				"\t\t\"Pages\": 336,\n",
 				"\t\t\"RatingsCount\": 811,\n",
-				"\t\t\"ReviewsCount\": 86\n",
+				"\t\t\"ReviewsCount\": 86,\n",
+				"\t\t\"Series\": \"\",\n",
+				"\t\t\"SeriesIndex\": \"\",\n",
+				"\t\t\"WorkID\": 3588,\n",
+				"\t\t\"WeightedRating\": 4.285588\n",
 				"\t}\n",
 				"]",
 			),
@@ -186,6 +338,283 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+// TestSearchStream tests that listed books are streamed using chunked encoding,
+// and match the books found by books.Search.
+func TestSearchStream(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	all, err := books.Search(server.searchIn.booksIn(), books.NewSearchBy())
+	if err != nil {
+		t.Fatalf("search failed: %s", err.Error())
+	}
+	expected, _ := json.MarshalIndent(all, "", "\t")
+
+	resp, err := http.Get(httpServer.URL + "/books")
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %s", err.Error())
+	}
+	if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("expected chunked encoding, got: %v", resp.TransferEncoding)
+	}
+	if string(body) != string(expected) {
+		t.Errorf("incorrect response, want: %s, got: %s", expected, body)
+	}
+}
+
+// TestSimilar tests searching for books similar to a book.
+func TestSimilar(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for _, test := range []struct {
+		url      string
+		response string
+		status   int
+	}{
+		{
+			url:      "/book/a/similar",
+			response: "Invalid id \"a\".\n",
+			status:   400,
+		},
+		{
+			url:      "/book/14/similar?Limit=0",
+			response: "Invalid limit \"0\".\n",
+			status:   400,
+		},
+		{
+			url:      "/book/40/similar",
+			response: "Search failed.\n",
+			status:   400,
+		},
+		{
+			url:      "/book/3588/similar",
+			response: "[]",
+			status:   200,
+		},
+		{
+			url: "/book/24/similar?Limit=1",
+			response: fmt.Sprint(
+				"[\n",
+				"\t{\n",
+				"\t\t\"ID\": 25,\n",
+				"\t\t\"Title\": \"I'm a Stranger Here Myself: Notes on Returning to America After Twenty Years Away\",\n",
+				"\t\t\"Authors\": \"Bill Bryson\",\n",
+				"\t\t\"AverageRating\": 3.9,\n",
+				"\t\t\"ISBN\": \"076790382X\",\n",
+				"\t\t\"ISBN13\": \"9780767903820\",\n",
+				"\t\t\"LanguageCode\": \"eng\",\n",
+				"\t\t\"Pages\": 304,\n",
+				"\t\t\"RatingsCount\": 47490,\n",
+				"\t\t\"ReviewsCount\": 2153,\n",
+				"\t\t\"Series\": \"\",\n",
+				"\t\t\"SeriesIndex\": \"\",\n",
+				"\t\t\"WorkID\": 25,\n",
+				"\t\t\"WeightedRating\": 3.9075437\n",
+				"\t}\n",
+				"]",
+			),
+			status: 200,
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("url:%s", test.url),
+			func(*testing.T) {
+				recorder := recordResponse(t, test.url, "/book/{id}/similar", server.similar)
+				if recorder.Code != test.status {
+					t.Errorf("incorrect status, want: %d, got: %d", test.status, recorder.Code)
+				}
+				if recorder.Body.String() != test.response {
+					t.Errorf("incorrect response, want: %s, got: %s", test.response, recorder.Body.String())
+				}
+			},
+		)
+	}
+}
+
+// TestSuggest tests suggesting completions of titles and authors.
+func TestSuggest(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for _, test := range []struct {
+		queryParams string
+		response    string
+		status      int
+	}{
+		{
+			queryParams: "field=title",
+			response:    "Missing prefix.\n",
+			status:      400,
+		},
+		{
+			queryParams: "prefix=a&field=isbn",
+			response:    "Invalid field \"isbn\".\n",
+			status:      400,
+		},
+		{
+			queryParams: "prefix=a&limit=100",
+			response:    "Invalid limit \"100\".\n",
+			status:      400,
+		},
+		{
+			queryParams: "prefix=harry%20potter%20c",
+			response: fmt.Sprint(
+				"[\n",
+				"\t{\n",
+				"\t\t\"Text\": \"Harry Potter Collection (Harry Potter  #1-6)\",\n",
+				"\t\t\"RatingsCount\": 27410\n",
+				"\t}\n",
+				"]",
+			),
+			status: 200,
+		},
+		{
+			queryParams: "prefix=D&field=author&limit=1",
+			response: fmt.Sprint(
+				"[\n",
+				"\t{\n",
+				"\t\t\"Text\": \"Douglas Adams\",\n",
+				"\t\t\"RatingsCount\": 252230\n",
+				"\t}\n",
+				"]",
+			),
+			status: 200,
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("query:%s", test.queryParams),
+			func(*testing.T) {
+				recorder := recordResponse(t, fmt.Sprintf("/suggest?%s", test.queryParams), "/suggest", server.suggest)
+				if recorder.Code != test.status {
+					t.Errorf("incorrect status, want: %d, got: %d", test.status, recorder.Code)
+				}
+				if recorder.Body.String() != test.response {
+					t.Errorf("incorrect response, want: %s, got: %s", test.response, recorder.Body.String())
+				}
+			},
+		)
+	}
+}
+
+// TestSuggestEdited tests rebuilding the suggestions' index after books are
+// edited without using the server.
+func TestSuggestEdited(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+	server := New(nil, searchIn)
+
+	for i, suggested := range []bool{true, false} {
+		if i > 0 {
+			if err := books.Delete(searchIn.booksIn(), 10); err != nil {
+				t.Fatalf("failed to delete book: %s", err.Error())
+			}
+		}
+
+		recorder := recordResponse(t, "/suggest?prefix=harry%20potter%20c", "/suggest", server.suggest)
+		if recorder.Code != http.StatusOK || strings.Contains(recorder.Body.String(), "Harry Potter Collection") != suggested {
+			t.Errorf("%d: expected suggestion: %t, got: %d %s", i, suggested, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+// TestStats tests computing statistics of books matching a set of parameters.
+func TestStats(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for _, test := range []struct {
+		queryParams string
+		response    string
+		status      int
+	}{
+		{
+			queryParams: "Wrong=10",
+			response:    "Unable to decode search query.\n",
+			status:      400,
+		},
+		{
+			queryParams: "Authors=Arthur&RatingFloor=4.3",
+			response: fmt.Sprint(
+				"{\n",
+				"\t\"Count\": 1,\n",
+				"\t\"AverageRating\": {\n",
+				"\t\t\"Min\": 4.31,\n",
+				"\t\t\"Max\": 4.31,\n",
+				"\t\t\"Mean\": 4.31,\n",
+				"\t\t\"Median\": 4.31\n",
+				"\t},\n",
+				"\t\"Pages\": {\n",
+				"\t\t\"Min\": 336,\n",
+				"\t\t\"Max\": 336,\n",
+				"\t\t\"Mean\": 336,\n",
+				"\t\t\"Median\": 336\n",
+				"\t},\n",
+				"\t\"RatingsCount\": {\n",
+				"\t\t\"Min\": 811,\n",
+				"\t\t\"Max\": 811,\n",
+				"\t\t\"Mean\": 811,\n",
+				"\t\t\"Median\": 811\n",
+				"\t},\n",
+				"\t\"ReviewsCount\": {\n",
+				"\t\t\"Min\": 86,\n",
+				"\t\t\"Max\": 86,\n",
+				"\t\t\"Mean\": 86,\n",
+				"\t\t\"Median\": 86\n",
+				"\t}\n",
+				"}",
+			),
+			status: 200,
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("query:%s", test.queryParams),
+			func(*testing.T) {
+				recorder := recordResponse(t, fmt.Sprintf("/stats?%s", test.queryParams), "/stats", server.stats)
+				if recorder.Code != test.status {
+					t.Errorf("incorrect status, want: %d, got: %d", test.status, recorder.Code)
+				}
+				if recorder.Body.String() != test.response {
+					t.Errorf("incorrect response, want: %s, got: %s", test.response, recorder.Body.String())
+				}
+			},
+		)
+	}
+}
+
 // recordResponse performs a test request with a ResponseRecoder and returns the
 // recoder.
 func recordResponse(t *testing.T, url, muxURL string, helper http.HandlerFunc) *httptest.ResponseRecorder {
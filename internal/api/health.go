@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/datastore"
+)
+
+// Health is the response to a health or readiness check.
+type Health struct {
+	Status string
+}
+
+// Version describes the running server and its datastore.
+type Version struct {
+	Version       string    // Version of the server's build.
+	SchemaVersion int       // Version of the datastore's schema.
+	ImportedAt    time.Time // Time the dataset was imported.
+	ModifiedAt    time.Time // Time books were last modified, either imported or edited.
+	Revision      int       // Number of times books were edited since the dataset was imported.
+	Books         int       // Number of books in the datastore.
+}
+
+// healthz is a handler for GET /healthz endpoint.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	write(w, r, &Health{Status: "ok"}, http.StatusOK)
+}
+
+// readyz is a handler for GET /readyz endpoint.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := readyzResponse(s.searchIn)
+	if ok {
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// version is a handler for GET /version endpoint.
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := versionResponse(s.searchIn, s.buildVersion())
+	if ok {
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// readyzResponse checks that the datastore is reachable, has the expected schema
+// version and contains books, and returns a response, a status code, and bool
+// indicating if the server is ready. It should be used by Server.readyz.
+func readyzResponse(searchIn *SearchIn) (interface{}, int, bool) {
+	if err := searchIn.Datastore.Ping(); err != nil {
+		return "Datastore is unreachable.", http.StatusServiceUnavailable, false
+	}
+
+	metadata, err := datastore.ReadMetadata(searchIn.Datastore, searchIn.MetadataTable)
+	if err != nil {
+		return "Datastore has no metadata, recreate it using a dataset.", http.StatusServiceUnavailable, false
+	}
+	if metadata.SchemaVersion != datastore.SchemaVersion {
+		return fmt.Sprintf(
+			"Datastore has schema version %d, expected %d.", metadata.SchemaVersion, datastore.SchemaVersion,
+		), http.StatusServiceUnavailable, false
+	}
+
+	count, err := countBooks(searchIn)
+	if err != nil {
+		return "Failed to count books.", http.StatusServiceUnavailable, false
+	}
+	if count == 0 {
+		return "Datastore has no books.", http.StatusServiceUnavailable, false
+	}
+
+	return &Health{Status: "ready"}, http.StatusOK, true
+}
+
+// versionResponse returns a response describing given build version and the
+// datastore, a status code, and bool indicating if the operation was performed
+// successfully. It should be used by Server.version.
+func versionResponse(searchIn *SearchIn, buildVersion string) (interface{}, int, bool) {
+	metadata, err := datastore.ReadMetadata(searchIn.Datastore, searchIn.MetadataTable)
+	if err != nil {
+		return "Failed to read datastore's metadata.", http.StatusInternalServerError, false
+	}
+
+	count, err := countBooks(searchIn)
+	if err != nil {
+		return "Failed to count books.", http.StatusInternalServerError, false
+	}
+
+	return &Version{
+		Version:       buildVersion,
+		SchemaVersion: metadata.SchemaVersion,
+		ImportedAt:    metadata.ImportedAt,
+		ModifiedAt:    metadata.ModifiedAt,
+		Revision:      metadata.Revision,
+		Books:         count,
+	}, http.StatusOK, true
+}
+
+// countBooks returns the number of books in the datastore.
+func countBooks(searchIn *SearchIn) (int, error) {
+	var count int
+	err := searchIn.Datastore.QueryRow(fmt.Sprintf("select count(*) from %s;", searchIn.BookTable)).Scan(&count)
+	return count, err
+}
+
+// buildVersion returns the version of the server's build, or "unknown".
+func (s *Server) buildVersion() string {
+	if s.cfg == nil || s.cfg.Version == "" {
+		return "unknown"
+	}
+	return s.cfg.Version
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/datastore"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestHealthz tests the liveness check.
+func TestHealthz(t *testing.T) {
+	server := New(nil, nil)
+
+	recorder := recordResponse(t, "/healthz", "/healthz", server.healthz)
+	expected := "{\n\t\"Status\": \"ok\"\n}"
+	if recorder.Code != http.StatusOK || recorder.Body.String() != expected {
+		t.Errorf("expected: %d %s, got: %d %s", http.StatusOK, expected, recorder.Code, recorder.Body.String())
+	}
+}
+
+// TestReadyz tests the readiness check with ready and unready datastores.
+func TestReadyz(t *testing.T) {
+	db, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: db,
+		BookTable: bookTable,
+	})
+
+	for _, test := range []struct {
+		statement string // Executed before the check.
+		status    int
+		body      string
+	}{
+		{
+			statement: "",
+			status:    http.StatusOK,
+			body:      "{\n\t\"Status\": \"ready\"\n}",
+		},
+		{
+			statement: "update metadata set value = '0' where key = 'schemaVersion';",
+			status:    http.StatusServiceUnavailable,
+			body:      "Datastore has schema version 0, expected 1.\n",
+		},
+		{
+			statement: "drop table metadata;",
+			status:    http.StatusServiceUnavailable,
+			body:      "Datastore has no metadata, recreate it using a dataset.\n",
+		},
+	} {
+		if test.statement != "" {
+			if _, err := db.Exec(test.statement); err != nil {
+				t.Fatalf("failed to modify datastore: %s", err.Error())
+			}
+		}
+
+		recorder := recordResponse(t, "/readyz", "/readyz", server.readyz)
+		if recorder.Code != test.status || recorder.Body.String() != test.body {
+			t.Errorf("expected: %d %q, got: %d %q", test.status, test.body, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+// TestReadyzMigrated tests the readiness check with a datastore created before
+// books had works and metadata was stored, which is migrated when opened.
+func TestReadyzMigrated(t *testing.T) {
+	db, _, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	for _, statement := range []string{
+		"create table baseline as select id, title, authors, averageRating, isbn, isbn13, languageCode, " +
+			"pages, ratingsCount, reviewsCount from books;",
+		"drop table metadata;",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("failed to create a baseline datastore: %s", err.Error())
+		}
+	}
+
+	migrated, err := datastore.Open(&datastore.Config{
+		Driver:    "sqlite3",
+		Datastore: "testDatastore.db",
+		BookTable: "baseline",
+	})
+	if err != nil {
+		t.Fatalf("failed to open a baseline datastore: %s", err.Error())
+	}
+	defer migrated.Close()
+
+	server := New(nil, &SearchIn{
+		Datastore: migrated,
+		BookTable: "baseline",
+	})
+
+	recorder := recordResponse(t, "/readyz", "/readyz", server.readyz)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected a ready migrated datastore, got: %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+// TestReadyzEmpty tests the readiness check with a datastore without books.
+func TestReadyzEmpty(t *testing.T) {
+	db, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	if _, err := db.Exec("delete from books;"); err != nil {
+		t.Fatalf("failed to delete books: %s", err.Error())
+	}
+
+	server := New(nil, &SearchIn{
+		Datastore: db,
+		BookTable: bookTable,
+	})
+
+	recorder := recordResponse(t, "/readyz", "/readyz", server.readyz)
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Body.String() != "Datastore has no books.\n" {
+		t.Errorf("expected unready empty datastore, got: %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+// TestVersion tests reporting build and datastore information.
+func TestVersion(t *testing.T) {
+	db, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(&Config{Version: "v2.1.0"}, &SearchIn{
+		Datastore: db,
+		BookTable: bookTable,
+	})
+
+	recorder := recordResponse(t, "/version", "/version", server.version)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status: %d, got: %d", http.StatusOK, recorder.Code)
+	}
+
+	version := new(Version)
+	if err := json.Unmarshal(recorder.Body.Bytes(), version); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+
+	if version.Version != "v2.1.0" || version.SchemaVersion != datastore.SchemaVersion || version.Books != 19 {
+		t.Errorf("incorrect version: %+v", *version)
+	}
+	if time.Since(version.ImportedAt) > time.Minute {
+		t.Errorf("incorrect import time: %s", version.ImportedAt)
+	}
+}
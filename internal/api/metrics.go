@@ -0,0 +1,64 @@
+package api
+
+import (
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/metrics"
+)
+
+// serverMetrics holds the metrics exposed by the server's /metrics endpoint.
+type serverMetrics struct {
+	registry *metrics.Registry
+	http     *metrics.HTTP      // Requests served.
+	queries  *metrics.Histogram // Durations of books queries by shape.
+	results  *metrics.Counter   // Lookups of cached search results by result.
+}
+
+// newServerMetrics registers the server's metrics, including connections of
+// the datastore in searchIn, if not nil.
+func newServerMetrics(searchIn *SearchIn) *serverMetrics {
+	registry := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: registry,
+		http:     metrics.NewHTTP(registry, "bfr_api"),
+		queries: registry.Histogram(
+			"bfr_books_query_duration_seconds",
+			"Durations of datastore queries in seconds, by shape of query.",
+			metrics.DefaultBuckets,
+			"shape",
+		),
+		results: registry.Counter(
+			"bfr_api_search_cache_lookups_total",
+			"Lookups of cached search results, by result, either hit or miss.",
+			"result",
+		),
+	}
+
+	if searchIn != nil {
+		registry.GaugeFunc(
+			"bfr_datastore_open_connections",
+			"Number of open connections to the datastore.",
+			func() float64 { return float64(searchIn.Datastore.Stats().OpenConnections) },
+		)
+		registry.GaugeFunc(
+			"bfr_datastore_in_use_connections",
+			"Number of connections to the datastore that are in use.",
+			func() float64 { return float64(searchIn.Datastore.Stats().InUse) },
+		)
+	}
+	return m
+}
+
+// observeSearchCache records a lookup of cached search results.
+func (m *serverMetrics) observeSearchCache(hit bool) {
+	if hit {
+		m.results.Inc("hit")
+	} else {
+		m.results.Inc("miss")
+	}
+}
+
+// observeQuery records the duration of a books query.
+func (m *serverMetrics) observeQuery(shape string, duration time.Duration) {
+	m.queries.Observe(duration.Seconds(), shape)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestMetrics tests that requests and queries are recorded and exposed.
+func TestMetrics(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(&Config{}, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for _, url := range []string{"/book/1", "/book/3", "/book/x", "/books?Authors=Bill%20Bryson"} {
+		request, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err.Error())
+		}
+		serveRecorded(server, request)
+	}
+
+	request, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	recorder := serveRecorded(server, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status: %d, got: %d", http.StatusOK, recorder.Code)
+	}
+
+	for _, line := range []string{
+		`bfr_api_requests_total{route="/book/{id}",method="GET",status="200"} 2`,
+		`bfr_api_requests_total{route="/book/{id}",method="GET",status="400"} 1`,
+		`bfr_api_requests_total{route="/books",method="GET",status="200"} 1`,
+		`bfr_api_request_duration_seconds_count{route="/book/{id}",method="GET"} 3`,
+		`bfr_books_query_duration_seconds_count{shape="byID"} 2`,
+		`bfr_books_query_duration_seconds_count{shape="stream"} 1`,
+		`# TYPE bfr_datastore_open_connections gauge`,
+	} {
+		if !strings.Contains(recorder.Body.String(), line+"\n") {
+			t.Errorf("expected metrics to contain: %s, got:\n%s", line, recorder.Body.String())
+		}
+	}
+}
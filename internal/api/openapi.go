@@ -0,0 +1,324 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// searchDescriptions describes query parameters of books.SearchBy's fields.
+// Each field of SearchBy must have a description.
+var searchDescriptions = map[string]string{
+	"TitleHas":            "A sub-string that must exist in the title.",
+	"Authors":             "Must have one of these authors.",
+	"LanguageCode":        "Must be written in one of these languages.",
+	"ISBN":                "10 digit ISBN.",
+	"ISBN13":              "13 digit ISBN.",
+	"Series":              "Must belong to this series, ignoring case.",
+	"RatingCeil":          "Rating must be less than or equal.",
+	"RatingFloor":         "Rating must be higher than.",
+	"WeightedRatingFloor": "Weighted rating must be higher than. Ignored if 0 or less.",
+	"PagesCeil":           "Number of pages must be less than or equal.",
+	"PagesFloor":          "Number of pages must be higher than.",
+	"RatingsCountCeil":    "Number of ratings must be less than or equal.",
+	"RatingsCountFloor":   "Number of ratings must be higher than.",
+	"ReviewsCountCeil":    "Number of reviews must be less than or equal.",
+	"ReviewsCountFloor":   "Number of reviews must be higher than.",
+	"SortBy":              "Name of a book field to sort results by.",
+	"Descending":          "If specified, sorts results in descending order.",
+}
+
+// openAPI is a handler for GET /openapi.json endpoint.
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.spec)
+}
+
+// docs is a handler for GET /docs endpoint.
+func (s *Server) docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
+
+// newSpec generates an OpenAPI 3 document describing given routes, and returns
+// it encoded as JSON.
+func newSpec(routes []*route, version string) []byte {
+	schemas := make(map[string]interface{})
+	paths := make(map[string]interface{})
+	for _, route := range routes {
+		item, ok := paths[route.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[route.path] = item
+		}
+		item[strings.ToLower(route.method)] = newOperation(route, schemas)
+	}
+
+	spec, err := json.MarshalIndent(map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "bfr",
+			"description": "A REST API that enables searching for books using a set of parameters.",
+			"version":     version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{
+					"type":   "http",
+					"scheme": "bearer",
+				},
+				"apiKey": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+		},
+	}, "", "\t")
+	if err != nil { // Just for safety as the document is built from maps and strings.
+		panic(err)
+	}
+	return spec
+}
+
+// newOperation returns an OpenAPI operation object describing route, and adds
+// schemas of types it uses to schemas.
+func newOperation(route *route, schemas map[string]interface{}) map[string]interface{} {
+	doc := route.doc
+	operation := map[string]interface{}{
+		"summary": doc.summary,
+	}
+	description := doc.description
+	if route.alias {
+		description = strings.TrimSpace(fmt.Sprintf("Alias of %s. %s", route.canonicalPath(), description))
+	}
+	if description != "" {
+		operation["description"] = description
+	}
+	if route.version != "" {
+		operation["tags"] = []string{route.version}
+	}
+	if route.deprecated != nil {
+		operation["deprecated"] = true
+	}
+
+	parameters := make([]interface{}, 0)
+	for _, p := range doc.parameters {
+		parameters = append(parameters, newParameter(p))
+	}
+	if doc.search {
+		for _, p := range searchParameters() {
+			parameters = append(parameters, newParameter(p))
+		}
+	}
+	if len(parameters) != 0 {
+		operation["parameters"] = parameters
+	}
+
+	if doc.body != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": objectSchema(reflect.TypeOf(doc.body).Elem(), false, schemas),
+				},
+			},
+		}
+	}
+
+	success := map[string]interface{}{
+		"description": http.StatusText(doc.status),
+	}
+	if len(doc.responses) != 0 {
+		contentType := doc.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		var schema map[string]interface{}
+		if len(doc.responses) == 1 {
+			schema = typeSchema(reflect.TypeOf(doc.responses[0]), schemas)
+		} else {
+			oneOf := make([]interface{}, len(doc.responses))
+			for i, response := range doc.responses {
+				oneOf[i] = typeSchema(reflect.TypeOf(response), schemas)
+			}
+			schema = map[string]interface{}{"oneOf": oneOf}
+		}
+		content := map[string]interface{}{
+			contentType: map[string]interface{}{"schema": schema},
+		}
+		if doc.formatted {
+			for _, f := range formats {
+				if _, ok := content[f.contentType]; !ok {
+					content[f.contentType] = map[string]interface{}{
+						"schema": map[string]interface{}{"type": "string"},
+					}
+				}
+			}
+		}
+		success["content"] = content
+	}
+	responses := map[string]interface{}{
+		strconv.Itoa(doc.status): success,
+		"default": map[string]interface{}{
+			"description": "A message describing why the request failed.",
+			"content": map[string]interface{}{
+				"text/plain": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	}
+	if route.cached() {
+		responses[strconv.Itoa(http.StatusNotModified)] = map[string]interface{}{
+			"description": "The response identified by If-None-Match or If-Modified-Since is still current.",
+		}
+	}
+	operation["responses"] = responses
+
+	switch route.role {
+	case auth.Admin:
+		operation["security"] = []interface{}{
+			map[string]interface{}{"bearer": []string{}},
+			map[string]interface{}{"apiKey": []string{}},
+		}
+	case auth.ReadOnly:
+		operation["security"] = []interface{}{
+			map[string]interface{}{}, // A key is optional unless the server is private.
+			map[string]interface{}{"bearer": []string{}},
+			map[string]interface{}{"apiKey": []string{}},
+		}
+	}
+
+	return operation
+}
+
+// newParameter returns an OpenAPI parameter object describing p.
+func newParameter(p *parameter) map[string]interface{} {
+	schema := map[string]interface{}{"type": p.kind}
+	if p.kind == "array" {
+		schema["items"] = map[string]interface{}{"type": "string"}
+	}
+	if p.enum != nil {
+		schema["enum"] = p.enum
+	}
+
+	return map[string]interface{}{
+		"name":        p.name,
+		"in":          p.in,
+		"required":    p.required,
+		"description": p.description,
+		"schema":      schema,
+	}
+}
+
+// searchParameters returns query parameters of books.SearchBy's fields.
+func searchParameters() []*parameter {
+	t := reflect.TypeOf(books.SearchBy{})
+	parameters := make([]*parameter, t.NumField())
+	for i := range parameters {
+		field := t.Field(i)
+		parameters[i] = &parameter{
+			name:        field.Name,
+			in:          "query",
+			kind:        typeSchema(field.Type, nil)["type"].(string),
+			description: searchDescriptions[field.Name],
+		}
+		if field.Name == "SortBy" {
+			parameters[i].enum = books.SortFields()
+		}
+	}
+	return parameters
+}
+
+// typeSchema returns an OpenAPI schema of type t. Named structs are added to
+// schemas, if not nil, and referenced.
+func typeSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), schemas)
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.Elem(), schemas),
+		}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": true}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" || schemas == nil {
+			return objectSchema(t, true, schemas)
+		}
+
+		if _, ok := schemas[t.Name()]; !ok {
+			schemas[t.Name()] = nil // Reserve the name for recursive types.
+			schemas[t.Name()] = objectSchema(t, true, schemas)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// objectSchema returns an OpenAPI schema of struct type t, with fields of
+// embedded structs inlined. If required is true, all fields are marked as
+// required.
+func objectSchema(t reflect.Type, required bool, schemas map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	names := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded := objectSchema(field.Type, required, schemas)
+			for name, property := range embedded["properties"].(map[string]interface{}) {
+				properties[name] = property
+			}
+			if required {
+				names = append(names, embedded["required"].([]string)...)
+			}
+			continue
+		}
+
+		name := field.Name
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		properties[name] = typeSchema(field.Type, schemas)
+		names = append(names, name)
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if required {
+		schema["required"] = names
+	}
+	return schema
+}
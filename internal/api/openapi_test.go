@@ -0,0 +1,301 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// TestOpenAPIRoutes tests that the OpenAPI document describes exactly the
+// routes served by the router.
+func TestOpenAPIRoutes(t *testing.T) {
+	server := New(nil, nil)
+	spec := readSpec(t, server)
+
+	served := make([]string, 0)
+	err := server.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			served = append(served, method+" "+path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk routes: %s", err.Error())
+	}
+
+	documented := make([]string, 0)
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(served)
+	sort.Strings(documented)
+	if !reflect.DeepEqual(served, documented) {
+		t.Errorf("expected documented routes: %v, got: %v", served, documented)
+	}
+}
+
+// TestOpenAPISearchParameters tests that each field of books.SearchBy is
+// described.
+func TestOpenAPISearchParameters(t *testing.T) {
+	fields := reflect.TypeOf(books.SearchBy{})
+	for i := 0; i < fields.NumField(); i++ {
+		if searchDescriptions[fields.Field(i).Name] == "" {
+			t.Errorf("expected a description of %s", fields.Field(i).Name)
+		}
+	}
+
+	if len(searchDescriptions) != fields.NumField() {
+		t.Errorf("expected %d descriptions, got: %d", fields.NumField(), len(searchDescriptions))
+	}
+}
+
+// TestOpenAPIResponses tests that GET operations accept each of their
+// documented parameters, and respond with documented status codes and bodies.
+func TestOpenAPIResponses(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+	spec := readSpec(t, server)
+
+	book, err := books.SearchByID(server.searchIn.booksIn(), 1)
+	if err != nil {
+		t.Fatalf("failed to find book: %s", err.Error())
+	}
+	pathValues := map[string]string{
+		"/book/{id}":          "1",
+		"/book/{id}/similar":  "1",
+		"/books/{title}":      book.Title,
+		"/series/{name}":      book.Series,
+		"/work/{id}/editions": fmt.Sprint(book.WorkID),
+	}
+
+	for path, item := range spec["paths"].(map[string]interface{}) {
+		operation, ok := item.(map[string]interface{})["get"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		base := path
+		for template, value := range pathValues {
+			if strings.TrimPrefix(path, "/"+v1) == template {
+				base = path[:strings.Index(path, "{")] + url.PathEscape(value) + path[strings.Index(path, "}")+1:]
+			}
+		}
+		if strings.Contains(base, "{") {
+			t.Fatalf("expected a value of path parameters of %s", path)
+		}
+
+		// Required parameters are sent with all requests, and each optional
+		// one is sent with a single request.
+		required := url.Values{}
+		optional := make([]url.Values, 0)
+		parameters, _ := operation["parameters"].([]interface{})
+		for _, p := range parameters {
+			p := p.(map[string]interface{})
+			if p["in"] != "query" {
+				continue
+			}
+
+			value := sampleValue(p["name"].(string), p["schema"].(map[string]interface{}))
+			if p["required"] == true {
+				required.Set(p["name"].(string), value)
+			} else {
+				optional = append(optional, url.Values{p["name"].(string): {value}})
+			}
+		}
+
+		for _, query := range append([]url.Values{{}}, optional...) {
+			for name, values := range required {
+				query[name] = values
+			}
+
+			target := base
+			if len(query) != 0 {
+				target += "?" + query.Encode()
+			}
+
+			recorder := serveRequest(t, server, "GET", target, "", "")
+			responses := operation["responses"].(map[string]interface{})
+			response, ok := responses[fmt.Sprint(recorder.Code)].(map[string]interface{})
+			if !ok {
+				t.Errorf("GET %s: expected a documented status, got: %d %s", target, recorder.Code, recorder.Body.String())
+				continue
+			}
+
+			content, ok := response["content"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			media, ok := content["application/json"].(map[string]interface{})
+			if !ok {
+				if _, ok := content[strings.Split(recorder.Header().Get("Content-Type"), ";")[0]]; !ok {
+					t.Errorf("GET %s: expected a documented content type, got: %s", target, recorder.Header().Get("Content-Type"))
+				}
+				continue
+			}
+
+			var body interface{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Errorf("GET %s: failed to decode response: %s", target, err.Error())
+				continue
+			}
+			if err := validate(spec, media["schema"].(map[string]interface{}), body); err != nil {
+				t.Errorf("GET %s: response doesn't match documented schema: %s", target, err.Error())
+			}
+		}
+	}
+}
+
+// readSpec returns the decoded OpenAPI document served by server.
+func readSpec(t *testing.T, server *Server) map[string]interface{} {
+	t.Helper()
+	recorder := serveRequest(t, server, "GET", "/openapi.json", "", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status: %d, got: %d", http.StatusOK, recorder.Code)
+	}
+
+	var spec map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("failed to decode OpenAPI document: %s", err.Error())
+	}
+	return spec
+}
+
+// sampleValue returns a valid value of a query parameter with given name and
+// schema.
+func sampleValue(name string, schema map[string]interface{}) string {
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		return enum[0].(string)
+	}
+
+	switch schema["type"] {
+	case "integer", "number":
+		return "1"
+	case "boolean":
+		return "true"
+	default:
+		if name == "prefix" {
+			return "harry"
+		}
+		return "a"
+	}
+}
+
+// validate checks that a decoded JSON value matches an OpenAPI schema. Objects
+// must have exactly the properties of their schema, unless it allows additional
+// properties.
+func validate(spec, schema map[string]interface{}, value interface{}) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		referenced, ok := schemas[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("undefined schema %s", ref)
+		}
+		return validate(spec, referenced, value)
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		errs := make([]string, 0)
+		for _, option := range oneOf {
+			err := validate(spec, option.(map[string]interface{}), value)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("matches none of the schemas: %s", strings.Join(errs, "; "))
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected an object, got: %v", value)
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range object {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == true {
+					continue
+				}
+				return fmt.Errorf("undocumented property %s", name)
+			}
+			if err := validate(spec, propertySchema, property); err != nil {
+				return fmt.Errorf("%s: %s", name, err.Error())
+			}
+		}
+
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("missing property %s", name)
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected an array, got: %v", value)
+		}
+		for i, item := range array {
+			if err := validate(spec, schema["items"].(map[string]interface{}), item); err != nil {
+				return fmt.Errorf("[%d]: %s", i, err.Error())
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got: %v", value)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("expected a date-time, got: %s", s)
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("expected an integer, got: %v", value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("expected a number, got: %v", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected a boolean, got: %v", value)
+		}
+	default:
+		return fmt.Errorf("unknown type %v", schema["type"])
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+)
+
+// sweepInterval is the minimum time between removals of idle buckets.
+const sweepInterval = time.Minute
+
+// limiter is a token bucket rate limiter that keeps a bucket per client. Each
+// bucket holds at most burst tokens and is refilled at rate tokens per second,
+// and each request takes a token.
+type limiter struct {
+	rate  float64 // Tokens added to a bucket per second.
+	burst int     // Capacity of a bucket.
+
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time // Returns current time, replaced in tests.
+}
+
+// bucket holds a client's tokens.
+type bucket struct {
+	tokens float64   // Tokens available at last.
+	last   time.Time // Time tokens were last updated.
+}
+
+// newLimiter returns a limiter that allows rate requests per second, and at
+// most burst requests at once, per client. A burst less than 1 is treated as 1.
+func newLimiter(rate float64, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &limiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// take takes a token from client's bucket. It returns true if a token was
+// available, the number of tokens remaining, and if no token was available,
+// the time until one is.
+func (l *limiter) take(client string) (bool, int, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := l.refill(client)
+	if b.tokens < 1 {
+		return false, 0, l.wait(b)
+	}
+
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// available returns true if client's bucket has a token, without taking it,
+// and if it doesn't, the time until it does.
+func (l *limiter) available(client string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	b := l.refill(client)
+	if b.tokens < 1 {
+		return false, l.wait(b)
+	}
+	return true, 0
+}
+
+// refill returns client's bucket, after adding the tokens it gained since it
+// was last updated. Must be called with the mutex held.
+func (l *limiter) refill(client string) *bucket {
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	return b
+}
+
+// wait returns the time until bucket b has a token.
+func (l *limiter) wait(b *bucket) time.Duration {
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep removes buckets that would have been refilled by now, since they are
+// identical to new ones. Buckets are swept at most once every sweepInterval.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	full := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// limit wraps a handler so that requests are rate limited per client, if the
+// server is configured with a rate limit. Clients are identified by their API
+// key if they are authenticated, and by their remote address otherwise. The
+// remaining quota is reported in X-RateLimit-Limit and X-RateLimit-Remaining
+// headers, and rejected requests get a Retry-After header.
+func (s *Server) limit(handler http.HandlerFunc) http.HandlerFunc {
+	if s.limiter == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, remaining, wait := s.limiter.take(client(r))
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(s.limiter.burst))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !allowed {
+			tooManyRequests(w, r, wait)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// tooManyRequests rejects a request that exceeded a rate limit, and tells the
+// client to retry after wait.
+func tooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, r, "Too many requests.", http.StatusTooManyRequests)
+}
+
+// client returns an identifier of the client that sent a request.
+func client(r *http.Request) string {
+	if key, ok := auth.FromContext(r.Context()); ok {
+		return fmt.Sprintf("key:%d", key.ID)
+	}
+	return address(r)
+}
+
+// address returns an identifier of the remote address that sent a request.
+func address(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "address:" + host
+}
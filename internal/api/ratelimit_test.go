@@ -0,0 +1,188 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestLimiter tests taking tokens from, and refilling of buckets.
+func TestLimiter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i, test := range []struct {
+		advance   time.Duration
+		client    string
+		allowed   bool
+		remaining int
+		wait      time.Duration
+	}{
+		{advance: 0, client: "a", allowed: true, remaining: 2},
+		{advance: 0, client: "a", allowed: true, remaining: 1},
+		{advance: 0, client: "a", allowed: true, remaining: 0},
+		{advance: 0, client: "a", allowed: false, remaining: 0, wait: 500 * time.Millisecond},
+		{advance: 0, client: "b", allowed: true, remaining: 2},
+		{advance: 250 * time.Millisecond, client: "a", allowed: false, remaining: 0, wait: 250 * time.Millisecond},
+		{advance: 250 * time.Millisecond, client: "a", allowed: true, remaining: 0},
+		{advance: 10 * time.Second, client: "a", allowed: true, remaining: 2},
+	} {
+		now = now.Add(test.advance)
+		allowed, remaining, wait := l.take(test.client)
+		if allowed != test.allowed || remaining != test.remaining || wait != test.wait {
+			t.Errorf(
+				"test %d: expected: (%t, %d, %s), got: (%t, %d, %s)",
+				i, test.allowed, test.remaining, test.wait, allowed, remaining, wait,
+			)
+		}
+	}
+
+	now = now.Add(time.Hour)
+	l.take("c")
+	if len(l.buckets) != 1 {
+		t.Errorf("expected idle buckets to be swept, got %d buckets", len(l.buckets))
+	}
+}
+
+// TestLimit tests rate limiting of requests by remote address and API key.
+func TestLimit(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	keys, err := auth.New(datastore, "keys")
+	if err != nil {
+		t.Fatalf("failed to create keys: %s", err.Error())
+	}
+	reader, _, err := keys.Add("reader", auth.ReadOnly)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+
+	server := New(
+		&Config{
+			Keys:      keys,
+			RateLimit: 0.01,
+			RateBurst: 2,
+		},
+		&SearchIn{
+			Datastore: datastore,
+			BookTable: bookTable,
+		},
+	)
+
+	for i, test := range []struct {
+		address    string
+		key        string
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{address: "10.0.0.1:1000", status: http.StatusOK, remaining: "1"},
+		{address: "10.0.0.1:2000", status: http.StatusOK, remaining: "0"},
+		{address: "10.0.0.1:1000", status: http.StatusTooManyRequests, remaining: "0", retryAfter: "100"},
+		{address: "10.0.0.2:1000", status: http.StatusOK, remaining: "1"},
+		{address: "10.0.0.1:1000", key: reader, status: http.StatusOK, remaining: "1"},
+		{address: "10.0.0.2:1000", key: reader, status: http.StatusOK, remaining: "0"},
+		{address: "10.0.0.3:1000", key: reader, status: http.StatusTooManyRequests, remaining: "0", retryAfter: "100"},
+	} {
+		t.Run(
+			fmt.Sprintf("test: %d", i),
+			func(t *testing.T) {
+				request, err := http.NewRequest("GET", "/book/1", nil)
+				if err != nil {
+					t.Fatalf("failed to create request: %s", err.Error())
+				}
+				request.RemoteAddr = test.address
+				if test.key != "" {
+					request.Header.Set("X-API-Key", test.key)
+				}
+
+				recorder := serveRecorded(server, request)
+				if recorder.Code != test.status {
+					t.Errorf("expected status: %d, got: %d", test.status, recorder.Code)
+				}
+
+				for header, expected := range map[string]string{
+					"X-RateLimit-Limit":     "2",
+					"X-RateLimit-Remaining": test.remaining,
+					"Retry-After":           test.retryAfter,
+				} {
+					if value := recorder.Header().Get(header); value != expected {
+						t.Errorf("expected %s: %q, got: %q", header, expected, value)
+					}
+				}
+			},
+		)
+	}
+}
+
+// TestLimitFailures tests rate limiting failed authentications by remote
+// address, separately from other requests.
+func TestLimitFailures(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	keys, err := auth.New(datastore, "keys")
+	if err != nil {
+		t.Fatalf("failed to create keys: %s", err.Error())
+	}
+	reader, _, err := keys.Add("reader", auth.ReadOnly)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+
+	server := New(
+		&Config{
+			Keys:         keys,
+			PrivateReads: true,
+			RateLimit:    0.01,
+			RateBurst:    2,
+		},
+		&SearchIn{
+			Datastore: datastore,
+			BookTable: bookTable,
+		},
+	)
+
+	for i, test := range []struct {
+		address    string
+		key        string
+		status     int
+		retryAfter string
+	}{
+		{address: "10.0.0.1:1000", key: "guess", status: http.StatusUnauthorized},
+		{address: "10.0.0.1:2000", status: http.StatusUnauthorized},
+		{address: "10.0.0.1:1000", key: "guess", status: http.StatusTooManyRequests, retryAfter: "100"},
+		{address: "10.0.0.1:1000", key: reader, status: http.StatusTooManyRequests, retryAfter: "100"},
+		{address: "10.0.0.2:1000", key: reader, status: http.StatusOK},
+		{address: "10.0.0.2:1000", key: reader, status: http.StatusOK},
+		{address: "10.0.0.2:1000", key: "guess", status: http.StatusUnauthorized},
+	} {
+		t.Run(
+			fmt.Sprintf("test: %d", i),
+			func(t *testing.T) {
+				request, err := http.NewRequest("GET", "/book/1", nil)
+				if err != nil {
+					t.Fatalf("failed to create request: %s", err.Error())
+				}
+				request.RemoteAddr = test.address
+				if test.key != "" {
+					request.Header.Set("X-API-Key", test.key)
+				}
+
+				recorder := serveRecorded(server, request)
+				if recorder.Code != test.status {
+					t.Errorf("expected status: %d, got: %d", test.status, recorder.Code)
+				}
+				if value := recorder.Header().Get("Retry-After"); value != test.retryAfter {
+					t.Errorf("expected Retry-After: %q, got: %q", test.retryAfter, value)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,426 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+	"github.com/sudo-sturbia/bfr/v2/internal/graphql"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// route is an endpoint of the API. Routes are used both to register handlers,
+// and to generate the API's OpenAPI document.
+type route struct {
+	method  string
+	path    string           // Path template, e.g. "/book/{id}".
+	role    auth.Role        // Role required to use the route, empty if the route is open to all.
+	version string           // Version of the API the route belongs to, empty if the route isn't versioned.
+	handler http.HandlerFunc // Handler of requests, not including authorization and rate limiting.
+	doc     *operation       // Documentation of the route.
+
+	alias      bool         // If true, the route is served under its bare path, see versioned.
+	deprecated *Deprecation // Deprecation of the route's version, nil if it isn't deprecated.
+}
+
+// cached returns true if responses of route can be cached, see Server.cache.
+// Only reads are cached.
+func (route *route) cached() bool {
+	return route.method == http.MethodGet && route.role == auth.ReadOnly
+}
+
+// operation documents a route for the OpenAPI document.
+type operation struct {
+	summary     string
+	description string
+	parameters  []*parameter  // Path and query parameters.
+	search      bool          // If true, the route accepts search parameters of SearchBy.
+	formatted   bool          // If true, responses can be encoded in any of formats.
+	body        interface{}   // Value of the type of request's body, nil if there's none.
+	status      int           // Status code of a successful response.
+	responses   []interface{} // Values of possible types of a successful response, nil if there's no body.
+	contentType string        // Content type of a successful response, JSON if empty.
+}
+
+// parameter documents a path or query parameter.
+type parameter struct {
+	name        string
+	in          string   // Either "path" or "query".
+	kind        string   // JSON type, e.g. "integer".
+	enum        []string // Allowed values, any value is allowed if nil.
+	required    bool
+	description string
+}
+
+// Common parameters.
+var (
+	idParameter = &parameter{
+		name:        "id",
+		in:          "path",
+		kind:        "integer",
+		required:    true,
+		description: "ID of a book.",
+	}
+	titlesOnlyParameter = &parameter{
+		name:        "TitlesOnly",
+		in:          "query",
+		kind:        "boolean",
+		description: "If specified, returns a list of titles instead of books.",
+	}
+	formatParameter = &parameter{
+		name:        "format",
+		in:          "query",
+		kind:        "string",
+		enum:        formatNames(),
+		description: "Format of the response, overrides the Accept header. Default is pretty.",
+	}
+	groupEditionsParameter = &parameter{
+		name:        "GroupEditions",
+		in:          "query",
+		kind:        "boolean",
+		description: "If specified, returns the most rated edition of each work along with its number of matching Editions.",
+	}
+)
+
+// routes returns all routes of the server.
+func (s *Server) routes() []*route {
+	return []*route{
+		{
+			method:  "GET",
+			path:    "/book/{id}",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.searchByID,
+			doc: &operation{
+				summary:    "Find a book by ID.",
+				parameters: []*parameter{idParameter, formatParameter},
+				formatted:  true,
+				status:     http.StatusOK,
+				responses:  []interface{}{new(books.Book)},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/book/{id}/similar",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.similar,
+			doc: &operation{
+				summary:     "List books similar to a book.",
+				description: "Books are scored by shared authors, shared title words, and closeness of their ratings and number of pages, most similar first.",
+				parameters: []*parameter{
+					idParameter,
+					{
+						name:        "Limit",
+						in:          "query",
+						kind:        "integer",
+						description: "Number of books to list, between 1 and 50, default is 10.",
+					},
+				},
+				status:    http.StatusOK,
+				responses: []interface{}{[]*books.Book{}},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/books/{title}",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.searchByTitle,
+			doc: &operation{
+				summary: "List books with a title.",
+				parameters: []*parameter{
+					{
+						name:        "title",
+						in:          "path",
+						kind:        "string",
+						required:    true,
+						description: "Exact title of the books.",
+					},
+					formatParameter,
+				},
+				formatted: true,
+				status:    http.StatusOK,
+				responses: []interface{}{[]*books.Book{}},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/books",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.search,
+			doc: &operation{
+				summary:     "Search for books.",
+				description: "Lists books, or their titles, that match all given search parameters. A search with no parameters lists all books. Books are streamed as they're read, so if the search fails after some were sent, the connection is aborted instead of ending the response, which is then incomplete.",
+				parameters:  []*parameter{titlesOnlyParameter, groupEditionsParameter, formatParameter},
+				search:      true,
+				formatted:   true,
+				status:      http.StatusOK,
+				responses:   []interface{}{[]*books.Book{}, []*books.Work{}, []string{}},
+			},
+		},
+		{
+			method:  "POST",
+			path:    "/books/search",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.searchByBody,
+			doc: &operation{
+				summary:     "Search for books using a JSON body.",
+				description: "Works like GET /books, but takes search parameters, including TitlesOnly and GroupEditions, as a JSON object. Parameters missing from the body are ignored, and unknown parameters are rejected. Books are streamed as they're read, so if the search fails after some were sent, the connection is aborted instead of ending the response, which is then incomplete.",
+				parameters:  []*parameter{formatParameter},
+				body:        new(searchRequest),
+				formatted:   true,
+				status:      http.StatusOK,
+				responses:   []interface{}{[]*books.Book{}, []*books.Work{}, []string{}},
+			},
+		},
+		{
+			method:  "POST",
+			path:    "/books/batch",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.batch,
+			doc: &operation{
+				summary:     "Look up books by IDs and ISBNs.",
+				description: fmt.Sprintf("Finds books with any of given IDs or 10 or 13 digit ISBNs, at most %d in total, and lists IDs and ISBNs that weren't found.", books.MaxBatch),
+				body:        new(batchRequest),
+				status:      http.StatusOK,
+				responses:   []interface{}{new(books.Batch)},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/series/{name}",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.searchBySeries,
+			doc: &operation{
+				summary: "List books in a series in reading order.",
+				parameters: []*parameter{
+					{
+						name:        "name",
+						in:          "path",
+						kind:        "string",
+						required:    true,
+						description: "Name of the series, ignoring case.",
+					},
+				},
+				status:    http.StatusOK,
+				responses: []interface{}{[]*books.Book{}},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/work/{id}/editions",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.searchEditions,
+			doc: &operation{
+				summary: "List editions of a work, most rated first.",
+				parameters: []*parameter{
+					{
+						name:        "id",
+						in:          "path",
+						kind:        "integer",
+						required:    true,
+						description: "WorkID of the work.",
+					},
+				},
+				status:    http.StatusOK,
+				responses: []interface{}{[]*books.Book{}},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/stats",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.stats,
+			doc: &operation{
+				summary:   "Compute statistics of books that match search parameters.",
+				search:    true,
+				status:    http.StatusOK,
+				responses: []interface{}{new(books.Statistics)},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/suggest",
+			role:    auth.ReadOnly,
+			version: v1,
+			handler: s.suggest,
+			doc: &operation{
+				summary: "List completions of a prefix of a title or an author's name.",
+				parameters: []*parameter{
+					{
+						name:        "prefix",
+						in:          "query",
+						kind:        "string",
+						required:    true,
+						description: "Prefix to complete, ignoring case.",
+					},
+					{
+						name:        "field",
+						in:          "query",
+						kind:        "string",
+						enum:        []string{"title", "author"},
+						description: "Field to complete, default is title.",
+					},
+					{
+						name:        "limit",
+						in:          "query",
+						kind:        "integer",
+						description: "Number of completions, between 1 and 50, default is 10.",
+					},
+				},
+				status:    http.StatusOK,
+				responses: []interface{}{[]*books.Suggestion{}},
+			},
+		},
+		{
+			method:  "POST",
+			path:    "/books",
+			role:    auth.Admin,
+			version: v1,
+			handler: s.insert,
+			doc: &operation{
+				summary:     "Create a book.",
+				description: "ID may be omitted to have one assigned. Series, SeriesIndex, WorkID and WeightedRating are computed and ignored.",
+				body:        new(books.Book),
+				status:      http.StatusCreated,
+				responses:   []interface{}{new(books.Book)},
+			},
+		},
+		{
+			method:  "PUT",
+			path:    "/book/{id}",
+			role:    auth.Admin,
+			version: v1,
+			handler: s.replace,
+			doc: &operation{
+				summary:     "Replace a book.",
+				description: "ID may be omitted, and must match the path otherwise.",
+				parameters:  []*parameter{idParameter},
+				body:        new(books.Book),
+				status:      http.StatusOK,
+				responses:   []interface{}{new(books.Book)},
+			},
+		},
+		{
+			method:  "PATCH",
+			path:    "/book/{id}",
+			role:    auth.Admin,
+			version: v1,
+			handler: s.patch,
+			doc: &operation{
+				summary:     "Modify fields of a book.",
+				description: "Only fields in the body are changed.",
+				parameters:  []*parameter{idParameter},
+				body:        new(books.Book),
+				status:      http.StatusOK,
+				responses:   []interface{}{new(books.Book)},
+			},
+		},
+		{
+			method:  "DELETE",
+			path:    "/book/{id}",
+			role:    auth.Admin,
+			version: v1,
+			handler: s.remove,
+			doc: &operation{
+				summary:    "Delete a book.",
+				parameters: []*parameter{idParameter},
+				status:     http.StatusNoContent,
+			},
+		},
+		{
+			method:  "POST",
+			path:    "/graphql",
+			role:    auth.ReadOnly,
+			handler: s.graphQL,
+			doc: &operation{
+				summary:     "Query books using GraphQL.",
+				description: "Executes a GraphQL query against the schema served by GET /graphql/schema. Invalid queries, and ones that exceed the server's depth and complexity limits, are responded to with errors and no data. Only queries are supported.",
+				body:        new(graphql.Request),
+				status:      http.StatusOK,
+				responses:   []interface{}{new(graphQLResponseDoc)},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/graphql/schema",
+			role:    auth.ReadOnly,
+			handler: s.graphQLSchemaText,
+			doc: &operation{
+				summary:     "Describe the GraphQL schema in its schema definition language.",
+				status:      http.StatusOK,
+				responses:   []interface{}{""},
+				contentType: "text/plain",
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/healthz",
+			handler: s.healthz,
+			doc: &operation{
+				summary:   "Check that the server is alive.",
+				status:    http.StatusOK,
+				responses: []interface{}{new(Health)},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/readyz",
+			handler: s.readyz,
+			doc: &operation{
+				summary:   "Check that the server is ready to serve requests.",
+				status:    http.StatusOK,
+				responses: []interface{}{new(Health)},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/version",
+			handler: s.version,
+			doc: &operation{
+				summary:   "Describe the server's build and datastore.",
+				status:    http.StatusOK,
+				responses: []interface{}{new(Version)},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/metrics",
+			handler: s.metrics.registry.ServeHTTP,
+			doc: &operation{
+				summary:     "Expose metrics in Prometheus' text format.",
+				status:      http.StatusOK,
+				responses:   []interface{}{""},
+				contentType: "text/plain",
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/openapi.json",
+			handler: s.openAPI,
+			doc: &operation{
+				summary:   "Describe the API using OpenAPI 3.",
+				status:    http.StatusOK,
+				responses: []interface{}{map[string]interface{}{}},
+			},
+		},
+		{
+			method:  "GET",
+			path:    "/docs",
+			handler: s.docs,
+			doc: &operation{
+				summary:     "Browse and try the API's documentation.",
+				status:      http.StatusOK,
+				responses:   []interface{}{""},
+				contentType: "text/html",
+			},
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// searchRequest holds parameters of a search, given either as query parameters
+// of GET /books, or as the JSON body of POST /books/search.
+type searchRequest struct {
+	books.SearchBy
+	TitlesOnly    bool // If true, lists titles instead of books.
+	GroupEditions bool // If true, lists the most rated edition of each work.
+}
+
+// newSearchRequest returns a searchRequest with all parameters set to be
+// ignored, to be filled with given parameters.
+func newSearchRequest() *searchRequest {
+	return &searchRequest{
+		SearchBy: *books.NewSearchBy(),
+	}
+}
+
+// queryRequest decodes a search request from query parameters. If parameters
+// can't be decoded returns a message and false.
+func queryRequest(query url.Values) (*searchRequest, string, bool) {
+	request := newSearchRequest()
+	request.TitlesOnly, query = isFlagSet(query, "TitlesOnly")
+	request.GroupEditions, query = isFlagSet(query, "GroupEditions")
+	if err := decoder.Decode(&request.SearchBy, query); err != nil {
+		return nil, "Unable to decode search query.", false
+	}
+
+	return request, "", true
+}
+
+// bodyRequest decodes a search request from a JSON body. Parameters missing from
+// body are ignored, and unknown parameters are rejected. If body can't be decoded
+// returns a message and false.
+func bodyRequest(body io.Reader) (*searchRequest, string, bool) {
+	request := newSearchRequest()
+	if err := decodeJSON(body, request); err != nil {
+		return nil, fmt.Sprintf("Unable to decode search query: %s.", err.Error()), false
+	}
+
+	return request, "", true
+}
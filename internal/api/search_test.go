@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestSearchByBody tests searching using a JSON body, and that it responds the
+// same as searching using query parameters.
+func TestSearchByBody(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(nil, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	for _, test := range []struct {
+		body     string
+		query    string // Equivalent query parameters of GET /books, empty if none.
+		response string // Expected response if there's no equivalent query.
+		status   int
+	}{
+		{
+			body:   `{}`,
+			query:  "",
+			status: http.StatusOK,
+		},
+		{
+			body:   `{"Authors": ["Douglas"], "TitlesOnly": true, "GroupEditions": true, "SortBy": "ID"}`,
+			query:  "Authors=Douglas&TitlesOnly=true&GroupEditions=true&SortBy=ID",
+			status: http.StatusOK,
+		},
+		{
+			body:   `{"Authors": ["Bryson", "Douglas"], "LanguageCode": ["eng"], "PagesFloor": 300, "SortBy": "Pages", "Descending": true}`,
+			query:  "Authors=Bryson&Authors=Douglas&LanguageCode=eng&PagesFloor=300&SortBy=Pages&Descending=true",
+			status: http.StatusOK,
+		},
+		{
+			body:   `{"TitleHas": "Harry", "RatingFloor": 4.5, "GroupEditions": true}`,
+			query:  "TitleHas=Harry&RatingFloor=4.5&GroupEditions=true",
+			status: http.StatusOK,
+		},
+		{
+			body:     `{"SortBy": "Wrong"}`,
+			response: "Invalid search query: can't sort by \"Wrong\".\n",
+			status:   http.StatusBadRequest,
+		},
+		{
+			body:     `{"Wrong": 10}`,
+			response: "Unable to decode search query: json: unknown field \"Wrong\".\n",
+			status:   http.StatusBadRequest,
+		},
+		{
+			body:     `{"Authors": ["Douglas"`,
+			response: "Unable to decode search query: unexpected EOF.\n",
+			status:   http.StatusBadRequest,
+		},
+	} {
+		recorder := serveRequest(t, server, "POST", "/books/search", "", test.body)
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status: %d, got: %d %s", test.body, test.status, recorder.Code, recorder.Body.String())
+			continue
+		}
+
+		expected := test.response
+		if test.status == http.StatusOK {
+			expected = serveRequest(t, server, "GET", "/books?"+test.query, "", "").Body.String()
+		}
+		if recorder.Body.String() != expected {
+			t.Errorf("%s: expected response: %s, got: %s", test.body, expected, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"container/list"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxCachedResults is the maximum number of results of a search that can be
+// cached. Larger results are searched for every time, so that the cache's size
+// stays bounded.
+const maxCachedResults = 1000
+
+// searchCache is a least recently used cache of search results, keyed by
+// canonical search requests. Results belong to a version of the dataset, and
+// are discarded once the dataset changes. A nil searchCache caches nothing.
+type searchCache struct {
+	size int           // Maximum number of cached results.
+	ttl  time.Duration // Maximum time results are cached, no limit if 0.
+
+	mutex   sync.Mutex
+	version string                   // Version of the dataset cached results belong to.
+	entries map[string]*list.Element // Elements of order by key.
+	order   *list.List               // Entries, most recently used first.
+
+	observe func(hit bool) // Called with the result of each lookup.
+}
+
+// searchCacheEntry is a cached search result.
+type searchCacheEntry struct {
+	key     string
+	results interface{}
+	expires time.Time // Zero if results don't expire.
+}
+
+// newSearchCache returns a searchCache holding at most size results, each for at
+// most ttl, or nil if size is 0. observe, if not nil, is called with the result of
+// each lookup.
+func newSearchCache(size int, ttl time.Duration, observe func(hit bool)) *searchCache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &searchCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		observe: observe,
+	}
+}
+
+// get returns results cached for key, if any, for given version of the dataset.
+func (c *searchCache) get(version, key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.setVersion(version)
+	element, ok := c.entries[key]
+	if ok {
+		entry := element.Value.(*searchCacheEntry)
+		if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+			c.remove(element)
+			ok = false
+		} else {
+			c.order.MoveToFront(element)
+		}
+	}
+
+	if c.observe != nil {
+		c.observe(ok)
+	}
+	if !ok {
+		return nil, false
+	}
+	return element.Value.(*searchCacheEntry).results, true
+}
+
+// add caches results for key, for given version of the dataset, evicting the
+// least recently used results if the cache is full.
+func (c *searchCache) add(version, key string, results interface{}) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.setVersion(version)
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	entry := &searchCacheEntry{key: key, results: results}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// clear discards all cached results.
+func (c *searchCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// len returns the number of cached results.
+func (c *searchCache) len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.order.Len()
+}
+
+// setVersion discards cached results if they belong to a version of the dataset
+// other than given. c.mutex must be held.
+func (c *searchCache) setVersion(version string) {
+	if c.version != version {
+		c.version = version
+		c.entries = make(map[string]*list.Element)
+		c.order.Init()
+	}
+}
+
+// remove discards a cached result. c.mutex must be held.
+func (c *searchCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*searchCacheEntry).key)
+}
+
+// cacheKey returns a key identifying a search request's results. Requests that
+// differ only in the order of authors or language codes have the same key.
+func (request *searchRequest) cacheKey() string {
+	canonical := *request
+	canonical.Authors = sortedCopy(request.Authors)
+	canonical.LanguageCode = sortedCopy(request.LanguageCode)
+	if canonical.SortBy == "" {
+		canonical.Descending = false
+	}
+
+	key, err := json.Marshal(&canonical)
+	if err != nil { // Just for safety as requests consist of strings, numbers and booleans.
+		panic(err)
+	}
+	return string(key)
+}
+
+// sortedCopy returns a sorted copy of values, or nil if values is empty.
+func sortedCopy(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
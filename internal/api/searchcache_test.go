@@ -0,0 +1,157 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// TestSearchCacheEviction tests evicting least recently used, expired, and
+// outdated results.
+func TestSearchCacheEviction(t *testing.T) {
+	hits, misses := 0, 0
+	cache := newSearchCache(2, time.Hour, func(hit bool) {
+		if hit {
+			hits++
+		} else {
+			misses++
+		}
+	})
+
+	cache.add("v1", "a", "A")
+	cache.add("v1", "b", "B")
+	if _, ok := cache.get("v1", "a"); !ok {
+		t.Errorf("expected a to be cached")
+	}
+
+	cache.add("v1", "c", "C") // Evicts b, the least recently used.
+	for key, cached := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.get("v1", key); ok != cached {
+			t.Errorf("expected %s cached: %t, got: %t", key, cached, ok)
+		}
+	}
+	if hits != 3 || misses != 1 {
+		t.Errorf("expected 3 hits and 1 miss, got: %d and %d", hits, misses)
+	}
+
+	if _, ok := cache.get("v2", "a"); ok || cache.len() != 0 {
+		t.Errorf("expected results of v1 to be discarded, %d cached", cache.len())
+	}
+
+	cache.add("v2", "a", "A")
+	cache.clear()
+	if _, ok := cache.get("v2", "a"); ok {
+		t.Errorf("expected results to be cleared")
+	}
+
+	cache.ttl = time.Nanosecond
+	cache.add("v2", "a", "A")
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.get("v2", "a"); ok || cache.len() != 0 {
+		t.Errorf("expected a to expire, %d cached", cache.len())
+	}
+
+	disabled := newSearchCache(0, time.Hour, nil)
+	disabled.add("v1", "a", "A")
+	if _, ok := disabled.get("v1", "a"); ok {
+		t.Errorf("expected a disabled cache to cache nothing")
+	}
+}
+
+// TestCacheKey tests that keys of search requests ignore the order of authors
+// and language codes.
+func TestCacheKey(t *testing.T) {
+	for _, test := range []struct {
+		a, b  string // Query parameters of requests.
+		equal bool
+	}{
+		{a: "Authors=Bryson&Authors=Douglas", b: "Authors=Douglas&Authors=Bryson", equal: true},
+		{a: "LanguageCode=eng&LanguageCode=fre&PagesFloor=3", b: "PagesFloor=3&LanguageCode=fre&LanguageCode=eng", equal: true},
+		{a: "Descending=true", b: "", equal: true},
+		{a: "SortBy=ID&Descending=true", b: "SortBy=ID", equal: false},
+		{a: "Authors=Bryson", b: "Authors=Douglas", equal: false},
+		{a: "TitlesOnly=true", b: "GroupEditions=true", equal: false},
+	} {
+		keys := make([]string, 2)
+		for i, query := range []string{test.a, test.b} {
+			request, err := http.NewRequest("GET", "/books?"+query, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %s", err.Error())
+			}
+			search, message, ok := queryRequest(request.URL.Query())
+			if !ok {
+				t.Fatalf("%s: %s", query, message)
+			}
+			keys[i] = search.cacheKey()
+		}
+
+		if (keys[0] == keys[1]) != test.equal {
+			t.Errorf("%s, %s: expected equal keys: %t, got: %s, %s", test.a, test.b, test.equal, keys[0], keys[1])
+		}
+	}
+}
+
+// TestSearchCache tests that searches are answered from the cache until books
+// are edited, even by other servers, and that cached responses equal uncached
+// ones.
+func TestSearchCache(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+	server := New(&Config{SearchCacheSize: 10, SearchCacheTTL: time.Hour}, searchIn)
+	uncached := New(&Config{}, searchIn)
+
+	for i, test := range []struct {
+		method   string
+		url      string
+		body     string
+		hits     int  // Expected number of hits after the request.
+		misses   int  // Expected number of misses after the request.
+		modifies bool // If true, a book is deleted, without using the server, before the request.
+	}{
+		{method: "GET", url: "/books?Authors=Douglas&Authors=Bryson", hits: 0, misses: 1},
+		{method: "GET", url: "/books?Authors=Bryson&Authors=Douglas&format=csv", hits: 1, misses: 1},
+		{method: "POST", url: "/books/search", body: `{"Authors": ["Bryson", "Douglas"]}`, hits: 2, misses: 1},
+		{method: "GET", url: "/books?Authors=Douglas&GroupEditions=true", hits: 2, misses: 2},
+		{method: "GET", url: "/books?Authors=Douglas&GroupEditions=true&TitlesOnly=true", hits: 2, misses: 3},
+		{method: "GET", url: "/books?Authors=Douglas&GroupEditions=true", hits: 3, misses: 3},
+		{method: "GET", url: "/books?SortBy=Wrong", hits: 3, misses: 3},
+		{method: "GET", url: "/books?Authors=Douglas&Authors=Bryson", hits: 3, misses: 4, modifies: true},
+		{method: "GET", url: "/books?Authors=Douglas&Authors=Bryson", hits: 4, misses: 4},
+	} {
+		if test.modifies {
+			if err := books.Delete(searchIn.booksIn(), 14); err != nil {
+				t.Fatalf("%d: failed to delete book: %s", i, err.Error())
+			}
+		}
+
+		recorder := serveRequest(t, server, test.method, test.url, "", test.body)
+		expected := serveRequest(t, uncached, test.method, test.url, "", test.body)
+		if recorder.Code != expected.Code || recorder.Body.String() != expected.Body.String() {
+			t.Errorf("%d: expected response: %d %s, got: %d %s", i, expected.Code, expected.Body.String(), recorder.Code, recorder.Body.String())
+		}
+		if recorder.Header().Get("Content-Type") != expected.Header().Get("Content-Type") {
+			t.Errorf("%d: expected Content-Type: %s, got: %s", i, expected.Header().Get("Content-Type"), recorder.Header().Get("Content-Type"))
+		}
+
+		metrics := serveRequest(t, server, "GET", "/metrics", "", "").Body.String()
+		for _, line := range []string{
+			fmt.Sprintf(`bfr_api_search_cache_lookups_total{result="hit"} %d`, test.hits),
+			fmt.Sprintf(`bfr_api_search_cache_lookups_total{result="miss"} %d`, test.misses),
+		} {
+			zero := strings.HasSuffix(line, " 0") && !strings.Contains(metrics, strings.TrimSuffix(line, " 0"))
+			if !zero && !strings.Contains(metrics, line+"\n") {
+				t.Errorf("%d: expected metrics to contain: %s, got:\n%s", i, line, metrics)
+			}
+		}
+	}
+}
@@ -3,11 +3,22 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+	"github.com/sudo-sturbia/bfr/v2/internal/certs"
+	"github.com/sudo-sturbia/bfr/v2/internal/datastore"
+	"github.com/sudo-sturbia/bfr/v2/internal/graceful"
+	"github.com/sudo-sturbia/bfr/v2/internal/graphql"
+	"github.com/sudo-sturbia/bfr/v2/internal/logging"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
 )
 
 // Server represents bfr's backend server, and holds all dependencies needed
@@ -16,13 +27,52 @@ type Server struct {
 	cfg    *Config     // Server's configuration options.
 	router *mux.Router // Server's router.
 
-	searchIn *SearchIn // Datastore to search in.
+	searchIn *SearchIn      // Datastore to search in.
+	limiter  *limiter       // Rate limiter of requests, nil if requests aren't limited.
+	failures *limiter       // Rate limiter of failed authentications per address, nil if requests aren't limited.
+	metrics  *serverMetrics // Metrics exposed by /metrics.
+	spec     []byte         // OpenAPI document served by /openapi.json.
+	results  *searchCache   // Cached search results, nil if results aren't cached.
+
+	graphQLSchema *graphql.Schema // Schema of queries served by /graphql.
+	graphQLLimits *graphql.Limits // Limits of queries served by /graphql.
+
+	indexMutex   sync.Mutex         // Guards index and indexVersion.
+	index        *books.PrefixIndex // Index used for suggestions, built on first use.
+	indexVersion string             // Version of the dataset index was built for.
 }
 
 // Config holds server's configuration options.
 type Config struct {
 	Host string // Host to run the server on.
 	Port string // Port to run the server on.
+
+	Version string // Version of the server's build, reported by /version.
+
+	ReadTimeout     time.Duration // Maximum duration for reading a request, no limit if 0.
+	WriteTimeout    time.Duration // Maximum duration for writing a response, no limit if 0.
+	IdleTimeout     time.Duration // Maximum time to keep an idle connection open, no limit if 0.
+	ShutdownTimeout time.Duration // Maximum time to wait for requests in progress when shutting down, no limit if 0.
+
+	TLS *certs.ServerConfig // Options of serving HTTPS, plain HTTP is served if nil.
+
+	Keys         *auth.Keys // API keys used to authenticate requests, writes are disabled if nil.
+	PrivateReads bool       // If true, read requests also require an API key.
+
+	CORS *CORS // Options of cross-origin requests, which aren't allowed if nil.
+
+	Deprecations map[string]*Deprecation // Deprecated versions of the API by name, e.g. "v1".
+
+	RateLimit float64 // Requests per second allowed per client, requests aren't limited if 0.
+	RateBurst int     // Maximum number of requests a client can make at once.
+
+	CacheMaxAge time.Duration // Maximum time read responses can be reused without revalidating, always revalidated if 0.
+
+	SearchCacheSize int           // Maximum number of search results cached by the server, results aren't cached if 0.
+	SearchCacheTTL  time.Duration // Maximum time search results are cached by the server, no limit if 0.
+
+	GraphQLMaxDepth      int // Maximum depth of nested fields of GraphQL queries, no limit if 0.
+	GraphQLMaxComplexity int // Maximum complexity, i.e. estimated number of resolved fields, of GraphQL queries, no limit if 0.
 }
 
 // SearchIn Contains a database and name of the table to search in. It is a
@@ -31,25 +81,179 @@ type Config struct {
 type SearchIn struct {
 	Datastore *sql.DB // Datastore to search in.
 	BookTable string  // Table to search in.
+
+	MetadataTable string // Table containing the datastore's metadata, see datastore.DefaultMetadataTable.
+
+	observe func(string, time.Duration) // Observer of books queries, set by New.
+}
+
+// booksIn returns a books.SearchIn equivalent to searchIn.
+func (searchIn *SearchIn) booksIn() *books.SearchIn {
+	return &books.SearchIn{
+		Datastore:     searchIn.Datastore,
+		BookTable:     searchIn.BookTable,
+		MetadataTable: searchIn.MetadataTable,
+		Observe:       searchIn.observe,
+	}
 }
 
 // New creates and returns a new, initialized server instance with handlers
 // pointing to correct routes.
 func New(cfg *Config, searchIn *SearchIn) *Server {
 	s := &Server{
-		cfg:      cfg,
-		searchIn: searchIn,
-		router:   mux.NewRouter(),
+		cfg:     cfg,
+		router:  mux.NewRouter(),
+		metrics: newServerMetrics(searchIn),
+	}
+
+	if searchIn != nil {
+		s.searchIn = &SearchIn{
+			Datastore:     searchIn.Datastore,
+			BookTable:     searchIn.BookTable,
+			MetadataTable: searchIn.MetadataTable,
+			observe:       s.metrics.observeQuery,
+		}
+	}
+
+	if cfg != nil && cfg.RateLimit > 0 {
+		s.limiter = newLimiter(cfg.RateLimit, cfg.RateBurst)
+		s.failures = newLimiter(cfg.RateLimit, cfg.RateBurst)
+	}
+	if cfg != nil {
+		s.results = newSearchCache(cfg.SearchCacheSize, cfg.SearchCacheTTL, s.metrics.observeSearchCache)
+	}
+
+	s.graphQLSchema = newGraphQLSchema(s.searchIn)
+	s.graphQLLimits = new(graphql.Limits)
+	if cfg != nil {
+		s.graphQLLimits.MaxDepth = cfg.GraphQLMaxDepth
+		s.graphQLLimits.MaxComplexity = cfg.GraphQLMaxComplexity
+	}
+
+	routes := s.versioned(s.routes())
+	for _, route := range routes {
+		handler := route.handler
+		if route.cached() {
+			handler = s.cache(handler)
+		}
+		if route.role != "" {
+			handler = s.handler(route.role, handler)
+		}
+		if route.deprecated != nil {
+			handler = deprecate(route.deprecated, handler)
+		}
+		s.router.HandleFunc(route.path, handler).Methods(route.method)
 	}
+	s.spec = newSpec(routes, s.buildVersion())
+
+	s.router.Use(logging.Middleware, s.metrics.http.Middleware)
 
-	s.router.HandleFunc("/book/{id}", s.searchByID).Methods("GET")
-	s.router.HandleFunc("/books/{title}", s.searchByTitle).Methods("GET")
-	s.router.HandleFunc("/books", s.search).Methods("GET")
+	// Middleware only wraps matched routes, so requests that don't match one
+	// are logged and measured by their handlers.
+	s.router.NotFoundHandler = logging.Middleware(s.metrics.http.Middleware(http.NotFoundHandler()))
+	s.router.MethodNotAllowedHandler = logging.Middleware(s.metrics.http.Middleware(http.HandlerFunc(methodNotAllowed)))
+
+	if cfg != nil && cfg.CORS != nil {
+		routed := make(map[string]bool)
+		for _, route := range routes {
+			if !routed[route.path] {
+				routed[route.path] = true
+				s.router.HandleFunc(route.path, s.preflight).Methods(http.MethodOptions)
+			}
+		}
+		s.router.Use(s.cors)
+	}
 
 	return s
 }
 
-// Run runs a server instance on the host and port specified in its config.
-func (s *Server) Run() {
-	http.ListenAndServe(fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port), s.router)
+// Run runs a server instance on the host and port specified in its config until
+// ctx is done, then shuts it down gracefully. If the server uses TLS, plain HTTP
+// requests on the configured redirect port are redirected to HTTPS. See Serve.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port))
+	if err != nil {
+		return err
+	}
+	if s.cfg.TLS == nil || s.cfg.TLS.RedirectPort == "" {
+		return s.Serve(ctx, listener)
+	}
+
+	redirect, err := net.Listen("tcp", fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.TLS.RedirectPort))
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	return certs.ServeRedirect(ctx, redirect, s.cfg.Port, s.cfg.ShutdownTimeout, func(ctx context.Context) error {
+		return s.Serve(ctx, listener)
+	})
+}
+
+// Serve serves requests on given listener until ctx is done, then stops
+// accepting connections and waits for requests in progress to finish. Requests
+// are served using TLS, and HTTP/2, if the server's config specifies a
+// certificate, which is reloaded on SIGHUP. Returns an error if serving fails,
+// or if requests don't finish in time.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{
+		Handler:      s,
+		ReadTimeout:  s.cfg.ReadTimeout,
+		WriteTimeout: s.cfg.WriteTimeout,
+		IdleTimeout:  s.cfg.IdleTimeout,
+	}
+	if s.cfg.TLS != nil {
+		config, reloader, err := s.cfg.TLS.Load()
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		server.TLSConfig = config
+
+		reloadCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go certs.ReloadOnHangup(reloadCtx, reloader)
+	}
+	return graceful.Serve(ctx, server, listener, s.cfg.ShutdownTimeout)
+}
+
+// methodNotAllowed responds to requests whose method doesn't match their
+// route's.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, "Method not allowed.", http.StatusMethodNotAllowed)
+}
+
+// ServeHTTP dispatches a request to the handler of the route it matches.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// handler wraps a route's handler so that requests are authorized with the
+// required role, then rate limited. Failed authentications are rate limited
+// separately by authorize.
+func (s *Server) handler(required auth.Role, handler http.HandlerFunc) http.HandlerFunc {
+	return s.authorize(required, s.limit(handler))
+}
+
+// prefixIndex returns the server's prefix index, building it if it wasn't
+// built yet, or if it was built for another version of the dataset, e.g.
+// before books were edited by another server.
+func (s *Server) prefixIndex() (*books.PrefixIndex, error) {
+	version := ""
+	metadata, err := datastore.ReadMetadata(s.searchIn.Datastore, s.searchIn.MetadataTable)
+	if err == nil { // Indexes of datastores without metadata are only rebuilt after writes.
+		version = datasetVersion(metadata)
+	}
+
+	s.indexMutex.Lock()
+	defer s.indexMutex.Unlock()
+
+	if s.index == nil || s.indexVersion != version {
+		index, err := books.NewPrefixIndex(s.searchIn.booksIn())
+		if err != nil {
+			return nil, err
+		}
+		s.index = index
+		s.indexVersion = version
+	}
+	return s.index, nil
 }
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sudo-sturbia/bfr/v2/internal/certs"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestServeHTTP tests serving requests using an httptest server.
+func TestServeHTTP(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := httptest.NewServer(
+		New(
+			&Config{},
+			&SearchIn{
+				Datastore: datastore,
+				BookTable: bookTable,
+			},
+		),
+	)
+	defer server.Close()
+
+	for url, status := range map[string]int{
+		"/book/1":       http.StatusOK,
+		"/book/1/title": http.StatusNotFound,
+		"/stats":        http.StatusOK,
+	} {
+		resp, err := http.Get(server.URL + url)
+		if err != nil {
+			t.Fatalf("request failed: %s", err.Error())
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected status: %d, got: %d", url, status, resp.StatusCode)
+		}
+	}
+}
+
+// TestServe tests that Serve serves requests until its context is done.
+func TestServe(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := New(
+		&Config{
+			ReadTimeout:     time.Second,
+			WriteTimeout:    time.Second,
+			ShutdownTimeout: time.Second,
+		},
+		&SearchIn{
+			Datastore: datastore,
+			BookTable: bookTable,
+		},
+	)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx, listener)
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/book/1")
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status: %d, got: %d", http.StatusOK, resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("expected no error, got: %s", err.Error())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("server didn't shut down")
+	}
+}
+
+// TestServeTLS tests serving HTTP/2 requests using TLS, and authenticating
+// clients using their certificates.
+func TestServeTLS(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	files, deferCerts := testhelper.NewCertificates(t)
+	defer deferCerts()
+
+	server := New(
+		&Config{
+			ShutdownTimeout: time.Second,
+			TLS: &certs.ServerConfig{
+				CertFile:     files.ServerCertFile,
+				KeyFile:      files.ServerKeyFile,
+				ClientCAFile: files.CAFile,
+			},
+		},
+		&SearchIn{
+			Datastore: datastore,
+			BookTable: bookTable,
+		},
+	)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx, listener)
+	}()
+
+	for _, test := range []struct {
+		config *certs.ClientConfig
+		ok     bool
+	}{
+		{config: &certs.ClientConfig{CAFile: files.CAFile, CertFile: files.ClientCertFile, KeyFile: files.ClientKeyFile}, ok: true},
+		{config: &certs.ClientConfig{CAFile: files.CAFile}, ok: false},
+	} {
+		config, _, err := test.config.Load()
+		if err != nil {
+			t.Fatalf("failed to load client's configuration: %s", err.Error())
+		}
+
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   config,
+				ForceAttemptHTTP2: true,
+			},
+		}
+		resp, err := client.Get("https://" + listener.Addr().String() + "/book/1")
+		if err != nil {
+			if test.ok {
+				t.Errorf("%+v: expected request to succeed, got: %s", *test.config, err.Error())
+			}
+			continue
+		}
+		resp.Body.Close()
+
+		if !test.ok {
+			t.Errorf("%+v: expected request to fail", *test.config)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status: %d, got: %d", http.StatusOK, resp.StatusCode)
+		}
+		if resp.ProtoMajor != 2 {
+			t.Errorf("expected HTTP/2, got: %s", resp.Proto)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("expected no error, got: %s", err.Error())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("server didn't shut down")
+	}
+}
+
+// TestRunFails tests that Run returns an error if it can't listen.
+func TestRunFails(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+	defer listener.Close()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	server := New(&Config{Host: host, Port: port}, nil)
+	if err := server.Run(context.Background()); err == nil {
+		t.Errorf("expected running on a port in use to fail")
+	}
+}
+
+// TestRequestID tests that request IDs are returned, and included in error logs.
+func TestRequestID(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	server := New(&Config{}, &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+
+	request, err := http.NewRequest("GET", "/book/x", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	request.Header.Set("X-Request-ID", "frontend-1234")
+
+	recorder := serveRecorded(server, request)
+	if id := recorder.Header().Get("X-Request-ID"); id != "frontend-1234" {
+		t.Errorf("expected request ID: frontend-1234, got: %s", id)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected an error line and a request line, got: %s", output.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "RequestID=frontend-1234") && !strings.Contains(line, `"RequestID":"frontend-1234"`) {
+			t.Errorf("expected log line to contain request ID, got: %s", line)
+		}
+	}
+}
+
+// TestUnmatched tests that requests that don't match a route are logged and
+// measured.
+func TestUnmatched(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	server := New(&Config{}, nil)
+	for _, test := range []struct {
+		method string
+		url    string
+		status int
+	}{
+		{method: "GET", url: "/book/1/title", status: http.StatusNotFound},
+		{method: "PUT", url: "/stats", status: http.StatusMethodNotAllowed},
+	} {
+		output.Reset()
+
+		request, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %s", err.Error())
+		}
+		request.Header.Set("X-Request-ID", "unmatched-1")
+
+		recorder := serveRecorded(server, request)
+		if recorder.Code != test.status || recorder.Header().Get("X-Request-ID") != "unmatched-1" {
+			t.Errorf("%s %s: expected status: %d and a request ID, got: %d %q", test.method, test.url, test.status, recorder.Code, recorder.Header().Get("X-Request-ID"))
+		}
+
+		logged := false
+		for _, line := range strings.Split(output.String(), "\n") {
+			logged = logged || strings.Contains(line, "Request served.") && strings.Contains(line, "Status="+strconv.Itoa(test.status))
+		}
+		if !logged {
+			t.Errorf("%s %s: expected the request to be logged, got: %s", test.method, test.url, output.String())
+		}
+	}
+
+	request, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	metrics := serveRecorded(server, request).Body.String()
+	for _, line := range []string{
+		`bfr_api_requests_total{route="unknown",method="GET",status="404"} 1`,
+		`bfr_api_requests_total{route="unknown",method="PUT",status="405"} 1`,
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("expected metrics to contain: %s, got:\n%s", line, metrics)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Versions of the API. Routes that read or write books are versioned, and are
+// served under their version's prefix, e.g. /v1/books. A change to a route
+// that would break its clients, e.g. of its response's shape, is made by
+// adding a route to a new version, while the old version keeps serving the
+// previous shape until it's removed.
+const (
+	v1 = "v1"
+
+	// aliasVersion is the version whose routes are also served under their
+	// bare paths, e.g. /books, which predate versioning.
+	aliasVersion = v1
+)
+
+// versions lists versions of the API, oldest first.
+var versions = []string{v1}
+
+// Deprecation schedules the removal of a version of the API. Responses of a
+// deprecated version's routes carry a Deprecation header (RFC 9745), and a
+// Sunset header (RFC 8594) if the time of removal is known.
+type Deprecation struct {
+	Since  time.Time // Time the version is deprecated since.
+	Sunset time.Time // Time the version stops being served, unknown if zero.
+}
+
+// versioned returns given routes as they're served: versioned routes under
+// their version's prefix, and routes of aliasVersion also under their bare
+// paths. Other routes are returned as is.
+func (s *Server) versioned(routes []*route) []*route {
+	served := make([]*route, 0, 2*len(routes))
+	for _, r := range routes {
+		if r.version == "" {
+			served = append(served, r)
+			continue
+		}
+
+		var deprecated *Deprecation
+		if s.cfg != nil {
+			deprecated = s.cfg.Deprecations[r.version]
+		}
+
+		prefixed := *r
+		prefixed.path = "/" + r.version + r.path
+		prefixed.deprecated = deprecated
+		served = append(served, &prefixed)
+
+		if r.version == aliasVersion {
+			alias := *r
+			alias.alias = true
+			alias.deprecated = deprecated
+			served = append(served, &alias)
+		}
+	}
+	return served
+}
+
+// canonicalPath returns the path of route under its version's prefix.
+func (route *route) canonicalPath() string {
+	if route.alias {
+		return "/" + route.version + route.path
+	}
+	return route.path
+}
+
+// deprecate wraps handler of a route of a deprecated version, announcing the
+// deprecation in headers of its responses.
+func deprecate(deprecation *Deprecation, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecation.Since.Unix()))
+		if !deprecation.Sunset.IsZero() {
+			w.Header().Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+		}
+		handler(w, r)
+	}
+}
+
+// ParseDeprecation parses a deprecation of a version of the API from a string
+// of the form "version=since[/sunset]", where times are dates, e.g.
+// "v1=2026-10-01/2027-04-01".
+func ParseDeprecation(s string) (version string, deprecation *Deprecation, err error) {
+	version, dates := split(s, "=")
+	version = strings.ToLower(version)
+	if version == "" || dates == "" {
+		return "", nil, fmt.Errorf("expected version=since[/sunset], got: %q", s)
+	}
+	if !contains(versions, version) {
+		return "", nil, fmt.Errorf("unknown version %q", version)
+	}
+
+	since, sunset := split(dates, "/")
+	deprecation = new(Deprecation)
+	deprecation.Since, err = time.Parse("2006-01-02", since)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid date %q", since)
+	}
+	if sunset != "" {
+		deprecation.Sunset, err = time.Parse("2006-01-02", sunset)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid date %q", sunset)
+		}
+		if deprecation.Sunset.Before(deprecation.Since) {
+			return "", nil, fmt.Errorf("sunset %s is before deprecation %s", sunset, since)
+		}
+	}
+	return version, deprecation, nil
+}
+
+// split splits s around the first instance of sep, the second part is empty
+// if s doesn't contain sep.
+func split(s, sep string) (string, string) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):]
+	}
+	return s, ""
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestVersions tests serving versioned routes under their prefix and bare
+// paths, and announcing deprecations of versions.
+func TestVersions(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+	for i, test := range []struct {
+		deprecations map[string]*Deprecation
+		url          string
+		status       int
+		deprecation  string // Expected Deprecation header, none if empty.
+		sunset       string // Expected Sunset header, none if empty.
+	}{
+		{url: "/v1/book/1", status: http.StatusOK},
+		{url: "/book/1", status: http.StatusOK},
+		{url: "/v1/books?Authors=J.K.%20Rowling", status: http.StatusOK},
+		{url: "/v2/book/1", status: http.StatusNotFound},
+		{url: "/v1/healthz", status: http.StatusNotFound},
+		{
+			deprecations: map[string]*Deprecation{v1: {Since: since, Sunset: sunset}},
+			url:          "/v1/book/1",
+			status:       http.StatusOK,
+			deprecation:  "@1790812800",
+			sunset:       "Thu, 01 Apr 2027 00:00:00 GMT",
+		},
+		{
+			deprecations: map[string]*Deprecation{v1: {Since: since}},
+			url:          "/book/1",
+			status:       http.StatusOK,
+			deprecation:  "@1790812800",
+		},
+		{
+			deprecations: map[string]*Deprecation{v1: {Since: since}},
+			url:          "/v1/book/x",
+			status:       http.StatusBadRequest,
+			deprecation:  "@1790812800",
+		},
+		{
+			deprecations: map[string]*Deprecation{v1: {Since: since}},
+			url:          "/healthz",
+			status:       http.StatusOK,
+		},
+	} {
+		server := New(&Config{Deprecations: test.deprecations}, searchIn)
+
+		recorder := serveRequest(t, server, "GET", test.url, "", "")
+		if recorder.Code != test.status {
+			t.Errorf("%d: expected status: %d, got: %d", i, test.status, recorder.Code)
+		}
+		if deprecation := recorder.Header().Get("Deprecation"); deprecation != test.deprecation {
+			t.Errorf("%d: expected Deprecation: %q, got: %q", i, test.deprecation, deprecation)
+		}
+		if sunset := recorder.Header().Get("Sunset"); sunset != test.sunset {
+			t.Errorf("%d: expected Sunset: %q, got: %q", i, test.sunset, sunset)
+		}
+	}
+
+	server := New(nil, searchIn)
+	versioned := serveRequest(t, server, "GET", "/v1/book/1", "", "")
+	alias := serveRequest(t, server, "GET", "/book/1", "", "")
+	if versioned.Body.String() != alias.Body.String() {
+		t.Errorf("expected /book/1 to respond like /v1/book/1, got: %s and %s", alias.Body.String(), versioned.Body.String())
+	}
+
+	spec := readSpec(t, New(&Config{Deprecations: map[string]*Deprecation{v1: {Since: since}}}, nil))
+	paths := spec["paths"].(map[string]interface{})
+	for _, path := range []string{"/v1/book/{id}", "/book/{id}"} {
+		operation := paths[path].(map[string]interface{})["get"].(map[string]interface{})
+		if operation["deprecated"] != true {
+			t.Errorf("expected GET %s to be documented as deprecated", path)
+		}
+	}
+	if _, ok := paths["/healthz"].(map[string]interface{})["get"].(map[string]interface{})["deprecated"]; ok {
+		t.Errorf("expected GET /healthz not to be documented as deprecated")
+	}
+}
+
+// TestParseDeprecation tests parsing deprecations of versions.
+func TestParseDeprecation(t *testing.T) {
+	for _, test := range []struct {
+		s           string
+		version     string
+		deprecation *Deprecation
+		ok          bool
+	}{
+		{
+			s:           "v1=2026-10-01/2027-04-01",
+			version:     v1,
+			deprecation: &Deprecation{Since: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Sunset: time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)},
+			ok:          true,
+		},
+		{
+			s:           "V1=2026-10-01",
+			version:     v1,
+			deprecation: &Deprecation{Since: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+			ok:          true,
+		},
+		{s: "v1", ok: false},
+		{s: "v1=", ok: false},
+		{s: "v0=2026-10-01", ok: false},
+		{s: "v1=October", ok: false},
+		{s: "v1=2026-10-01/never", ok: false},
+		{s: "v1=2026-10-01/2026-09-01", ok: false},
+	} {
+		version, deprecation, err := ParseDeprecation(test.s)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: expected success: %t, got error: %v", test.s, test.ok, err)
+			continue
+		}
+		if !test.ok {
+			continue
+		}
+
+		if version != test.version {
+			t.Errorf("%q: expected version: %s, got: %s", test.s, test.version, version)
+		}
+		if !deprecation.Since.Equal(test.deprecation.Since) || !deprecation.Sunset.Equal(test.deprecation.Sunset) {
+			t.Errorf("%q: expected deprecation: %+v, got: %+v", test.s, *test.deprecation, *deprecation)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// maxBodySize is the maximum size in bytes of a request's body.
+const maxBodySize = 1 << 20
+
+// insert is a handler for POST /books endpoint.
+func (s *Server) insert(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := insertResponse(http.MaxBytesReader(w, r.Body, maxBodySize), s.searchIn)
+	if ok {
+		s.invalidate()
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// replace is a handler for PUT /book/{id} endpoint.
+func (s *Server) replace(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := replaceResponse(http.MaxBytesReader(w, r.Body, maxBodySize), s.searchIn, mux.Vars(r)["id"])
+	if ok {
+		s.invalidate()
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// patch is a handler for PATCH /book/{id} endpoint.
+func (s *Server) patch(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := patchResponse(http.MaxBytesReader(w, r.Body, maxBodySize), s.searchIn, mux.Vars(r)["id"])
+	if ok {
+		s.invalidate()
+		write(w, r, response, status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// remove is a handler for DELETE /book/{id} endpoint.
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	response, status, ok := removeResponse(s.searchIn, mux.Vars(r)["id"])
+	if ok {
+		s.invalidate()
+		w.WriteHeader(status)
+	} else {
+		message, ok := response.(string)
+		if ok {
+			writeError(w, r, message, status)
+		}
+	}
+}
+
+// insertResponse adds the book in given body to the database and returns a response,
+// a status code, and bool indicating if the operation was performed successfully. It
+// should be used by Server.insert.
+func insertResponse(body io.Reader, searchIn *SearchIn) (interface{}, int, bool) {
+	book := new(books.Book)
+	if err := decodeJSON(body, book); err != nil {
+		return fmt.Sprintf("Unable to decode book: %s.", err.Error()), http.StatusBadRequest, false
+	}
+
+	book, err := books.Insert(searchIn.booksIn(), book)
+	if err != nil {
+		return writeFailure(err, "Insert failed.")
+	}
+	return book, http.StatusCreated, true
+}
+
+// replaceResponse replaces the book with given id with the book in given body and
+// returns a response, a status code, and bool indicating if the operation was
+// performed successfully. It should be used by Server.replace.
+func replaceResponse(body io.Reader, searchIn *SearchIn, idString string) (interface{}, int, bool) {
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return fmt.Sprintf("Invalid id \"%s\".", idString), http.StatusBadRequest, false
+	}
+
+	book := new(books.Book)
+	if err := decodeJSON(body, book); err != nil {
+		return fmt.Sprintf("Unable to decode book: %s.", err.Error()), http.StatusBadRequest, false
+	}
+	return update(searchIn, id, book)
+}
+
+// patchResponse modifies the fields given in body of the book with given id and
+// returns a response, a status code, and bool indicating if the operation was
+// performed successfully. It should be used by Server.patch.
+func patchResponse(body io.Reader, searchIn *SearchIn, idString string) (interface{}, int, bool) {
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return fmt.Sprintf("Invalid id \"%s\".", idString), http.StatusBadRequest, false
+	}
+
+	book, err := books.SearchByID(searchIn.booksIn(), id)
+	if err != nil {
+		return writeFailure(err, "Search failed.")
+	}
+
+	if err := decodeJSON(body, book); err != nil {
+		return fmt.Sprintf("Unable to decode book: %s.", err.Error()), http.StatusBadRequest, false
+	}
+	return update(searchIn, id, book)
+}
+
+// removeResponse deletes the book with given id and returns a response, a status
+// code, and bool indicating if the operation was performed successfully. It should
+// be used by Server.remove.
+func removeResponse(searchIn *SearchIn, idString string) (interface{}, int, bool) {
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return fmt.Sprintf("Invalid id \"%s\".", idString), http.StatusBadRequest, false
+	}
+
+	err = books.Delete(searchIn.booksIn(), id)
+	if err != nil {
+		return writeFailure(err, "Delete failed.")
+	}
+	return nil, http.StatusNoContent, true
+}
+
+// update stores given book as the book with given id, and returns a response, a
+// status code, and bool indicating if the operation was performed successfully.
+// The book's ID must be either id or unspecified.
+func update(searchIn *SearchIn, id int, book *books.Book) (interface{}, int, bool) {
+	if book.ID != 0 && book.ID != id {
+		return "Book's ID doesn't match the URL.", http.StatusBadRequest, false
+	}
+	book.ID = id
+
+	book, err := books.Update(searchIn.booksIn(), book)
+	if err != nil {
+		return writeFailure(err, "Update failed.")
+	}
+	return book, http.StatusOK, true
+}
+
+// writeFailure returns a response, a status code, and false based on an error
+// returned by a books function. message is used for unexpected errors.
+func writeFailure(err error, message string) (interface{}, int, bool) {
+	switch err.(type) {
+	case *books.ValidationError:
+		return fmt.Sprintf("Invalid book: %s.", err.Error()), http.StatusBadRequest, false
+	}
+
+	switch err {
+	case books.ErrNotFound:
+		return "Book not found.", http.StatusNotFound, false
+	case books.ErrExists:
+		return "A book with the same ID exists.", http.StatusConflict, false
+	default:
+		return message, http.StatusInternalServerError, false
+	}
+}
+
+// decodeJSON decodes a JSON value from body into v. Fields missing from body are
+// left unchanged, and unknown fields are rejected.
+func decodeJSON(body io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// invalidate discards data derived from the datastore after it's modified.
+func (s *Server) invalidate() {
+	s.indexMutex.Lock()
+	defer s.indexMutex.Unlock()
+
+	s.index = nil
+	s.results.clear()
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/auth"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// TestWrite tests creating, updating and deleting books. Requests are
+// performed in order, each on the datastore modified by previous ones.
+func TestWrite(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	keys, err := auth.New(datastore, "keys")
+	if err != nil {
+		t.Fatalf("failed to create keys: %s", err.Error())
+	}
+	admin, _, err := keys.Add("admin", auth.Admin)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+
+	server := New(
+		&Config{Keys: keys},
+		&SearchIn{
+			Datastore: datastore,
+			BookTable: bookTable,
+		},
+	)
+
+	const newBook = `{"Title": "Good Omens", "Authors": "Terry Pratchett-Neil Gaiman", "AverageRating": 4.25, ` +
+		`"ISBN": "60853980", "ISBN13": "9780060853983", "LanguageCode": "eng", "Pages": 491}`
+
+	for i, test := range []struct {
+		method string
+		url    string
+		token  string
+		body   string
+		status int
+		title  string // Expected title of returned book, not checked if empty.
+	}{
+		{method: "POST", url: "/books", token: "", body: newBook, status: http.StatusUnauthorized},
+		{method: "POST", url: "/books", token: "wrong", body: newBook, status: http.StatusUnauthorized},
+		{method: "POST", url: "/books", token: admin, body: newBook, status: http.StatusCreated, title: "Good Omens"},
+		{method: "POST", url: "/books", token: admin, body: `{"ID": 1, "Title": "Taken"}`, status: http.StatusBadRequest},
+		{method: "POST", url: "/books", token: admin, body: strings.Replace(newBook, "{", `{"ID": 1, `, 1), status: http.StatusConflict},
+		{method: "POST", url: "/books", token: admin, body: `{"Name": "Unknown field"}`, status: http.StatusBadRequest},
+		{method: "POST", url: "/books", token: admin, body: `{"Title": `, status: http.StatusBadRequest},
+		{method: "PATCH", url: "/book/22", token: admin, body: `{"Title": "African Diary"}`, status: http.StatusOK, title: "African Diary"},
+		{method: "PATCH", url: "/book/22", token: admin, body: `{"AverageRating": 7}`, status: http.StatusBadRequest},
+		{method: "PATCH", url: "/book/22", token: admin, body: `{"ID": 23}`, status: http.StatusBadRequest},
+		{method: "PATCH", url: "/book/7", token: admin, body: `{"Title": "Missing"}`, status: http.StatusNotFound},
+		{method: "PUT", url: "/book/24", token: admin, body: strings.Replace(newBook, "Good Omens", "Replaced", 1), status: http.StatusOK, title: "Replaced"},
+		{method: "PUT", url: "/book/7", token: admin, body: newBook, status: http.StatusNotFound},
+		{method: "PUT", url: "/book/x", token: admin, body: newBook, status: http.StatusBadRequest},
+		{method: "DELETE", url: "/book/25", token: admin, status: http.StatusNoContent},
+		{method: "DELETE", url: "/book/25", token: admin, status: http.StatusNotFound},
+		{method: "GET", url: "/book/22", status: http.StatusOK, title: "African Diary"},
+	} {
+		t.Run(
+			fmt.Sprintf("test: %d", i),
+			func(t *testing.T) {
+				recorder := serveRequest(t, server, test.method, test.url, test.token, test.body)
+				if recorder.Code != test.status {
+					t.Fatalf("expected status: %d, got: %d, body: %s", test.status, recorder.Code, recorder.Body.String())
+				}
+
+				if test.title != "" {
+					book := new(books.Book)
+					if err := json.Unmarshal(recorder.Body.Bytes(), book); err != nil {
+						t.Fatalf("failed to decode response: %s", err.Error())
+					}
+					if book.Title != test.title {
+						t.Errorf("expected title: %s, got: %s", test.title, book.Title)
+					}
+				}
+			},
+		)
+	}
+}
+
+// serveRequest performs a request, with given bearer token if not empty, using
+// server's router and returns the recorded response.
+func serveRequest(t *testing.T, server *Server, method, url, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	request, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	return serveRecorded(server, request)
+}
+
+// serveRecorded serves a request using server's router and returns the recorded
+// response.
+func serveRecorded(server *Server, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
@@ -0,0 +1,220 @@
+// Package auth manages API keys used to authenticate requests to the API.
+// Keys are stored hashed in the datastore, and each key has a role that
+// specifies what requests it can be used for.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Role specifies what requests a key can be used for.
+type Role string
+
+// Supported roles.
+const (
+	ReadOnly Role = "read-only" // Can search for books.
+	Admin    Role = "admin"     // Can search for, create, update and delete books.
+)
+
+// prefix is the prefix of all generated keys, used to recognize them.
+const prefix = "bfr_"
+
+// ErrInvalidKey is returned when authenticating with a key that doesn't exist.
+var ErrInvalidKey = errors.New("invalid key")
+
+// ErrNotFound is returned when revoking a key that doesn't exist.
+var ErrNotFound = errors.New("failed to find key")
+
+// ParseRole returns the role with the given name.
+func ParseRole(name string) (Role, error) {
+	switch role := Role(name); role {
+	case ReadOnly, Admin:
+		return role, nil
+	default:
+		return "", fmt.Errorf("unknown role %q", name)
+	}
+}
+
+// Allows returns true if a key with role r can be used for requests that
+// require the given role.
+func (r Role) Allows(required Role) bool {
+	return r == Admin || r == required
+}
+
+// Key is a stored API key. The key itself is only known when it's created.
+type Key struct {
+	ID      int
+	Name    string    // Description of the key's owner or use.
+	Role    Role      // Requests the key can be used for.
+	Created time.Time // Time the key was created.
+}
+
+// Keys is a set of API keys stored in a table of a database.
+type Keys struct {
+	datastore *sql.DB
+	table     string
+}
+
+// New returns the set of keys stored in given table, creating the table if
+// it doesn't exist.
+func New(datastore *sql.DB, table string) (*Keys, error) {
+	_, err := datastore.Exec(
+		fmt.Sprintf(
+			"create table if not exists %s ("+
+				"id integer not null primary key, "+
+				"name text, "+
+				"role text, "+
+				"hash text not null unique, "+
+				"created integer);",
+			table,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keys{
+		datastore: datastore,
+		table:     table,
+	}, nil
+}
+
+// Add generates and stores a new key with given name and role. The generated
+// key is returned, along with its stored representation. Only a hash of the
+// key is stored, so it can't be retrieved later.
+func (k *Keys) Add(name string, role Role) (string, *Key, error) {
+	if _, err := ParseRole(string(role)); err != nil {
+		return "", nil, err
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, err
+	}
+
+	secret := prefix + hex.EncodeToString(random)
+	created := time.Now().UTC().Truncate(time.Second)
+	result, err := k.datastore.Exec(
+		fmt.Sprintf("insert into %s (name, role, hash, created) values (?, ?, ?, ?);", k.table),
+		name,
+		string(role),
+		hash(secret),
+		created.Unix(),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, &Key{
+		ID:      int(id),
+		Name:    name,
+		Role:    role,
+		Created: created,
+	}, nil
+}
+
+// List returns all stored keys, ordered by ID.
+func (k *Keys) List() ([]*Key, error) {
+	rows, err := k.datastore.Query(fmt.Sprintf("select id, name, role, created from %s order by id;", k.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*Key, 0)
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke deletes the key with the given ID, after which it can't be used to
+// authenticate.
+func (k *Keys) Revoke(id int) error {
+	result, err := k.datastore.Exec(fmt.Sprintf("delete from %s where id = ?;", k.table), id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the stored key matching given key, or ErrInvalidKey.
+func (k *Keys) Authenticate(secret string) (*Key, error) {
+	row := k.datastore.QueryRow(
+		fmt.Sprintf("select id, name, role, created from %s where hash = ?;", k.table),
+		hash(secret),
+	)
+
+	key, err := scanKey(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidKey
+	}
+	return key, err
+}
+
+// contextKey is the type of keys of values stored in a context by this package.
+type contextKey int
+
+// keyContextKey is the context key of an authenticated Key.
+const keyContextKey contextKey = 0
+
+// NewContext returns a copy of ctx that carries given authenticated key.
+func NewContext(ctx context.Context, key *Key) context.Context {
+	return context.WithValue(ctx, keyContextKey, key)
+}
+
+// FromContext returns the authenticated key carried by ctx, if any.
+func FromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(keyContextKey).(*Key)
+	return key, ok
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanKey scans a key's id, name, role and creation time.
+func scanKey(row scanner) (*Key, error) {
+	key := new(Key)
+	var role string
+	var created int64
+	if err := row.Scan(&key.ID, &key.Name, &role, &created); err != nil {
+		return nil, err
+	}
+
+	key.Role = Role(role)
+	key.Created = time.Unix(created, 0).UTC()
+	return key, nil
+}
+
+// hash returns the hex encoded SHA-256 hash of a key. Keys are random and long,
+// so a fast unsalted hash is enough to protect them if the datastore leaks.
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestKeys tests adding, listing, authenticating with, and revoking keys.
+func TestKeys(t *testing.T) {
+	datastore, _, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	keys, err := New(datastore, "keys")
+	if err != nil {
+		t.Fatalf("failed to create keys: %s", err.Error())
+	}
+
+	reader, readerKey, err := keys.Add("reader", ReadOnly)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+	admin, adminKey, err := keys.Add("admin", Admin)
+	if err != nil {
+		t.Fatalf("failed to add key: %s", err.Error())
+	}
+	if _, _, err := keys.Add("unknown", Role("owner")); err == nil {
+		t.Errorf("expected adding a key with an unknown role to fail")
+	}
+
+	if !strings.HasPrefix(reader, prefix) || reader == admin {
+		t.Errorf("generated keys aren't unique, or are missing prefix: %s, %s", reader, admin)
+	}
+
+	listed, err := keys.List()
+	if err != nil {
+		t.Fatalf("failed to list keys: %s", err.Error())
+	}
+	if len(listed) != 2 || *listed[0] != *readerKey || *listed[1] != *adminKey {
+		t.Errorf("incorrect list of keys: %v", listed)
+	}
+
+	for secret, expected := range map[string]*Key{reader: readerKey, admin: adminKey} {
+		key, err := keys.Authenticate(secret)
+		if err != nil {
+			t.Fatalf("failed to authenticate: %s", err.Error())
+		}
+		if *key != *expected {
+			t.Errorf("expected key: %v, got: %v", *expected, *key)
+		}
+	}
+
+	if _, err := keys.Authenticate(prefix + "0000"); err != ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got: %v", err)
+	}
+
+	var stored string
+	datastore.QueryRow("select hash from keys where id = ?;", readerKey.ID).Scan(&stored)
+	if stored == reader || stored != hash(reader) {
+		t.Errorf("key isn't stored hashed: %s", stored)
+	}
+
+	if err := keys.Revoke(readerKey.ID); err != nil {
+		t.Fatalf("failed to revoke key: %s", err.Error())
+	}
+	if err := keys.Revoke(readerKey.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err := keys.Authenticate(reader); err != ErrInvalidKey {
+		t.Errorf("expected revoked key to be invalid, got: %v", err)
+	}
+}
+
+// TestRoles tests parsing roles and checking their permissions.
+func TestRoles(t *testing.T) {
+	for _, test := range []struct {
+		role     Role
+		required Role
+		allowed  bool
+	}{
+		{ReadOnly, ReadOnly, true},
+		{ReadOnly, Admin, false},
+		{Admin, ReadOnly, true},
+		{Admin, Admin, true},
+	} {
+		if test.role.Allows(test.required) != test.allowed {
+			t.Errorf("expected %s allowing %s to be %t", test.role, test.required, test.allowed)
+		}
+	}
+
+	if role, err := ParseRole("admin"); err != nil || role != Admin {
+		t.Errorf("failed to parse admin role: %v", err)
+	}
+	if _, err := ParseRole("owner"); err == nil {
+		t.Errorf("expected parsing unknown role to fail")
+	}
+}
+
+// TestContext tests carrying a key in a context.
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Errorf("expected no key in an empty context")
+	}
+
+	key := &Key{ID: 1, Name: "reader", Role: ReadOnly}
+	if carried, ok := FromContext(NewContext(context.Background(), key)); !ok || carried != key {
+		t.Errorf("expected carried key: %v, got: %v", key, carried)
+	}
+}
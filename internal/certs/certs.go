@@ -0,0 +1,228 @@
+// Package certs loads TLS configurations of servers and clients from PEM files,
+// and reloads certificates when they're renewed.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sudo-sturbia/bfr/v2/internal/graceful"
+)
+
+// redirectTimeout is the maximum duration for reading a request, and writing a
+// response, of plain HTTP requests that are redirected to HTTPS.
+const redirectTimeout = 5 * time.Second
+
+// ServerConfig holds paths of PEM files used by a server to serve HTTPS.
+type ServerConfig struct {
+	CertFile     string // Server's certificate, optionally followed by intermediate certificates.
+	KeyFile      string // Private key of the server's certificate.
+	ClientCAFile string // Certificates that clients' certificates must be signed by, clients aren't authenticated if empty.
+	RedirectPort string // Port to redirect plain HTTP requests to HTTPS from, requests aren't redirected if empty.
+}
+
+// ClientConfig holds paths of PEM files used by a client to make HTTPS requests.
+type ClientConfig struct {
+	CAFile   string // Certificates that servers' certificates must be signed by, the system's if empty.
+	CertFile string // Client's certificate, presented to servers that authenticate clients, none if empty.
+	KeyFile  string // Private key of the client's certificate.
+}
+
+// Load returns a TLS configuration of a server that supports HTTP/2, and the
+// Reloader of its certificate.
+func (c *ServerConfig) Load() (*tls.Config, *Reloader, error) {
+	reloader, err := NewReloader(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if c.ClientCAFile != "" {
+		config.ClientCAs, err = LoadPool(c.ClientCAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, reloader, nil
+}
+
+// Load returns a TLS configuration of a client, and the Reloader of its
+// certificate, nil if the client has none.
+func (c *ClientConfig) Load() (*tls.Config, *Reloader, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var err error
+	if c.CAFile != "" {
+		config.RootCAs, err = LoadPool(c.CAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var reloader *Reloader
+	if c.CertFile != "" {
+		reloader, err = NewReloader(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.GetClientCertificate = reloader.GetClientCertificate
+	}
+	return config, reloader, nil
+}
+
+// LoadPool returns a pool of the PEM encoded certificates in file.
+func LoadPool(file string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+// Reloader holds a certificate and its private key loaded from files, which can
+// be reloaded while the certificate is in use, e.g. after it's renewed.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mutex sync.RWMutex
+	cert  *tls.Certificate
+}
+
+// NewReloader returns a Reloader of the certificate and key in given files.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reloads the certificate and key from their files. The previous
+// certificate is kept if loading fails.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.cert = &cert
+	return nil
+}
+
+// GetCertificate returns the certificate, used as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.cert, nil
+}
+
+// GetClientCertificate returns the certificate, used as
+// tls.Config.GetClientCertificate.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.cert, nil
+}
+
+// ReloadOnHangup reloads given certificates whenever the process receives a
+// SIGHUP, until ctx is done. Nil reloaders are ignored, and failures are logged.
+func ReloadOnHangup(ctx context.Context, reloaders ...*Reloader) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			for _, r := range reloaders {
+				if r == nil {
+					continue
+				}
+
+				if err := r.Reload(); err != nil {
+					log.Warnf("Failed to reload certificate %s: %s.", r.certFile, err.Error())
+				} else {
+					log.Infof("Reloaded certificate %s.", r.certFile)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ServeRedirect calls serve, while redirecting plain HTTP requests made on given
+// listener to HTTPS on given port, until serve returns. ctx is passed to serve,
+// and both stop serving once it's done, see graceful.Serve.
+func ServeRedirect(ctx context.Context, listener net.Listener, port string, shutdownTimeout time.Duration, serve func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	server := &http.Server{
+		Handler:      Redirect(port),
+		ReadTimeout:  redirectTimeout,
+		WriteTimeout: redirectTimeout,
+	}
+	redirected := make(chan error, 1)
+	go func() {
+		redirected <- graceful.Serve(ctx, server, listener, shutdownTimeout)
+	}()
+
+	err := serve(ctx)
+	cancel()
+	if redirectErr := <-redirected; err == nil {
+		err = redirectErr
+	}
+	return err
+}
+
+// Redirect returns a handler that redirects requests to the same host and URL
+// using HTTPS on given port.
+func Redirect(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host // Requests to the default port carry no port.
+		}
+
+		url := *r.URL
+		url.Scheme = "https"
+		url.Host = host
+		if port != "443" {
+			url.Host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, url.String(), http.StatusPermanentRedirect)
+	})
+}
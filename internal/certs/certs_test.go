@@ -0,0 +1,225 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestReloader tests reloading a renewed certificate, and keeping the previous
+// one if reloading fails.
+func TestReloader(t *testing.T) {
+	files, deferFn := testhelper.NewCertificates(t)
+	defer deferFn()
+
+	reloader, err := NewReloader(files.ServerCertFile, files.ServerKeyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %s", err.Error())
+	}
+	if name := commonName(t, reloader); name != "localhost" {
+		t.Errorf("expected certificate of: localhost, got: %s", name)
+	}
+
+	files.RenewServer(t, "renewed")
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("failed to reload certificate: %s", err.Error())
+	}
+	if name := commonName(t, reloader); name != "renewed" {
+		t.Errorf("expected certificate of: renewed, got: %s", name)
+	}
+
+	if err := os.Remove(files.ServerKeyFile); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := reloader.Reload(); err == nil {
+		t.Errorf("expected reloading a missing key to fail")
+	}
+	if name := commonName(t, reloader); name != "renewed" {
+		t.Errorf("expected certificate of: renewed, got: %s", name)
+	}
+
+	if _, err := NewReloader(files.ServerCertFile, files.ServerKeyFile); err == nil {
+		t.Errorf("expected loading a missing key to fail")
+	}
+}
+
+// TestReloadOnHangup tests that certificates are reloaded on SIGHUP.
+func TestReloadOnHangup(t *testing.T) {
+	files, deferFn := testhelper.NewCertificates(t)
+	defer deferFn()
+
+	reloader, err := NewReloader(files.ServerCertFile, files.ServerKeyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %s", err.Error())
+	}
+
+	// Keep SIGHUP from terminating the test if it arrives before ReloadOnHangup
+	// starts listening.
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ReloadOnHangup(ctx, reloader, nil)
+
+	files.RenewServer(t, "renewed")
+	for deadline := time.Now().Add(5 * time.Second); commonName(t, reloader) != "renewed"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected certificate to be reloaded")
+		}
+
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatalf("failed to send signal: %s", err.Error())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestLoad tests that servers and clients using loaded configurations
+// authenticate each other.
+func TestLoad(t *testing.T) {
+	files, deferFn := testhelper.NewCertificates(t)
+	defer deferFn()
+
+	serverConfig, _, err := (&ServerConfig{
+		CertFile:     files.ServerCertFile,
+		KeyFile:      files.ServerKeyFile,
+		ClientCAFile: files.CAFile,
+	}).Load()
+	if err != nil {
+		t.Fatalf("failed to load server's configuration: %s", err.Error())
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	// httptest's own certificate would be preferred over GetCertificate by
+	// StartTLS, so TLS is served by wrapping the listener instead.
+	server.Listener = tls.NewListener(server.Listener, serverConfig)
+	server.Start()
+	defer server.Close()
+	url := "https://" + server.Listener.Addr().String()
+
+	for _, test := range []struct {
+		config *ClientConfig
+		ok     bool
+	}{
+		{config: &ClientConfig{CAFile: files.CAFile, CertFile: files.ClientCertFile, KeyFile: files.ClientKeyFile}, ok: true},
+		{config: &ClientConfig{CAFile: files.CAFile}, ok: false},
+		{config: &ClientConfig{CertFile: files.ClientCertFile, KeyFile: files.ClientKeyFile}, ok: false},
+	} {
+		clientConfig, _, err := test.config.Load()
+		if err != nil {
+			t.Fatalf("failed to load client's configuration: %s", err.Error())
+		}
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+		resp, err := client.Get(url)
+		if err != nil {
+			if test.ok {
+				t.Errorf("%+v: expected request to succeed, got: %s", *test.config, err.Error())
+			}
+			continue
+		}
+		resp.Body.Close()
+
+		if !test.ok {
+			t.Errorf("%+v: expected request to fail", *test.config)
+		}
+	}
+
+	if _, _, err := (&ClientConfig{CAFile: files.ServerKeyFile}).Load(); err == nil {
+		t.Errorf("expected loading a file with no certificates to fail")
+	}
+}
+
+// TestRedirect tests redirecting requests to HTTPS.
+func TestRedirect(t *testing.T) {
+	for _, test := range []struct {
+		port     string
+		url      string
+		expected string
+	}{
+		{port: "443", url: "http://example.com/books?Authors=Douglas", expected: "https://example.com/books?Authors=Douglas"},
+		{port: "443", url: "http://example.com:80/book/1", expected: "https://example.com/book/1"},
+		{port: "6443", url: "http://localhost:6060/book/1", expected: "https://localhost:6443/book/1"},
+	} {
+		recorder := httptest.NewRecorder()
+		Redirect(test.port).ServeHTTP(recorder, httptest.NewRequest("GET", test.url, nil))
+
+		if recorder.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: expected status: %d, got: %d", test.url, http.StatusPermanentRedirect, recorder.Code)
+		}
+		if location := recorder.Header().Get("Location"); location != test.expected {
+			t.Errorf("%s: expected location: %s, got: %s", test.url, test.expected, location)
+		}
+	}
+}
+
+// TestServeRedirect tests that requests are redirected while serve runs.
+func TestServeRedirect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeRedirect(ctx, listener, "6443", time.Second, func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return nil
+		})
+	}()
+	<-started
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get("http://" + listener.Addr().String() + "/book/1")
+	if err != nil {
+		t.Fatalf("request failed: %s", err.Error())
+	}
+	resp.Body.Close()
+	if location := resp.Header.Get("Location"); location != "https://127.0.0.1:6443/book/1" {
+		t.Errorf("expected location: https://127.0.0.1:6443/book/1, got: %s", location)
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("expected no error, got: %s", err.Error())
+	}
+	if _, err := client.Get("http://" + listener.Addr().String()); err == nil {
+		t.Errorf("expected requests after shutdown to fail")
+	}
+}
+
+// commonName returns the common name of reloader's current certificate.
+func commonName(t *testing.T, reloader *Reloader) string {
+	t.Helper()
+	cert, err := reloader.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("failed to get certificate: %s", err.Error())
+	}
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %s", err.Error())
+	}
+	return parsed.Subject.CommonName
+}
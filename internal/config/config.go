@@ -4,6 +4,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/sudo-sturbia/bfr/v2/internal/datastore"
@@ -14,6 +15,25 @@ type Config struct {
 	Host      string            // Host to run the server on.
 	Port      string            // Port to run the server on.
 	Datastore *datastore.Config // Datastore's configuration options.
+
+	ReadTimeout     time.Duration // Maximum duration for reading a request.
+	WriteTimeout    time.Duration // Maximum duration for writing a response.
+	IdleTimeout     time.Duration // Maximum time to keep an idle connection open.
+	ShutdownTimeout time.Duration // Maximum time to wait for requests in progress when shutting down.
+
+	CacheMaxAge time.Duration // Maximum time read responses can be reused without revalidating.
+
+	SearchCacheSize int           // Maximum number of search results cached by the server.
+	SearchCacheTTL  time.Duration // Maximum time search results are cached by the server.
+
+	CORSMaxAge time.Duration // Maximum time browsers can cache responses to preflight requests.
+
+	GraphQLMaxDepth      int // Maximum depth of nested fields of GraphQL queries.
+	GraphQLMaxComplexity int // Maximum complexity, i.e. estimated number of resolved fields, of GraphQL queries.
+
+	APITimeout time.Duration // Maximum duration of each attempt of a frontend's request to the API.
+	APIRetries int           // Number of times a frontend's requests to the API are retried if they fail temporarily.
+	APIBackoff time.Duration // Time a frontend waits before retrying a request to the API the first time.
 }
 
 // New returns a new Config object with Port as 6060.
@@ -34,6 +54,22 @@ func NewOnPort(port string) *Config {
 			Dir:       fmt.Sprintf("%s/.config/bfr/", os.Getenv("HOME")),
 			Datastore: "bfr.db",
 			BookTable: "books",
+			KeyTable:  "keys",
+
+			MetadataTable: datastore.DefaultMetadataTable,
 		},
+		ReadTimeout:          5 * time.Second,
+		WriteTimeout:         30 * time.Second,
+		IdleTimeout:          2 * time.Minute,
+		ShutdownTimeout:      15 * time.Second,
+		CacheMaxAge:          time.Minute,
+		SearchCacheSize:      1000,
+		SearchCacheTTL:       10 * time.Minute,
+		CORSMaxAge:           10 * time.Minute,
+		GraphQLMaxDepth:      8,
+		GraphQLMaxComplexity: 1000,
+		APITimeout:           5 * time.Second,
+		APIRetries:           2,
+		APIBackoff:           100 * time.Millisecond,
 	}
 }
@@ -11,6 +11,7 @@ import (
 
 	_ "github.com/mattn/go-sqlite3" // Used with sql package.
 	log "github.com/sirupsen/logrus"
+	"github.com/sudo-sturbia/bfr/v2/internal/titles"
 )
 
 // columns is number of dataset's columns.
@@ -23,11 +24,26 @@ type Config struct {
 	Dir       string // Directory containing the datastore.
 	Datastore string // Datastore's name.
 	BookTable string // Table containing books.
+	KeyTable  string // Table containing API keys.
+
+	MetadataTable string // Table containing the datastore's metadata, DefaultMetadataTable if empty.
 }
 
 // Open opens a connection to a database specified by given configuration.
+// Datastores created before books had series and works are migrated by adding
+// the missing columns to the books' table and filling them, and metadata is
+// written if they have none, so they don't have to be recreated.
 func Open(config *Config) (*sql.DB, error) {
-	return sql.Open(config.Driver, fmt.Sprintf("file:%s%s", config.Dir, config.Datastore))
+	datastore, err := sql.Open(config.Driver, fmt.Sprintf("file:%s%s", config.Dir, config.Datastore))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrate(datastore, config); err != nil {
+		datastore.Close()
+		return nil, fmt.Errorf("failed to migrate datastore: %s", err.Error())
+	}
+	return datastore, nil
 }
 
 // New creates a new datastore to be used by the server. The datastore is created
@@ -38,7 +54,9 @@ func Open(config *Config) (*sql.DB, error) {
 // averageRating, isbn, isbn13, languageCode, pages, ratingsCount, textReviewsCount)
 // in this order. The dataset is processed line by line and corrupt lines (wrong
 // data types, incorrect number of columns, extra commas, etc..) are skipped (and
-// logged).
+// logged). Each book's series is parsed from its title, and editions of the same
+// work (same normalized title and first author) are grouped under one work ID.
+// The schema version and import time are stored as the datastore's metadata.
 // See https://www.kaggle.com/jealousleopard/goodreadsbooks
 func New(datasetPath string, config *Config, overwriteIfExists bool) error {
 	dataset, err := os.Open(datasetPath)
@@ -69,7 +87,11 @@ func New(datasetPath string, config *Config, overwriteIfExists bool) error {
 			"languageCode text, "+
 			"pages integer, "+
 			"ratingsCount integer, "+
-			"reviewsCount integer);", config.BookTable)
+			"reviewsCount integer, "+
+			"series text, "+
+			"seriesIndex text, "+
+			"workKey text, "+
+			"workID integer);", config.BookTable)
 
 	_, err = datastore.Exec(create)
 	if err != nil {
@@ -87,14 +109,20 @@ func New(datasetPath string, config *Config, overwriteIfExists bool) error {
 		return err
 	}
 
-	return nil
+	err = clusterWorks(tx, config)
+	if err != nil {
+		return err
+	}
+
+	return writeMetadata(tx, config.MetadataTable)
 }
 
 // insertBooks inserts books from a dataset (csv file) into a table using
-// given transaction. Corrupt lines are logged and skipped.
+// given transaction. Corrupt lines are logged and skipped. Books' series, and
+// volume numbers in their series, are parsed from titles.
 func insertBooks(dataset *os.File, tx *sql.Tx, config *Config) error {
 	// Use prepared statements to populate books' table in bfr's database.
-	insert := fmt.Sprintf("insert into %s values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?);", config.BookTable)
+	insert := fmt.Sprintf("insert into %s values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, null);", config.BookTable)
 	stmt, err := tx.Prepare(insert)
 	if err != nil {
 		return err
@@ -117,6 +145,7 @@ func insertBooks(dataset *os.File, tx *sql.Tx, config *Config) error {
 			continue
 		}
 
+		series, seriesIndex := titles.Series(fields[1])
 		_, err := stmt.Exec(
 			fields[0],
 			fields[1],
@@ -128,6 +157,9 @@ func insertBooks(dataset *os.File, tx *sql.Tx, config *Config) error {
 			fields[7],
 			fields[8],
 			fields[9],
+			series,
+			seriesIndex,
+			titles.WorkKey(fields[1], fields[2]),
 		)
 		if err != nil {
 			log.WithFields(
@@ -143,3 +175,21 @@ func insertBooks(dataset *os.File, tx *sql.Tx, config *Config) error {
 
 	return nil
 }
+
+// clusterWorks groups editions of the same work, which share a work key, by
+// setting their work ID to the smallest ID among them.
+func clusterWorks(tx *sql.Tx, config *Config) error {
+	index := fmt.Sprintf("create index if not exists %sWorkKey on %s (workKey);", config.BookTable, config.BookTable)
+	if _, err := tx.Exec(index); err != nil {
+		return err
+	}
+
+	update := fmt.Sprintf(
+		"update %s set workID = (select min(id) from %s as edition where edition.workKey = %s.workKey);",
+		config.BookTable,
+		config.BookTable,
+		config.BookTable,
+	)
+	_, err := tx.Exec(update)
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -33,11 +34,11 @@ func TestNew(t *testing.T) {
 
 	// Verify books.
 	books := map[string]bool{
-		"1,Harry Potter and the Half-Blood Prince (Harry Potter  #6),J.K. Rowling-Mary GrandPré,4.56,439785960,9780439785969,eng,652,1944099,26249":    true,
-		"2,Harry Potter and the Order of the Phoenix (Harry Potter  #5),J.K. Rowling-Mary GrandPré,4.49,439358078,9780439358071,eng,870,1996446,27613": true,
-		"3,Harry Potter and the Sorcerer's Stone (Harry Potter  #1),J.K. Rowling-Mary GrandPré,4.47,439554934,9780439554930,eng,320,5629932,70390":     true,
-		"4,Harry Potter and the Chamber of Secrets (Harry Potter  #2),J.K. Rowling,4.41,439554896,9780439554893,eng,352,6267,272":                      true,
-		"5,Harry Potter and the Prisoner of Azkaban (Harry Potter  #3),J.K. Rowling-Mary GrandPré,4.55,043965548X,9780439655484,eng,435,2149872,33964": true,
+		"1,Harry Potter and the Half-Blood Prince (Harry Potter  #6),J.K. Rowling-Mary GrandPré,4.56,439785960,9780439785969,eng,652,1944099,26249,Harry Potter,6,1":    true,
+		"2,Harry Potter and the Order of the Phoenix (Harry Potter  #5),J.K. Rowling-Mary GrandPré,4.49,439358078,9780439358071,eng,870,1996446,27613,Harry Potter,5,2": true,
+		"3,Harry Potter and the Sorcerer's Stone (Harry Potter  #1),J.K. Rowling-Mary GrandPré,4.47,439554934,9780439554930,eng,320,5629932,70390,Harry Potter,1,3":     true,
+		"4,Harry Potter and the Chamber of Secrets (Harry Potter  #2),J.K. Rowling,4.41,439554896,9780439554893,eng,352,6267,272,Harry Potter,2,4":                      true,
+		"5,Harry Potter and the Prisoner of Azkaban (Harry Potter  #3),J.K. Rowling-Mary GrandPré,4.55,043965548X,9780439655484,eng,435,2149872,33964,Harry Potter,3,5": true,
 	}
 
 	rows, err := datastore.Query("select * from books;")
@@ -60,6 +61,10 @@ func TestNew(t *testing.T) {
 			pages         int
 			ratingsCount  int
 			reviewsCount  int
+			series        string
+			seriesIndex   string
+			workKey       string
+			workID        int
 		)
 
 		rows.Scan(
@@ -73,10 +78,14 @@ func TestNew(t *testing.T) {
 			&pages,
 			&ratingsCount,
 			&reviewsCount,
+			&series,
+			&seriesIndex,
+			&workKey,
+			&workID,
 		)
 
 		row := fmt.Sprintf(
-			"%d,%s,%s,%.2f,%s,%s,%s,%d,%d,%d",
+			"%d,%s,%s,%.2f,%s,%s,%s,%d,%d,%d,%s,%s,%d",
 			id,
 			title,
 			authors,
@@ -87,6 +96,9 @@ func TestNew(t *testing.T) {
 			pages,
 			ratingsCount,
 			reviewsCount,
+			series,
+			seriesIndex,
+			workID,
 		)
 
 		if !books[row] {
@@ -100,3 +112,198 @@ func TestNew(t *testing.T) {
 		t.Errorf("Expected %d rows, found %d.", len(books), line)
 	}
 }
+
+// Test grouping editions of the same work.
+func TestClusterWorks(t *testing.T) {
+	config := &Config{
+		Driver:    "sqlite3",
+		Datastore: "testDatastore.db",
+		BookTable: "books",
+	}
+
+	err := New("../../test-data/booksTest.csv", config, true)
+	if err != nil {
+		t.Fatalf("Loading failed: %s.", err.Error())
+	}
+
+	datastore, err := sql.Open(config.Driver, fmt.Sprintf("file:%s", config.Datastore))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer datastore.Close()
+	defer os.Remove(config.Datastore)
+
+	for id, workID := range map[int]int{
+		1:  1,
+		8:  8,
+		12: 12,
+		13: 13,
+		14: 14,
+		16: 14,
+		18: 12,
+		21: 21,
+	} {
+		var found int
+		err := datastore.QueryRow("select workID from books where id = ?;", id).Scan(&found)
+		if err != nil {
+			t.Errorf("Failed to find book %d: %s.", id, err.Error())
+			continue
+		}
+
+		if found != workID {
+			t.Errorf("Incorrect work of book %d, expected %d, found %d.", id, workID, found)
+		}
+	}
+}
+
+// Test storing a datastore's metadata.
+func TestMetadata(t *testing.T) {
+	config := &Config{
+		Driver:    "sqlite3",
+		Datastore: "testDatastore.db",
+		BookTable: "books",
+
+		MetadataTable: "booksMetadata",
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	err := New("../../test-data/booksTest.csv", config, true)
+	if err != nil {
+		t.Fatalf("Loading failed: %s.", err.Error())
+	}
+
+	datastore, err := sql.Open(config.Driver, fmt.Sprintf("file:%s", config.Datastore))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer datastore.Close()
+	defer os.Remove(config.Datastore)
+
+	metadata, err := ReadMetadata(datastore, config.MetadataTable)
+	if err != nil {
+		t.Fatalf("Failed to read metadata: %s.", err.Error())
+	}
+
+	if _, err := ReadMetadata(datastore, DefaultMetadataTable); err == nil {
+		t.Errorf("Expected metadata to be stored only in the configured table.")
+	}
+
+	if metadata.SchemaVersion != SchemaVersion {
+		t.Errorf("Incorrect schema version, expected %d, found %d.", SchemaVersion, metadata.SchemaVersion)
+	}
+	if metadata.ImportedAt.Before(before) || metadata.ImportedAt.After(time.Now()) {
+		t.Errorf("Incorrect import time %s.", metadata.ImportedAt)
+	}
+	if !metadata.ModifiedAt.Equal(metadata.ImportedAt) || metadata.Revision != 0 {
+		t.Errorf("Incorrect modification time %s or revision %d.", metadata.ModifiedAt, metadata.Revision)
+	}
+
+	for i := 1; i <= 2; i++ {
+		tx, err := datastore.Begin()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := Touch(tx, config.MetadataTable); err != nil {
+			t.Fatalf("Failed to touch datastore: %s.", err.Error())
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		touched, err := ReadMetadata(datastore, config.MetadataTable)
+		if err != nil {
+			t.Fatalf("Failed to read metadata: %s.", err.Error())
+		}
+		if touched.Revision != i {
+			t.Errorf("Incorrect revision, expected %d, found %d.", i, touched.Revision)
+		}
+		if !touched.ModifiedAt.After(metadata.ModifiedAt) || !touched.ImportedAt.Equal(metadata.ImportedAt) {
+			t.Errorf("Incorrect modification time %s.", touched.ModifiedAt)
+		}
+		metadata = touched
+	}
+
+	if _, err := datastore.Exec("drop table booksMetadata;"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := ReadMetadata(datastore, config.MetadataTable); err == nil {
+		t.Errorf("Expected reading missing metadata to fail.")
+	}
+}
+
+// Test migrating a datastore created before books had series and works.
+func TestMigrate(t *testing.T) {
+	config := &Config{
+		Driver:    "sqlite3",
+		Datastore: "testDatastore.db",
+		BookTable: "books",
+	}
+	os.Remove(config.Datastore)
+	defer os.Remove(config.Datastore)
+
+	old, err := sql.Open(config.Driver, fmt.Sprintf("file:%s", config.Datastore))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, statement := range []string{
+		"create table books (id integer not null primary key, title text, authors text, averageRating float, " +
+			"isbn string, isbn13 string, languageCode text, pages integer, ratingsCount integer, reviewsCount integer, " +
+			"series text, seriesIndex text);",
+		"insert into books values (1, 'Harry Potter and the Half-Blood Prince (Harry Potter  #6)', 'J.K. Rowling-Mary GrandPré', 4.56, '439785960', '9780439785969', 'eng', 652, 1944099, 26249, null, null);",
+		"insert into books values (14, 'The Hitchhiker''s Guide to the Galaxy (Hitchhiker''s Guide to the Galaxy  #1)', 'Douglas Adams', 4.22, '1400052920', '9781400052929', 'eng', 215, 4416, 408, null, null);",
+		"insert into books values (16, 'The Hitchhiker''s Guide to the Galaxy (Hitchhiker''s Guide to the Galaxy  #1)', 'Douglas Adams-Stephen Fry', 4.22, '739322206', '9780739322208', 'eng', 6, 1222, 253, null, null);",
+		"insert into books values (21, 'A Short History of Nearly Everything', 'Bill Bryson-William Roberts', 4.2, '076790818X', '9780767908184', 'eng', 544, 228522, 8840, null, null);",
+	} {
+		if _, err := old.Exec(statement); err != nil {
+			t.Fatalf("Failed to create an old datastore: %s.", err.Error())
+		}
+	}
+	old.Close()
+
+	datastore, err := Open(config)
+	if err != nil {
+		t.Fatalf("Failed to open an old datastore: %s.", err.Error())
+	}
+
+	for id, expected := range map[int]string{
+		1:  "Harry Potter,6,1",
+		14: "Hitchhiker's Guide to the Galaxy,1,14",
+		16: "Hitchhiker's Guide to the Galaxy,1,14",
+		21: ",,21",
+	} {
+		var (
+			series      string
+			seriesIndex string
+			workID      int
+		)
+		err := datastore.QueryRow("select series, seriesIndex, workID from books where id = ?;", id).Scan(&series, &seriesIndex, &workID)
+		if err != nil {
+			t.Errorf("Failed to find book %d: %s.", id, err.Error())
+			continue
+		}
+
+		if found := fmt.Sprintf("%s,%s,%d", series, seriesIndex, workID); found != expected {
+			t.Errorf("Incorrect migration of book %d, expected %s, found %s.", id, expected, found)
+		}
+	}
+
+	metadata, err := ReadMetadata(datastore, config.MetadataTable)
+	if err != nil {
+		t.Fatalf("Failed to read a migrated datastore's metadata: %s.", err.Error())
+	}
+	if metadata.SchemaVersion != SchemaVersion || metadata.Revision != 0 {
+		t.Errorf("Incorrect metadata of a migrated datastore: %+v.", *metadata)
+	}
+
+	// Opening a migrated datastore again leaves it as is.
+	datastore.Close()
+	datastore, err = Open(config)
+	if err != nil {
+		t.Fatalf("Failed to open a migrated datastore: %s.", err.Error())
+	}
+	reopened, err := ReadMetadata(datastore, config.MetadataTable)
+	if err != nil || !reopened.ImportedAt.Equal(metadata.ImportedAt) {
+		t.Errorf("Expected a migrated datastore's metadata to be kept, got: %v, %v.", reopened, err)
+	}
+	datastore.Close()
+}
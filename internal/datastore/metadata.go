@@ -0,0 +1,140 @@
+package datastore
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// SchemaVersion is the version of the datastore's schema. It's incremented when
+// the schema changes, after which datastores must be recreated.
+const SchemaVersion = 1
+
+// DefaultMetadataTable is the table containing the datastore's metadata as
+// key-value pairs, used if no other table is given.
+const DefaultMetadataTable = "metadata"
+
+// metadataTable returns the given metadata table, or DefaultMetadataTable if
+// it's empty.
+func metadataTable(table string) string {
+	if table == "" {
+		return DefaultMetadataTable
+	}
+	return table
+}
+
+// Metadata describes a datastore.
+type Metadata struct {
+	SchemaVersion int       // Version of the schema the datastore was created with.
+	ImportedAt    time.Time // Time the dataset was imported.
+	ModifiedAt    time.Time // Time books were last modified, either imported or edited.
+	Revision      int       // Number of times books were edited since the dataset was imported.
+}
+
+// ReadMetadata reads the metadata of given datastore from given table.
+// Datastores created before metadata was stored have none, and an error is
+// returned.
+func ReadMetadata(datastore *sql.DB, table string) (*Metadata, error) {
+	values, err := readValues(datastore, table)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := new(Metadata)
+	metadata.SchemaVersion, err = strconv.Atoi(values["schemaVersion"])
+	if err != nil {
+		return nil, err
+	}
+
+	metadata.ImportedAt, err = time.Parse(time.RFC3339, values["importedAt"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Datastores that weren't edited since metadata was stored have no
+	// modification time or revision.
+	metadata.ModifiedAt = metadata.ImportedAt
+	if value, ok := values["modifiedAt"]; ok {
+		metadata.ModifiedAt, err = time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if value, ok := values["revision"]; ok {
+		metadata.Revision, err = strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return metadata, nil
+}
+
+// readValues returns all key-value pairs stored in given metadata table.
+func readValues(datastore *sql.DB, table string) (map[string]string, error) {
+	rows, err := datastore.Query("select key, value from " + metadataTable(table) + ";")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, rows.Err()
+}
+
+// Touch records that books were edited using given transaction, by
+// incrementing the datastore's revision and updating its modification time
+// in given metadata table.
+func Touch(tx *sql.Tx, table string) error {
+	if err := createMetadataTable(tx, table); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(
+		"insert into " + metadataTable(table) + " (key, value) values ('revision', '1') " +
+			"on conflict (key) do update set value = cast(value as integer) + 1;",
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"insert or replace into "+metadataTable(table)+" (key, value) values ('modifiedAt', ?);",
+		time.Now().UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// writeMetadata creates given metadata table if it doesn't exist, and stores
+// the current schema version and import time using given transaction.
+func writeMetadata(tx *sql.Tx, table string) error {
+	if err := createMetadataTable(tx, table); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for key, value := range map[string]string{
+		"schemaVersion": strconv.Itoa(SchemaVersion),
+		"importedAt":    now.Format(time.RFC3339),
+		"modifiedAt":    now.Format(time.RFC3339),
+		"revision":      "0",
+	} {
+		_, err := tx.Exec("insert or replace into "+metadataTable(table)+" (key, value) values (?, ?);", key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createMetadataTable creates given metadata table if it doesn't exist.
+func createMetadataTable(tx *sql.Tx, table string) error {
+	_, err := tx.Exec("create table if not exists " + metadataTable(table) + " (key text not null primary key, value text);")
+	return err
+}
@@ -0,0 +1,163 @@
+package datastore
+
+import (
+	"database/sql"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sudo-sturbia/bfr/v2/internal/titles"
+)
+
+// addedColumns are columns of the books' table that were added after the
+// table was first created, with their types, in order. Datastores created
+// before are migrated by adding the missing columns, see migrate.
+var addedColumns = []struct {
+	name string
+	typ  string
+}{
+	{name: "series", typ: "text"},
+	{name: "seriesIndex", typ: "text"},
+	{name: "workKey", typ: "text"},
+	{name: "workID", typ: "integer"},
+}
+
+// migrate adds columns that are missing from an existing books' table, and
+// fills them the same way New does, i.e. parses books' series from titles and
+// groups editions into works. Metadata is written, as New writes it, if the
+// datastore has none. Datastores that don't have a books' table yet are left
+// as is.
+func migrate(datastore *sql.DB, config *Config) error {
+	existing, err := tableColumns(datastore, config.BookTable)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	missing := make([]string, 0)
+	for _, column := range addedColumns {
+		if !existing[column.name] {
+			missing = append(missing, column.name)
+		}
+	}
+	_, err = ReadMetadata(datastore, config.MetadataTable)
+	hasMetadata := err == nil
+	if len(missing) == 0 && hasMetadata {
+		return nil
+	}
+
+	log.WithFields(
+		log.Fields{
+			"table":    config.BookTable,
+			"columns":  missing,
+			"metadata": !hasMetadata,
+		},
+	).Info("Migrating datastore.")
+
+	tx, err := datastore.Begin()
+	if err != nil {
+		return err
+	}
+	if len(missing) != 0 {
+		if err := addColumns(tx, config, existing); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := fillSeries(tx, config); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := clusterWorks(tx, config); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if !hasMetadata {
+		if err := writeMetadata(tx, config.MetadataTable); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// tableColumns returns the set of names of given table's columns, which is
+// empty if the table doesn't exist.
+func tableColumns(datastore *sql.DB, table string) (map[string]bool, error) {
+	rows, err := datastore.Query(fmt.Sprintf("select name from pragma_table_info('%s');", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// addColumns adds columns of addedColumns that don't exist to the books'
+// table using given transaction.
+func addColumns(tx *sql.Tx, config *Config, existing map[string]bool) error {
+	for _, column := range addedColumns {
+		if existing[column.name] {
+			continue
+		}
+
+		alter := fmt.Sprintf("alter table %s add column %s %s;", config.BookTable, column.name, column.typ)
+		if _, err := tx.Exec(alter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillSeries sets every book's series, volume number in its series, and work
+// key, which are parsed from the book's title and authors.
+func fillSeries(tx *sql.Tx, config *Config) error {
+	type book struct {
+		id      int
+		title   string
+		authors string
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("select id, title, authors from %s;", config.BookTable))
+	if err != nil {
+		return err
+	}
+
+	books := make([]*book, 0)
+	for rows.Next() {
+		b := new(book)
+		if err := rows.Scan(&b.id, &b.title, &b.authors); err != nil {
+			rows.Close()
+			return err
+		}
+		books = append(books, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	update := fmt.Sprintf("update %s set series = ?, seriesIndex = ?, workKey = ? where id = ?;", config.BookTable)
+	stmt, err := tx.Prepare(update)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, b := range books {
+		series, seriesIndex := titles.Series(b.title)
+		if _, err := stmt.Exec(series, seriesIndex, titles.WorkKey(b.title, b.authors), b.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,16 +1,33 @@
 package frontend
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
 	log "github.com/sirupsen/logrus"
+	"github.com/sudo-sturbia/bfr/v2/internal/logging"
 	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+	"github.com/sudo-sturbia/bfr/v2/pkg/client"
 )
 
+// A decoder to use for the search form's query parameters.
+var (
+	decoder = schema.NewDecoder()
+)
+
+// similarLimit is the number of similar books shown on a book's page.
+const similarLimit = 5
+
+// healthTimeout is the maximum time to wait for the API's health check.
+const healthTimeout = 2 * time.Second
+
 // searchForm serves the search form.
 func (s *Server) searchForm(w http.ResponseWriter, r *http.Request) {
 	s.tmpls[searchTmpl].Execute(w, nil)
@@ -18,72 +35,108 @@ func (s *Server) searchForm(w http.ResponseWriter, r *http.Request) {
 
 // searchResults serves the search results acquired from search form.
 func (s *Server) searchResults(w http.ResponseWriter, r *http.Request) {
-	books, err := results(s.apiURL, r.URL.RawQuery)
-	if err != nil {
-		s.serveError(w, r, err)
-	} else {
-		s.tmpls[resultsTmpl].Execute(w, books)
+	by := books.NewSearchBy()
+	if err := decoder.Decode(by, r.URL.Query()); err != nil {
+		s.serveError(w, r, fmt.Errorf("invalid search query: %s", err.Error()))
+		return
 	}
-}
 
-// serveBook serves a book based on an id.
-func (s *Server) serveBook(w http.ResponseWriter, r *http.Request) {
-	book, err := book(s.apiURL, mux.Vars(r)["id"])
+	books, err := s.api.Search(r.Context(), by)
 	if err != nil {
 		s.serveError(w, r, err)
 	} else {
-		s.tmpls[bookTmpl].Execute(w, book)
+		s.tmpls[resultsTmpl].Execute(w, books)
 	}
 }
 
-// serveError serves a static error page.
-func (s *Server) serveError(w http.ResponseWriter, r *http.Request, err error) {
-	log.WithFields(
-		log.Fields{
-			"Address": r.RemoteAddr,
-			"Method":  r.Method,
-			"URL":     r.URL.String(),
-		},
-	).Info(err.Error())
-	s.tmpls[errorTmpl].Execute(w, nil)
+// bookPage holds the data used to render a book's page.
+type bookPage struct {
+	*books.Book
+	Similar []*books.Book // Books to recommend on the page.
 }
 
-// results makes a request to the given api url and returns the response
-// as a book slice, and an error.
-func results(apiURL, query string) ([]*books.Book, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/books?%s", apiURL, query))
+// serveBook serves a book based on an id, along with a list of similar books.
+// Failing to find similar books is logged but doesn't prevent serving the book.
+func (s *Server) serveBook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		return nil, fmt.Errorf("failed to make API request: %s", err.Error())
+		s.serveError(w, r, fmt.Errorf("invalid id %q", mux.Vars(r)["id"]))
+		return
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+
+	book, err := s.api.Book(r.Context(), id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read API response: %s", err.Error())
+		s.serveError(w, r, err)
+		return
 	}
 
-	var books []*books.Book
-	if err = json.Unmarshal(body, &books); err != nil {
-		return nil, fmt.Errorf("invalid API reponse: %s", err.Error())
+	similar, err := s.api.Similar(r.Context(), id, similarLimit)
+	if err != nil {
+		log.WithFields(logging.Fields(r)).Info(err.Error())
 	}
-	return books, nil
+	s.tmpls[bookTmpl].Execute(w, &bookPage{Book: book, Similar: similar})
 }
 
-// book makes a request to the given api url and returns the response
-// as a book, and an error.
-func book(apiURL, id string) (*books.Book, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/book/%s", apiURL, id))
-	if err != nil {
-		return nil, fmt.Errorf("failed to make API request: %s", err.Error())
+// suggest gets suggestions from the API, and writes them as JSON. It's used by
+// the search form's script. Errors caused by the request are written as the
+// API responded with them.
+func (s *Server) suggest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 0
+	if query.Get("limit") != "" {
+		var err error
+		if limit, err = strconv.Atoi(query.Get("limit")); err != nil {
+			http.Error(w, "Invalid limit.", http.StatusBadRequest)
+			return
+		}
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+
+	suggestions, err := s.api.Suggest(r.Context(), query.Get("prefix"), query.Get("field"), limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read API response: %s", err.Error())
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+			http.Error(w, apiErr.Message, apiErr.StatusCode)
+			return
+		}
+
+		log.WithFields(logging.Fields(r)).Info(fmt.Sprintf("failed to make API request: %s", err.Error()))
+		http.Error(w, "Failed to get suggestions.", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// healthz reports the server as healthy if the API it uses is healthy.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+
+	if err := s.api.Health(ctx); err != nil {
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) {
+			http.Error(w, fmt.Sprintf("API is unhealthy, status: %d.", apiErr.StatusCode), http.StatusServiceUnavailable)
+			return
+		}
+
+		log.WithFields(logging.Fields(r)).Info(fmt.Sprintf("failed to make API request: %s", err.Error()))
+		http.Error(w, "API is unreachable.", http.StatusServiceUnavailable)
+		return
 	}
+	fmt.Fprintln(w, "ok")
+}
+
+// serveError serves a static error page.
+func (s *Server) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	log.WithFields(logging.Fields(r)).Info(err.Error())
+	s.tmpls[errorTmpl].Execute(w, nil)
+}
 
-	var book *books.Book
-	if err = json.Unmarshal(body, &book); err != nil {
-		return nil, fmt.Errorf("invalid API reponse: %s", err.Error())
+// forwardRequestID sends the ID of the request being served, carried by an API
+// request's context, to the API.
+func forwardRequestID(request *http.Request) {
+	if id := logging.RequestID(request.Context()); id != "" {
+		request.Header.Set(logging.Header, id)
 	}
-	return book, nil
 }
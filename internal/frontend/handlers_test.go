@@ -0,0 +1,202 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/api"
+	"github.com/sudo-sturbia/bfr/v2/internal/logging"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// TestSearchResults tests rendering books found by the API.
+func TestSearchResults(t *testing.T) {
+	apiServer, deferFn := newAPI(t, nil)
+	defer deferFn()
+
+	recorder := serve(t, newServer(t, apiServer.URL), "/search?Authors=Bill%20Bryson&RatingFloor=4")
+	body := recorder.Body.String()
+	if recorder.Code != http.StatusOK || !strings.Contains(body, "<title>Search Results - bfr</title>") {
+		t.Fatalf("expected search results, got: %d %s", recorder.Code, body)
+	}
+	if found := strings.Count(body, `class="book"`); found != 2 || !strings.Contains(body, "<b>A Short History of Nearly Everything</b>") {
+		t.Errorf("expected 2 books by Bill Bryson, got %d: %s", found, body)
+	}
+}
+
+// TestServeBook tests rendering a book's page, with and without similar books.
+func TestServeBook(t *testing.T) {
+	for _, failSimilar := range []bool{false, true} {
+		apiServer, deferFn := newAPI(t, func(w http.ResponseWriter, r *http.Request) bool {
+			if failSimilar && strings.HasSuffix(r.URL.Path, "/similar") {
+				http.Error(w, "Failed to find similar books.", http.StatusInternalServerError)
+				return true
+			}
+			return false
+		})
+
+		recorder := serve(t, newServer(t, apiServer.URL), "/book/21")
+		body := recorder.Body.String()
+		if recorder.Code != http.StatusOK || !strings.Contains(body, "<h1>A Short History of Nearly Everything</h1>") {
+			t.Errorf("similar failing: %t: expected book 21's page, got: %d %s", failSimilar, recorder.Code, body)
+		}
+		if strings.Contains(body, "You might also like") == failSimilar {
+			t.Errorf("similar failing: %t: incorrect similar books: %s", failSimilar, body)
+		}
+		deferFn()
+	}
+}
+
+// TestAPIErrors tests responding to requests that the API fails.
+func TestAPIErrors(t *testing.T) {
+	apiServer, deferFn := newAPI(t, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Query().Get("prefix") == "unavailable" {
+			http.Error(w, "Datastore is unavailable.", http.StatusInternalServerError)
+			return true
+		}
+		return false
+	})
+	defer deferFn()
+
+	server := newServer(t, apiServer.URL)
+	for _, test := range []struct {
+		url    string
+		status int
+		body   string // Expected body, or part of it if it's HTML.
+	}{
+		{url: "/suggest?prefix=a&field=isbn", status: http.StatusBadRequest, body: "Invalid field \"isbn\".\n"},
+		{url: "/suggest?prefix=unavailable", status: http.StatusBadGateway, body: "Failed to get suggestions.\n"},
+		{url: "/book/30", status: http.StatusOK, body: "There seems to be an error!"},
+		{url: "/search?SortBy=Wrong", status: http.StatusOK, body: "There seems to be an error!"},
+	} {
+		recorder := serve(t, server, test.url)
+		if recorder.Code != test.status || !strings.Contains(recorder.Body.String(), test.body) {
+			t.Errorf("%s: expected: %d %q, got: %d %q", test.url, test.status, test.body, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+// TestForwardRequestID tests sending the ID of the request being served with
+// requests to the API.
+func TestForwardRequestID(t *testing.T) {
+	var mutex sync.Mutex
+	ids := make([]string, 0)
+	apiServer, deferFn := newAPI(t, func(w http.ResponseWriter, r *http.Request) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		ids = append(ids, r.Header.Get(logging.Header))
+		return false
+	})
+	defer deferFn()
+
+	request := httptest.NewRequest("GET", "/book/14", nil)
+	request.Header.Set(logging.Header, "frontend-1234")
+	recorder := httptest.NewRecorder()
+	newServer(t, apiServer.URL).ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get(logging.Header) != "frontend-1234" {
+		t.Errorf("expected book 14's page with the request's ID, got: %d %q", recorder.Code, recorder.Header().Get(logging.Header))
+	}
+	if len(ids) != 2 || ids[0] != "frontend-1234" || ids[1] != "frontend-1234" {
+		t.Errorf("expected the book's and similar books' requests to carry the request's ID, got: %q", ids)
+	}
+}
+
+// TestHealthz tests reporting the health of the API the server uses.
+func TestHealthz(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	for _, test := range []struct {
+		name   string
+		api    string // URL of the API, a server responding with status is used if empty.
+		status int    // Status of the API's health check.
+		code   int
+		body   string
+	}{
+		{
+			name:   "healthy",
+			status: http.StatusOK,
+			code:   http.StatusOK,
+			body:   "ok\n",
+		},
+		{
+			name:   "unhealthy",
+			status: http.StatusServiceUnavailable,
+			code:   http.StatusServiceUnavailable,
+			body:   "API is unhealthy, status: 503.\n",
+		},
+		{
+			name: "unreachable",
+			api:  unreachable.URL,
+			code: http.StatusServiceUnavailable,
+			body: "API is unreachable.\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			api := test.api
+			if api == "" {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/healthz" {
+						t.Errorf("expected a request to /healthz, got: %s", r.URL.Path)
+					}
+					w.WriteHeader(test.status)
+				}))
+				defer server.Close()
+				api = server.URL
+			}
+
+			recorder := serve(t, newServer(t, api), "/healthz")
+			if recorder.Code != test.code || recorder.Body.String() != test.body {
+				t.Errorf("expected: %d %q, got: %d %q", test.code, test.body, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+// newAPI returns an API server of the test datastore. Requests are passed to
+// intercept first, if it's not nil, and are only served by the API if it
+// returns false. It also returns a function that frees resources, and should
+// be defered immediately after return.
+func newAPI(t *testing.T, intercept func(w http.ResponseWriter, r *http.Request) bool) (*httptest.Server, func()) {
+	t.Helper()
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+
+	handler := api.New(&api.Config{}, &api.SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if intercept == nil || !intercept(w, r) {
+			handler.ServeHTTP(w, r)
+		}
+	}))
+
+	return server, func() {
+		server.Close()
+		deferFn()
+	}
+}
+
+// newServer returns a frontend server that makes requests to the API at given
+// URL.
+func newServer(t *testing.T, api string) *Server {
+	t.Helper()
+	server, err := New(&Config{Static: "../../content/static"}, api+"/")
+	if err != nil {
+		t.Fatalf("failed to create server: %s", err.Error())
+	}
+	return server
+}
+
+// serve serves a GET request to given URL using server, and returns the
+// recorded response.
+func serve(t *testing.T, server *Server, url string) *httptest.ResponseRecorder {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest("GET", url, nil))
+	return recorder
+}
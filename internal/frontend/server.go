@@ -3,11 +3,20 @@
 package frontend
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/sudo-sturbia/bfr/v2/internal/certs"
+	"github.com/sudo-sturbia/bfr/v2/internal/graceful"
+	"github.com/sudo-sturbia/bfr/v2/internal/logging"
+	"github.com/sudo-sturbia/bfr/v2/internal/metrics"
+	"github.com/sudo-sturbia/bfr/v2/pkg/client"
 )
 
 // Names of available templates.
@@ -22,8 +31,13 @@ const (
 type Server struct {
 	cfg    *Config
 	router *mux.Router
-	apiURL string                        // URL of API to make requests to.
+	api    *client.Client                // Client of the API to make requests to.
 	tmpls  map[string]*template.Template // Map of HTML templates with names.
+
+	metrics  *metrics.Registry  // Metrics exposed by /metrics.
+	upstream *metrics.Histogram // Durations of API requests.
+
+	apiReloader *certs.Reloader // Reloader of the certificate presented to the API, nil if there's none.
 }
 
 // Config holds server's configuration options.
@@ -31,16 +45,66 @@ type Config struct {
 	Host   string // Host to run the server on.
 	Port   string // Port to run the server on.
 	Static string // Path to static files.
+
+	ReadTimeout     time.Duration // Maximum duration for reading a request, no limit if 0.
+	WriteTimeout    time.Duration // Maximum duration for writing a response, no limit if 0.
+	IdleTimeout     time.Duration // Maximum time to keep an idle connection open, no limit if 0.
+	ShutdownTimeout time.Duration // Maximum time to wait for requests in progress when shutting down, no limit if 0.
+
+	TLS    *certs.ServerConfig // Options of serving HTTPS, plain HTTP is served if nil.
+	APITLS *certs.ClientConfig // Options of making HTTPS requests to the API, system's defaults are used if nil.
+	APIKey string              // API key sent with requests to the API, none is sent if empty.
+
+	APITimeout time.Duration // Maximum duration of each attempt of an API request, no limit if 0.
+	APIRetries int           // Number of times API requests are retried if they fail temporarily.
+	APIBackoff time.Duration // Time to wait before the first retry of an API request, doubled before each following one.
 }
 
 // New returns a new, initialized frontend server.
 func New(cfg *Config, api string) (_ *Server, err error) {
 	s := &Server{
-		cfg:    cfg,
-		router: mux.NewRouter(),
-		apiURL: api,
+		cfg:     cfg,
+		router:  mux.NewRouter(),
+		metrics: metrics.NewRegistry(),
+	}
+
+	httpClient := http.DefaultClient
+	if cfg.APITLS != nil {
+		config, reloader, err := cfg.APITLS.Load()
+		if err != nil {
+			return nil, fmt.Errorf("New: %s", err.Error())
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		transport.ForceAttemptHTTP2 = true
+		httpClient = &http.Client{Transport: transport}
+		s.apiReloader = reloader
 	}
 
+	s.upstream = s.metrics.Histogram(
+		"bfr_frontend_upstream_duration_seconds",
+		"Durations of requests to the API in seconds, by endpoint and status code.",
+		metrics.DefaultBuckets,
+		"endpoint", "status",
+	)
+	s.api, err = client.New(api, &client.Config{
+		HTTPClient: httpClient,
+		Key:        cfg.APIKey,
+		Timeout:    cfg.APITimeout,
+		Retries:    cfg.APIRetries,
+		Backoff:    cfg.APIBackoff,
+		Prepare:    forwardRequestID,
+		Observe: func(endpoint, status string, duration time.Duration) {
+			s.upstream.Observe(duration.Seconds(), endpoint, status)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("New: %s", err.Error())
+	}
+
+	httpMetrics := metrics.NewHTTP(s.metrics, "bfr_frontend")
+
 	s.tmpls, err = newTemplates(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("New: %s", err.Error())
@@ -49,13 +113,86 @@ func New(cfg *Config, api string) (_ *Server, err error) {
 	s.router.HandleFunc("/", s.searchForm).Methods("GET")
 	s.router.HandleFunc("/search", s.searchResults).Methods("GET")
 	s.router.HandleFunc("/book/{id}", s.serveBook).Methods("GET")
+	s.router.HandleFunc("/suggest", s.suggest).Methods("GET")
+	s.router.HandleFunc("/healthz", s.healthz).Methods("GET")
+	s.router.Handle("/metrics", s.metrics).Methods("GET")
 	s.router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(s.cfg.Static))))
+
+	s.router.Use(logging.Middleware, httpMetrics.Middleware)
+
+	// Middleware only wraps matched routes, so requests that don't match one
+	// are logged and measured by their handlers.
+	s.router.NotFoundHandler = logging.Middleware(httpMetrics.Middleware(http.NotFoundHandler()))
+	s.router.MethodNotAllowedHandler = logging.Middleware(httpMetrics.Middleware(http.HandlerFunc(methodNotAllowed)))
 	return s, nil
 }
 
-// Run runs a server instance on the host and port specified in its config.
-func (s *Server) Run() {
-	http.ListenAndServe(fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port), s.router)
+// Run runs a server instance on the host and port specified in its config until
+// ctx is done, then shuts it down gracefully. If the server uses TLS, plain HTTP
+// requests on the configured redirect port are redirected to HTTPS. See Serve.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port))
+	if err != nil {
+		return err
+	}
+	if s.cfg.TLS == nil || s.cfg.TLS.RedirectPort == "" {
+		return s.Serve(ctx, listener)
+	}
+
+	redirect, err := net.Listen("tcp", fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.TLS.RedirectPort))
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	return certs.ServeRedirect(ctx, redirect, s.cfg.Port, s.cfg.ShutdownTimeout, func(ctx context.Context) error {
+		return s.Serve(ctx, listener)
+	})
+}
+
+// Serve serves requests on given listener until ctx is done, then stops
+// accepting connections and waits for requests in progress to finish. Requests
+// are served using TLS, and HTTP/2, if the server's config specifies a
+// certificate. Certificates of the server and the one presented to the API are
+// reloaded on SIGHUP. Returns an error if serving fails, or if requests don't
+// finish in time.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{
+		Handler:      s,
+		ReadTimeout:  s.cfg.ReadTimeout,
+		WriteTimeout: s.cfg.WriteTimeout,
+		IdleTimeout:  s.cfg.IdleTimeout,
+	}
+
+	var reloader *certs.Reloader
+	if s.cfg.TLS != nil {
+		var config *tls.Config
+		var err error
+		config, reloader, err = s.cfg.TLS.Load()
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		server.TLSConfig = config
+	}
+
+	if reloader != nil || s.apiReloader != nil {
+		reloadCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go certs.ReloadOnHangup(reloadCtx, reloader, s.apiReloader)
+	}
+
+	return graceful.Serve(ctx, server, listener, s.cfg.ShutdownTimeout)
+}
+
+// methodNotAllowed responds to requests whose method doesn't match their
+// route's.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "Method not allowed.", http.StatusMethodNotAllowed)
+}
+
+// ServeHTTP dispatches a request to the handler of the route it matches.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
 }
 
 // newTemplates parses templates and returns a new template map.
@@ -0,0 +1,73 @@
+// Package graceful contains functions used to run HTTP servers that shut down
+// gracefully, finishing requests in progress before returning.
+package graceful
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Serve serves requests using server on given listener until ctx is done, then
+// shuts server down. Requests are served using TLS if server.TLSConfig isn't nil,
+// in which case it must provide certificates. Shutting down stops accepting
+// connections, and waits for requests in progress to finish for at most
+// shutdownTimeout, or indefinitely if shutdownTimeout is 0. Returns an error if
+// serving or shutting down fails.
+func Serve(ctx context.Context, server *http.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+	served := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			served <- server.ServeTLS(listener, "", "")
+		} else {
+			served <- server.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx := context.Background()
+	if shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, shutdownTimeout)
+		defer cancel()
+	}
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// SignalContext returns a context that is done when the process receives a
+// SIGINT or SIGTERM, or when the returned function is called. The function
+// should be called once the context is no longer needed.
+func SignalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
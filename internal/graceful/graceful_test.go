@@ -0,0 +1,114 @@
+package graceful
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestServe tests that requests in progress finish before Serve returns.
+func TestServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+
+	started := make(chan struct{})
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte("done"))
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, server, listener, time.Second)
+	}()
+
+	responses := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+
+	<-started
+	cancel()
+
+	if response := <-responses; response != "done" {
+		t.Errorf("expected request in progress to finish, got: %s", response)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("expected no error, got: %s", err.Error())
+	}
+
+	if _, err := http.Get("http://" + listener.Addr().String()); err == nil {
+		t.Errorf("expected requests after shutdown to fail")
+	}
+}
+
+// TestServeTimeout tests that Serve returns an error if requests don't finish
+// before the shutdown timeout.
+func TestServeTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, server, listener, 50*time.Millisecond)
+	}()
+	go http.Get("http://" + listener.Addr().String())
+
+	<-started
+	cancel()
+	if err := <-served; err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got: %v", context.DeadlineExceeded, err)
+	}
+}
+
+// TestSignalContext tests that the context is done when a signal is received.
+func TestSignalContext(t *testing.T) {
+	ctx, stop := SignalContext()
+	defer stop()
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("failed to find process: %s", err.Error())
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		t.Skipf("failed to send signal: %s", err.Error())
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Errorf("expected context to be done after SIGTERM")
+	}
+}
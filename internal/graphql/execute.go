@@ -0,0 +1,703 @@
+// Package graphql implements executing GraphQL queries against a schema of
+// object types, whose fields are resolved by Go functions.
+//
+// Only queries are supported, there are no mutations, subscriptions,
+// interfaces, unions or enums, and the schema can't be introspected except
+// for __typename. Only the executed operation of a document is validated.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSelections is the maximum number of selections visited while planning a
+// query, which prevents fragments spread many times from taking long to plan.
+const maxSelections = 10000
+
+// maxCost is the cost at which complexity stops growing, to prevent overflows.
+const maxCost = 1<<31 - 1
+
+// Request is a GraphQL request, as sent in JSON bodies.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"` // Operation to execute, needed if the query has many.
+	Variables     map[string]interface{} `json:"variables"`     // Values of variables, as decoded from JSON.
+}
+
+// Response is the result of a request, encoded as JSON.
+type Response struct {
+	Data   json.RawMessage `json:"data,omitempty"` // Omitted if the request failed before execution.
+	Errors []*Error        `json:"errors,omitempty"`
+}
+
+// Error is an error of a request, e.g. a syntax error, or of a field.
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"` // Locations in the query the error refers to.
+	Path      []interface{} `json:"path,omitempty"`      // Response keys and list indices of the field that failed.
+}
+
+// Error returns the error's message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Location is a location in a query.
+type Location struct {
+	Line   int `json:"line"`   // Line, starting at 1.
+	Column int `json:"column"` // Column in characters, starting at 1.
+}
+
+// locatedError returns an error at given location.
+func locatedError(loc Location, format string, a ...interface{}) *Error {
+	return &Error{
+		Message:   fmt.Sprintf(format, a...),
+		Locations: []Location{loc},
+	}
+}
+
+// Limits limit queries a schema executes.
+type Limits struct {
+	MaxDepth      int // Maximum depth of nested fields, no limit if 0.
+	MaxComplexity int // Maximum complexity, i.e. estimated number of resolved fields, no limit if 0.
+}
+
+// Execute executes a query of given request. limits may be nil to execute
+// queries of any depth and complexity.
+//
+// Invalid requests, e.g. ones with syntax errors, unknown fields, or that
+// exceed the limits, fail before execution, and their responses have no data.
+// Otherwise, errors of fields are reported along with the data of the fields
+// that succeeded.
+func (s *Schema) Execute(ctx context.Context, req *Request, limits *Limits) *Response {
+	if limits == nil {
+		limits = &Limits{}
+	}
+
+	p := &planner{schema: s, limits: limits}
+	fields, err := p.planRequest(req)
+	if err != nil {
+		return &Response{Errors: []*Error{err.(*Error)}}
+	}
+
+	e := &executor{ctx: ctx}
+	data, failed := e.object(s.query, nil, fields, nil)
+	if failed {
+		data = nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return &Response{
+			Data:   json.RawMessage("null"),
+			Errors: []*Error{{Message: "Failed to encode response."}},
+		}
+	}
+	return &Response{Data: encoded, Errors: e.errors}
+}
+
+// plannedField is a field of a validated query, whose arguments are coerced,
+// and whose selections with the same response key are merged.
+type plannedField struct {
+	key    string
+	field  *Field // Queried field, nil if it's __typename.
+	args   map[string]interface{}
+	locs   []Location
+	fields []*plannedField // Selected fields of the field's value, if it's an object.
+}
+
+// collectedField is a field with its selections collected from a selection
+// set, before being planned.
+type collectedField struct {
+	key        string
+	name       string
+	field      *Field
+	args       map[string]interface{}
+	locs       []Location
+	selections []selection
+}
+
+// planner validates a query, and plans the fields to execute.
+type planner struct {
+	schema    *Schema
+	limits    *Limits
+	doc       *document
+	variables map[string]*variable
+	visited   int // Number of visited selections.
+}
+
+// planRequest parses and validates the query of a request, and returns the
+// fields of the operation to execute.
+func (p *planner) planRequest(req *Request) ([]*plannedField, error) {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	p.doc = doc
+
+	op, err := p.operation(req.OperationName)
+	if err != nil {
+		return nil, err
+	}
+	if op.kind != "query" {
+		return nil, locatedError(op.loc, "Schema doesn't support %ss.", op.kind)
+	}
+	if len(op.directives) != 0 {
+		return nil, locatedError(op.directives[0].loc, "Directive \"@%s\" may not be used on queries.", op.directives[0].name)
+	}
+	if err := p.defineVariables(op.variables, req.Variables); err != nil {
+		return nil, err
+	}
+
+	fields, complexity, err := p.plan(p.schema.query, op.selections, 1)
+	if err != nil {
+		return nil, err
+	}
+	if p.limits.MaxComplexity > 0 && complexity > p.limits.MaxComplexity {
+		return nil, locatedError(op.loc, "Query has a complexity of %d, which exceeds the maximum complexity of %d.", complexity, p.limits.MaxComplexity)
+	}
+	return fields, nil
+}
+
+// operation returns the operation of the document with given name, which may
+// be empty if the document has a single operation.
+func (p *planner) operation(name string) (*operation, error) {
+	names := make(map[string]bool)
+	for _, op := range p.doc.operations {
+		if op.name == "" && len(p.doc.operations) > 1 {
+			return nil, locatedError(op.loc, "This anonymous operation must be the only defined operation.")
+		}
+		if names[op.name] {
+			return nil, locatedError(op.loc, "There can be only one operation named %q.", op.name)
+		}
+		names[op.name] = true
+	}
+
+	if name == "" {
+		if len(p.doc.operations) != 1 {
+			return nil, &Error{Message: "Must provide operation name if query contains multiple operations."}
+		}
+		return p.doc.operations[0], nil
+	}
+	for _, op := range p.doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("Unknown operation named %q.", name)}
+}
+
+// defineVariables coerces the values of an operation's variables.
+func (p *planner) defineVariables(definitions []*variableDefinition, values map[string]interface{}) error {
+	p.variables = make(map[string]*variable)
+	for _, definition := range definitions {
+		if p.variables[definition.name] != nil {
+			return locatedError(definition.loc, "There can be only one variable named \"$%s\".", definition.name)
+		}
+
+		t, err := p.inputType(definition.typ, definition.loc)
+		if err != nil {
+			return err
+		}
+		v := &variable{typ: t}
+		p.variables[definition.name] = v
+
+		if definition.defaultValue != nil {
+			if v.value, _, err = coerceLiteral(definition.defaultValue, t, nil); err != nil {
+				return err
+			}
+			v.given, v.hasDefault = true, true
+		}
+
+		if value, ok := values[definition.name]; ok {
+			if v.value, err = coerceInput(value, t); err != nil {
+				return locatedError(definition.loc, "Variable \"$%s\" got invalid value; %s.", definition.name, err.Error())
+			}
+			v.given = true
+		} else if _, ok := t.(*NonNull); ok && !v.given {
+			return locatedError(definition.loc, "Variable \"$%s\" of required type \"%s\" was not provided.", definition.name, t)
+		}
+	}
+	return nil
+}
+
+// inputType returns the input type a variable's type refers to.
+func (p *planner) inputType(ref *typeRef, loc Location) (Type, error) {
+	var t Type
+	if ref.elem != nil {
+		elem, err := p.inputType(ref.elem, loc)
+		if err != nil {
+			return nil, err
+		}
+		t = &List{OfType: elem}
+	} else {
+		t = p.schema.types[ref.name]
+		if t == nil {
+			return nil, locatedError(loc, "Unknown type \"%s\".", ref.name)
+		}
+		if !input(t) {
+			return nil, locatedError(loc, "Variable cannot be of non-input type \"%s\".", ref.name)
+		}
+	}
+
+	if ref.nonNull {
+		t = &NonNull{OfType: t}
+	}
+	return t, nil
+}
+
+// plan plans the fields selected on an object at given depth, and returns them
+// with their complexity.
+func (p *planner) plan(object *Object, selections []selection, depth int) ([]*plannedField, int, error) {
+	var collected []*collectedField
+	if err := p.collect(object, selections, make(map[string]bool), &collected); err != nil {
+		return nil, 0, err
+	}
+
+	planned := make([]*plannedField, len(collected))
+	complexity := 0
+	for i, c := range collected {
+		if p.limits.MaxDepth > 0 && depth > p.limits.MaxDepth {
+			return nil, 0, locatedError(c.locs[0], "Query exceeds the maximum depth of %d.", p.limits.MaxDepth)
+		}
+
+		planned[i] = &plannedField{
+			key:   c.key,
+			field: c.field,
+			args:  c.args,
+			locs:  c.locs,
+		}
+
+		t := Type(String) // Type of __typename.
+		if c.field != nil {
+			t = c.field.Type
+		}
+		childObject, ok := named(t).(*Object)
+		if !ok {
+			if len(c.selections) != 0 {
+				return nil, 0, locatedError(c.locs[0], "Field %q must not have a selection since type \"%s\" has no subfields.", c.name, t)
+			}
+			complexity = add(complexity, 1)
+			continue
+		}
+		if len(c.selections) == 0 {
+			return nil, 0, locatedError(c.locs[0], "Field %q of type \"%s\" must have a selection of subfields.", c.name, t)
+		}
+
+		fields, childComplexity, err := p.plan(childObject, c.selections, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		planned[i].fields = fields
+
+		size := 1
+		if isList(t) && c.field.Size != nil {
+			if size = c.field.Size(c.args); size < 0 {
+				size = 0
+			}
+		}
+		complexity = add(complexity, add(1, multiply(size, childComplexity)))
+	}
+	return planned, complexity, nil
+}
+
+// collect collects the fields selected on an object, evaluating directives and
+// spreading fragments. spreads holds the names of fragments being spread.
+func (p *planner) collect(object *Object, selections []selection, spreads map[string]bool, collected *[]*collectedField) error {
+	for _, s := range selections {
+		switch s := s.(type) {
+		case *field:
+			if p.visited++; p.visited > maxSelections {
+				return locatedError(s.loc, "Query has more than %d selections.", maxSelections)
+			}
+			if include, err := p.include(s.directives); err != nil {
+				return err
+			} else if !include {
+				continue
+			}
+			if err := p.collectField(object, s, collected); err != nil {
+				return err
+			}
+		case *fragmentSpread:
+			if include, err := p.include(s.directives); err != nil {
+				return err
+			} else if !include {
+				continue
+			}
+
+			f := p.doc.fragments[s.name]
+			if f == nil {
+				return locatedError(s.loc, "Unknown fragment %q.", s.name)
+			}
+			if spreads[s.name] {
+				return locatedError(s.loc, "Cannot spread fragment %q within itself.", s.name)
+			}
+			if err := p.typeCondition(object, f.typeCondition, f.loc); err != nil {
+				return err
+			}
+
+			spreads[s.name] = true
+			if err := p.collect(object, f.selections, spreads, collected); err != nil {
+				return err
+			}
+			delete(spreads, s.name)
+		case *inlineFragment:
+			if include, err := p.include(s.directives); err != nil {
+				return err
+			} else if !include {
+				continue
+			}
+			if s.typeCondition != "" {
+				if err := p.typeCondition(object, s.typeCondition, s.loc); err != nil {
+					return err
+				}
+			}
+			if err := p.collect(object, s.selections, spreads, collected); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// collectField adds a selected field to collected fields, merging it with a
+// collected field that has the same response key.
+func (p *planner) collectField(object *Object, f *field, collected *[]*collectedField) error {
+	c := &collectedField{
+		key:        f.key(),
+		name:       f.name,
+		args:       make(map[string]interface{}),
+		locs:       []Location{f.loc},
+		selections: f.selections,
+	}
+	if f.name == "__typename" {
+		if len(f.arguments) != 0 {
+			return locatedError(f.arguments[0].loc, "Unknown argument %q on field \"__typename\".", f.arguments[0].name)
+		}
+	} else {
+		if c.field = object.field(f.name); c.field == nil {
+			return locatedError(f.loc, "Cannot query field %q on type \"%s\".", f.name, object.Name)
+		}
+
+		var err error
+		owner := fmt.Sprintf("field \"%s.%s\"", object.Name, f.name)
+		if c.args, err = p.arguments(owner, c.field.Args, f.arguments); err != nil {
+			return err
+		}
+	}
+
+	for _, existing := range *collected {
+		if existing.key != c.key {
+			continue
+		}
+		if existing.name != c.name || !reflect.DeepEqual(existing.args, c.args) {
+			return &Error{
+				Message:   fmt.Sprintf("Fields %q conflict because they have differing names or arguments.", c.key),
+				Locations: append(existing.locs[:len(existing.locs):len(existing.locs)], c.locs...),
+			}
+		}
+		existing.locs = append(existing.locs, c.locs...)
+		existing.selections = append(existing.selections[:len(existing.selections):len(existing.selections)], c.selections...)
+		return nil
+	}
+	*collected = append(*collected, c)
+	return nil
+}
+
+// arguments coerces the arguments given to owner, a field or a directive,
+// whose arguments are defined by definitions.
+func (p *planner) arguments(owner string, definitions []*Argument, given []*argument) (map[string]interface{}, error) {
+	byName := make(map[string]*argument)
+	for _, a := range given {
+		if byName[a.name] != nil {
+			return nil, locatedError(a.loc, "There can be only one argument named %q.", a.name)
+		}
+		byName[a.name] = a
+
+		defined := false
+		for _, definition := range definitions {
+			defined = defined || definition.Name == a.name
+		}
+		if !defined {
+			return nil, locatedError(a.loc, "Unknown argument %q on %s.", a.name, owner)
+		}
+	}
+
+	args := make(map[string]interface{})
+	for _, definition := range definitions {
+		a, ok := byName[definition.Name]
+		if ok {
+			value, given, err := coerceLiteral(a.value, definition.Type, p.variables)
+			if err != nil {
+				return nil, err
+			}
+			_, required := definition.Type.(*NonNull)
+			if given && value == nil && required {
+				return nil, locatedError(a.loc, "Argument %q of %s can't be null.", a.name, owner)
+			}
+			if given {
+				args[definition.Name] = value
+				continue
+			}
+		}
+
+		if definition.Default != nil {
+			args[definition.Name] = definition.Default
+		} else if _, required := definition.Type.(*NonNull); required {
+			loc := Location{}
+			if a != nil {
+				loc = a.loc
+			}
+			return nil, locatedError(loc, "Argument %q of %s of type \"%s\" is required, but it was not provided.", definition.Name, owner, definition.Type)
+		}
+	}
+	return args, nil
+}
+
+// conditional are the arguments of @skip and @include.
+var conditional = []*Argument{{Name: "if", Type: &NonNull{OfType: Boolean}}}
+
+// include evaluates the @skip and @include directives of a selection, and
+// returns false if it's skipped.
+func (p *planner) include(directives []*directive) (bool, error) {
+	include := true
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			return false, locatedError(d.loc, "Unknown directive \"@%s\".", d.name)
+		}
+
+		args, err := p.arguments(fmt.Sprintf("directive \"@%s\"", d.name), conditional, d.arguments)
+		if err != nil {
+			return false, err
+		}
+		if args["if"] == (d.name == "skip") {
+			include = false
+		}
+	}
+	return include, nil
+}
+
+// typeCondition returns an error unless a fragment with given type condition
+// can be spread on an object.
+func (p *planner) typeCondition(object *Object, condition string, loc Location) error {
+	t := p.schema.types[condition]
+	if t == nil {
+		return locatedError(loc, "Unknown type \"%s\".", condition)
+	}
+	if t != Type(object) {
+		return locatedError(loc, "Fragment cannot be spread here as objects of type \"%s\" can never be of type \"%s\".", object.Name, condition)
+	}
+	return nil
+}
+
+// add returns a+b, at most maxCost.
+func add(a, b int) int {
+	if a > maxCost-b {
+		return maxCost
+	}
+	return a + b
+}
+
+// multiply returns a*b, at most maxCost.
+func multiply(a, b int) int {
+	if a != 0 && b > maxCost/a {
+		return maxCost
+	}
+	return a * b
+}
+
+// executor executes planned fields.
+type executor struct {
+	ctx    context.Context
+	errors []*Error
+}
+
+// object resolves the planned fields of an object, and returns their values.
+// Returns true if the object is null because one of its non-null fields is.
+func (e *executor) object(object *Object, source interface{}, fields []*plannedField, path []interface{}) (orderedObject, bool) {
+	result := make(orderedObject, len(fields))
+	for i, f := range fields {
+		fieldPath := extend(path, f.key)
+		if f.field == nil {
+			result[i] = keyValue{key: f.key, value: object.Name}
+			continue
+		}
+
+		value, failed := e.field(source, f, fieldPath)
+		if failed {
+			return nil, true
+		}
+		result[i] = keyValue{key: f.key, value: value}
+	}
+	return result, false
+}
+
+// field resolves a planned field on source. Returns true if the field is null
+// and non-null, in which case its parent must be null.
+func (e *executor) field(source interface{}, f *plannedField, path []interface{}) (interface{}, bool) {
+	if err := e.ctx.Err(); err != nil {
+		e.report(f, path, errorMessage(err))
+		return nil, nonNull(f.field.Type)
+	}
+
+	var value interface{}
+	var err error
+	if f.field.Resolve != nil {
+		value, err = f.field.Resolve(e.ctx, source, f.args)
+	} else {
+		value, err = resolveField(source, f.field.Name)
+	}
+	if err != nil {
+		e.report(f, path, errorMessage(err))
+		return nil, nonNull(f.field.Type)
+	}
+
+	completed, failed := e.complete(f.field.Type, f, value, path)
+	if failed {
+		return nil, nonNull(f.field.Type)
+	}
+	return completed, false
+}
+
+// complete completes a resolved value of type t. Returns true if the value is
+// null because of an error that was reported, which makes the closest
+// nullable field or list element null.
+func (e *executor) complete(t Type, f *plannedField, value interface{}, path []interface{}) (interface{}, bool) {
+	if nonNullType, ok := t.(*NonNull); ok {
+		completed, failed := e.complete(nonNullType.OfType, f, value, path)
+		if failed {
+			return nil, true
+		}
+		if completed == nil {
+			e.report(f, path, fmt.Sprintf("Cannot return null for non-nullable field %q.", f.field.Name))
+			return nil, true
+		}
+		return completed, false
+	}
+
+	reflected := reflect.ValueOf(value)
+	if value == nil || (reflected.Kind() == reflect.Ptr || reflected.Kind() == reflect.Slice) && reflected.IsNil() {
+		return nil, false
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if reflected.Kind() != reflect.Slice && reflected.Kind() != reflect.Array {
+			e.report(f, path, fmt.Sprintf("Expected a list for field %q, got: %T.", f.field.Name, value))
+			return nil, true
+		}
+
+		list := make([]interface{}, reflected.Len())
+		for i := range list {
+			elem, failed := e.complete(t.OfType, f, reflected.Index(i).Interface(), extend(path, i))
+			if failed {
+				if nonNull(t.OfType) {
+					return nil, true
+				}
+				elem = nil
+			}
+			list[i] = elem
+		}
+		return list, false
+	case *Object:
+		return e.object(t, value, f.fields, path)
+	case *Scalar:
+		for reflected.Kind() == reflect.Ptr {
+			if reflected = reflected.Elem(); reflected.Kind() == reflect.Ptr && reflected.IsNil() {
+				return nil, false
+			}
+		}
+		serialized, ok := t.serialize(reflected.Interface())
+		if !ok {
+			e.report(f, path, fmt.Sprintf("%s cannot represent value of field %q: %v.", t.Name, f.field.Name, value))
+			return nil, true
+		}
+		return serialized, false
+	default:
+		e.report(f, path, fmt.Sprintf("Unsupported type %q of field %q.", t, f.field.Name))
+		return nil, true
+	}
+}
+
+// report reports an error of a field.
+func (e *executor) report(f *plannedField, path []interface{}, message string) {
+	e.errors = append(e.errors, &Error{
+		Message:   message,
+		Locations: f.locs[:1],
+		Path:      path,
+	})
+}
+
+// errorMessage returns the message of an error returned while resolving a
+// field as it's written to the response, capitalized and ending with a period.
+func errorMessage(err error) string {
+	message := err.Error()
+	if message == "" {
+		return message
+	}
+
+	first, size := utf8.DecodeRuneInString(message)
+	message = string(unicode.ToUpper(first)) + message[size:]
+	if !strings.HasSuffix(message, ".") {
+		message += "."
+	}
+	return message
+}
+
+// nonNull returns true if t is a non-null type.
+func nonNull(t Type) bool {
+	_, ok := t.(*NonNull)
+	return ok
+}
+
+// extend returns a copy of path with an added response key or list index.
+func extend(path []interface{}, key interface{}) []interface{} {
+	extended := make([]interface{}, len(path), len(path)+1)
+	copy(extended, path)
+	return append(extended, key)
+}
+
+// orderedObject is a resolved object, whose fields are encoded in the order
+// they were selected.
+type orderedObject []keyValue
+
+// keyValue is a field of a resolved object.
+type keyValue struct {
+	key   string
+	value interface{}
+}
+
+// MarshalJSON encodes the object's fields in order.
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, kv := range o {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(kv.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(kv.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,411 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// testBook is a book returned by the test schema.
+type testBook struct {
+	ID     int
+	Title  string
+	Series *string
+}
+
+var (
+	testSeries = "Hitchhiker's Guide"
+	testBooks  = []*testBook{
+		{ID: 1, Title: "Don't Panic", Series: &testSeries},
+		{ID: 2, Title: "Life, the Universe and Everything", Series: &testSeries},
+		{ID: 3, Title: "Dirk Gently"},
+	}
+)
+
+// testSchema returns a schema of test books.
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	book := &Object{Name: "Book", Description: "A book."}
+	filter := &InputObject{
+		Name: "Filter",
+		Fields: []*Argument{
+			{Name: "titleHas", Type: &NonNull{OfType: String}},
+			{Name: "ids", Type: &List{OfType: &NonNull{OfType: Int}}},
+			{Name: "limit", Type: Int, Default: 10},
+		},
+	}
+	first := []*Argument{{Name: "first", Description: "Number of books.", Type: Int, Default: 2}}
+	size := func(args map[string]interface{}) int {
+		return args["first"].(int)
+	}
+	list := func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+		switch first := args["first"].(int); {
+		case first < 0:
+			return nil, errors.New("invalid first")
+		case first < len(testBooks):
+			return testBooks[:first], nil
+		}
+		return testBooks, nil
+	}
+
+	book.Fields = []*Field{
+		{Name: "id", Type: &NonNull{OfType: ID}},
+		{Name: "title", Type: &NonNull{OfType: String}},
+		{Name: "series", Type: String},
+		{Name: "related", Type: &NonNull{OfType: &List{OfType: &NonNull{OfType: book}}}, Args: first, Resolve: list, Size: size},
+		{
+			Name: "sequel",
+			Type: book,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				id := source.(*testBook).ID
+				if id >= len(testBooks) {
+					return nil, nil
+				}
+				return testBooks[id], nil
+			},
+		},
+		{
+			Name: "rating",
+			Type: &NonNull{OfType: Float},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+				if source.(*testBook).ID == 2 {
+					return nil, errors.New("no ratings")
+				}
+				return 4.5, nil
+			},
+		},
+	}
+	query := &Object{
+		Name: "Query",
+		Fields: []*Field{
+			{
+				Name:        "book",
+				Description: "Returns the book with given ID.",
+				Type:        book,
+				Args:        []*Argument{{Name: "id", Type: &NonNull{OfType: Int}}},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					for _, b := range testBooks {
+						if b.ID == args["id"].(int) {
+							return b, nil
+						}
+					}
+					return nil, nil
+				},
+			},
+			{Name: "books", Type: &NonNull{OfType: &List{OfType: &NonNull{OfType: book}}}, Args: first, Resolve: list, Size: size},
+			{
+				Name: "search",
+				Type: &List{OfType: book},
+				Args: []*Argument{{Name: "by", Type: &NonNull{OfType: filter}}},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					by := args["by"].(map[string]interface{})
+					var found []*testBook
+					for _, b := range testBooks {
+						if strings.Contains(b.Title, by["titleHas"].(string)) && len(found) < by["limit"].(int) {
+							found = append(found, b)
+						}
+					}
+					return found, nil
+				},
+			},
+			{
+				Name: "echo",
+				Type: String,
+				Args: []*Argument{{Name: "value", Type: &List{OfType: Float}}, {Name: "id", Type: ID}},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					encoded, err := json.Marshal(args)
+					return string(encoded), err
+				},
+			},
+		},
+	}
+
+	schema, err := NewSchema(query)
+	if err != nil {
+		t.Fatalf("failed to create schema: %s", err.Error())
+	}
+	return schema
+}
+
+// TestExecute tests executing valid queries.
+func TestExecute(t *testing.T) {
+	schema := testSchema(t)
+	for _, test := range []struct {
+		query     string
+		operation string
+		variables map[string]interface{}
+		expected  string
+	}{
+		{
+			query:    `{ book(id: 1) { id title series __typename } }`,
+			expected: `{"data":{"book":{"id":"1","title":"Don't Panic","series":"Hitchhiker's Guide","__typename":"Book"}}}`,
+		},
+		{
+			query:    `{ book(id: 3) { series sequel { id } } missing: book(id: 4) { id } }`,
+			expected: `{"data":{"book":{"series":null,"sequel":null},"missing":null}}`,
+		},
+		{
+			query:    `{ books { id } more: books(first: 5) { id } }`,
+			expected: `{"data":{"books":[{"id":"1"},{"id":"2"}],"more":[{"id":"1"},{"id":"2"},{"id":"3"}]}}`,
+		},
+		{
+			query:     `query A { a: book(id: 1) { id } } query B($id: Int!, $first: Int) { b: book(id: $id) { related(first: $first) { id } } }`,
+			operation: "B",
+			variables: map[string]interface{}{"id": float64(2), "first": float64(1)},
+			expected:  `{"data":{"b":{"related":[{"id":"1"}]}}}`,
+		},
+		{
+			query:     `query($first: Int) { books(first: $first) { id } }`,
+			variables: map[string]interface{}{},
+			expected:  `{"data":{"books":[{"id":"1"},{"id":"2"}]}}`,
+		},
+		{
+			query:    `query($id: Int = 3) { book(id: $id) { ...Title ... on Book { id } ... { title } } } fragment Title on Book { title }`,
+			expected: `{"data":{"book":{"title":"Dirk Gently","id":"3"}}}`,
+		},
+		{
+			query:     `query($details: Boolean!) { book(id: 1) { id title @include(if: $details) series @skip(if: $details) } }`,
+			variables: map[string]interface{}{"details": true},
+			expected:  `{"data":{"book":{"id":"1","title":"Don't Panic"}}}`,
+		},
+		{
+			query:    `{ search(by: {titleHas: "an"}) { id } limited: search(by: {titleHas: "an", limit: 1, ids: 3}) { id } }`,
+			expected: `{"data":{"search":[{"id":"1"},{"id":"2"}],"limited":[{"id":"1"}]}}`,
+		},
+		{
+			query:     `query($by: Filter!) { search(by: $by) { id } }`,
+			variables: map[string]interface{}{"by": map[string]interface{}{"titleHas": "Dirk", "ids": []interface{}{float64(1)}}},
+			expected:  `{"data":{"search":[{"id":"3"}]}}`,
+		},
+		{
+			query:    `{ echo(value: 1, id: 7) }`,
+			expected: `{"data":{"echo":"{\"id\":\"7\",\"value\":[1]}"}}`,
+		},
+		{
+			query:    `{ book(id: 1) { id } book(id: 1) { title } }`,
+			expected: `{"data":{"book":{"id":"1","title":"Don't Panic"}}}`,
+		},
+	} {
+		response := schema.Execute(context.Background(), &Request{
+			Query:         test.query,
+			OperationName: test.operation,
+			Variables:     test.variables,
+		}, nil)
+		if encoded := encode(t, response); encoded != test.expected {
+			t.Errorf("%s: expected response: %s, got: %s", test.query, test.expected, encoded)
+		}
+	}
+}
+
+// TestFieldErrors tests that errors of fields are reported along with the data
+// of fields that succeeded, and that non-null fields make their parents null.
+func TestFieldErrors(t *testing.T) {
+	schema := testSchema(t)
+	for query, expected := range map[string]string{
+		`{ book(id: 2) { id rating } other: book(id: 1) { rating } }`: `{"data":{"book":null,"other":{"rating":4.5}},"errors":[{"message":"No ratings.","locations":[{"line":1,"column":20}],"path":["book","rating"]}]}`,
+		`{ book(id: 1) { related(first: 3) { id rating } } }`:         `{"data":{"book":null},"errors":[{"message":"No ratings.","locations":[{"line":1,"column":40}],"path":["book","related",1,"rating"]}]}`,
+		`{ books(first: 3) { rating } }`:                              `{"data":null,"errors":[{"message":"No ratings.","locations":[{"line":1,"column":21}],"path":["books",1,"rating"]}]}`,
+		`{ search(by: {titleHas: "i"}) { id rating } }`:               `{"data":{"search":[{"id":"1","rating":4.5},null,{"id":"3","rating":4.5}]},"errors":[{"message":"No ratings.","locations":[{"line":1,"column":36}],"path":["search",1,"rating"]}]}`,
+	} {
+		response := schema.Execute(context.Background(), &Request{Query: query}, nil)
+		if encoded := encode(t, response); encoded != expected {
+			t.Errorf("%s: expected response: %s, got: %s", query, expected, encoded)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response := schema.Execute(ctx, &Request{Query: `{ book(id: 1) { id } }`}, nil)
+	if len(response.Errors) != 1 || string(response.Data) != `{"book":null}` {
+		t.Errorf("expected a canceled query to fail, got: %s", encode(t, response))
+	}
+}
+
+// TestRequestErrors tests that invalid requests fail before execution.
+func TestRequestErrors(t *testing.T) {
+	schema := testSchema(t)
+	for _, test := range []struct {
+		query     string
+		operation string
+		variables map[string]interface{}
+		message   string
+	}{
+		{query: `{ book(id: 1) { id `, message: "Syntax error: unexpected end of document."},
+		{query: `{ book(id: 1) { isbn } }`, message: `Cannot query field "isbn" on type "Book".`},
+		{query: `{ book(id: 1) }`, message: `Field "book" of type "Book" must have a selection of subfields.`},
+		{query: `{ book(id: 1) { id { value } } }`, message: `Field "id" must not have a selection since type "ID!" has no subfields.`},
+		{query: `{ book { id } }`, message: `Argument "id" of field "Query.book" of type "Int!" is required, but it was not provided.`},
+		{query: `{ book(id: 1, isbn: "") { id } }`, message: `Unknown argument "isbn" on field "Query.book".`},
+		{query: `{ book(id: 1, id: 2) { id } }`, message: `There can be only one argument named "id".`},
+		{query: `{ book(id: "1") { id } }`, message: `Expected value of type "Int", found "1".`},
+		{query: `{ book(id: 1.5) { id } }`, message: `Expected value of type "Int", found 1.5.`},
+		{query: `{ book(id: 3000000000) { id } }`, message: `Expected value of type "Int", found 3000000000.`},
+		{query: `{ book(id: null) { id } }`, message: `Expected value of type "Int!", found null.`},
+		{query: `{ echo(value: ONE) }`, message: `Expected value of type "Float", found ONE.`},
+		{query: `{ search(by: {}) { id } }`, message: `Field "Filter.titleHas" of required type "String!" was not provided.`},
+		{query: `{ search(by: {titleHas: "a", author: "b"}) { id } }`, message: `Field "author" is not defined by type "Filter".`},
+		{query: `{ search(by: "a") { id } }`, message: `Expected value of type "Filter", found "a".`},
+		{query: `{ book(id: $id) { id } }`, message: `Variable "$id" is not defined.`},
+		{query: `query($id: Int) { book(id: $id) { id } }`, message: `Variable "$id" of type "Int" used in position expecting type "Int!".`},
+		{query: `query($id: Int!) { book(id: $id) { id } }`, message: `Variable "$id" of required type "Int!" was not provided.`},
+		{query: `query($id: Int!) { book(id: $id) { id } }`, variables: map[string]interface{}{"id": "1"}, message: `Variable "$id" got invalid value; expected value of type "Int", found "1".`},
+		{query: `query($id: Int!) { book(id: $id) { id } }`, variables: map[string]interface{}{"id": 1.5}, message: `Variable "$id" got invalid value; expected value of type "Int", found 1.5.`},
+		{query: `query($by: Filter!) { search(by: $by) { id } }`, variables: map[string]interface{}{"by": map[string]interface{}{"titleHas": "a", "ids": []interface{}{nil}}}, message: `Variable "$by" got invalid value; expected non-null value of type "Int!" at index 0 at field "ids".`},
+		{query: `query($book: Book) { books { id } }`, message: `Variable cannot be of non-input type "Book".`},
+		{query: `query($id: Int, $id: Int) { books { id } }`, message: `There can be only one variable named "$id".`},
+		{query: `{ books { id } } { books { title } }`, message: "This anonymous operation must be the only defined operation."},
+		{query: `query A { books { id } } query B { books { title } }`, message: "Must provide operation name if query contains multiple operations."},
+		{query: `query A { books { id } }`, operation: "B", message: `Unknown operation named "B".`},
+		{query: `mutation { books { id } }`, message: "Schema doesn't support mutations."},
+		{query: `{ books { id @cached } }`, message: `Unknown directive "@cached".`},
+		{query: `{ books { id @skip } }`, message: `Argument "if" of directive "@skip" of type "Boolean!" is required, but it was not provided.`},
+		{query: `{ books { ...Missing } }`, message: `Unknown fragment "Missing".`},
+		{query: `{ books { ...A } } fragment A on Book { ...B } fragment B on Book { ...A }`, message: `Cannot spread fragment "A" within itself.`},
+		{query: `{ books { ...A } } fragment A on Query { books { id } }`, message: `Fragment cannot be spread here as objects of type "Book" can never be of type "Query".`},
+		{query: `{ books { ... on Author { id } } }`, message: `Unknown type "Author".`},
+		{query: `{ books { id: title id } }`, message: `Fields "id" conflict because they have differing names or arguments.`},
+		{query: `{ books(first: 1) { id } books { id } }`, message: `Fields "books" conflict because they have differing names or arguments.`},
+		{query: `{ __typename(a: 1) }`, message: `Unknown argument "a" on field "__typename".`},
+	} {
+		response := schema.Execute(context.Background(), &Request{
+			Query:         test.query,
+			OperationName: test.operation,
+			Variables:     test.variables,
+		}, nil)
+		if response.Data != nil || len(response.Errors) != 1 || response.Errors[0].Message != test.message {
+			t.Errorf("%s: expected request to fail with: %s, got: %s", test.query, test.message, encode(t, response))
+		}
+	}
+}
+
+// TestLimits tests rejecting queries that are too deep, or too complex.
+func TestLimits(t *testing.T) {
+	schema := testSchema(t)
+	for _, test := range []struct {
+		query   string
+		limits  *Limits
+		message string // Empty if the query is executed.
+	}{
+		{query: `{ book(id: 1) { sequel { sequel { id } } } }`, limits: &Limits{MaxDepth: 4}},
+		{query: `{ book(id: 1) { sequel { sequel { sequel { id } } } } }`, limits: &Limits{MaxDepth: 4}, message: "Query exceeds the maximum depth of 4."},
+		{query: `{ books(first: 3) { related(first: 3) { id } } }`, limits: &Limits{MaxComplexity: 13}}, // 1 + 3 * (1 + 3 * 1)
+		{query: `{ books(first: 3) { related(first: 3) { id } } }`, limits: &Limits{MaxComplexity: 12}, message: "Query has a complexity of 13, which exceeds the maximum complexity of 12."},
+		{query: `query($n: Int) { books(first: $n) { id title } }`, limits: &Limits{MaxComplexity: 5}}, // Default of 2.
+		{query: `{ books(first: 1000000) { related(first: 1000000) { related(first: 1000000) { id } } } }`, limits: &Limits{MaxComplexity: 1000}, message: "Query has a complexity of 2147483647, which exceeds the maximum complexity of 1000."},
+		{query: `{ books { ...A } } fragment A on Book { id ...B ...B } fragment B on Book { id ...C ...C } fragment C on Book { id ...D ...D } fragment D on Book { id ...E ...E } fragment E on Book { id ...F ...F } fragment F on Book { id ...G ...G } fragment G on Book { id ...H ...H } fragment H on Book { id ...I ...I } fragment I on Book { id ...J ...J } fragment J on Book { id ...K ...K } fragment K on Book { id ...L ...L } fragment L on Book { id ...M ...M } fragment M on Book { id ...N ...N } fragment N on Book { id }`, message: "Query has more than 10000 selections."},
+	} {
+		response := schema.Execute(context.Background(), &Request{Query: test.query}, test.limits)
+		if test.message == "" && len(response.Errors) != 0 {
+			t.Errorf("%s: expected query to be executed, got: %s", test.query, encode(t, response))
+		}
+		if test.message != "" && (response.Data != nil || len(response.Errors) != 1 || response.Errors[0].Message != test.message) {
+			t.Errorf("%s: expected query to fail with: %s, got: %s", test.query, test.message, encode(t, response))
+		}
+	}
+}
+
+// TestExecuteRandom tests that executing randomly mutated queries responds with
+// data or errors, and doesn't panic.
+func TestExecuteRandom(t *testing.T) {
+	schema := testSchema(t)
+	limits := &Limits{MaxDepth: 8, MaxComplexity: 1000}
+	variables := map[string]interface{}{"id": float64(2), "first": "3", "details": true}
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		query := mutate(random, randomSeeds[random.Intn(len(randomSeeds))], 1+random.Intn(2))
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("%q: executing panicked: %v", query, r)
+				}
+			}()
+
+			response := schema.Execute(context.Background(), &Request{Query: query, Variables: variables}, limits)
+			if response.Data == nil && len(response.Errors) == 0 {
+				t.Errorf("%q: expected data or errors, got neither", query)
+			}
+			for _, err := range response.Errors {
+				if err.Message == "" {
+					t.Errorf("%q: expected errors to have messages, got: %s", query, encode(t, response))
+				}
+			}
+			encode(t, response)
+		}()
+	}
+}
+
+// TestSchemaString tests printing schemas.
+func TestSchemaString(t *testing.T) {
+	expected := `schema {
+  query: Query
+}
+
+type Query {
+  "Returns the book with given ID."
+  book(id: Int!): Book
+  books(
+    "Number of books."
+    first: Int = 2
+  ): [Book!]!
+  search(by: Filter!): [Book]
+  echo(value: [Float], id: ID): String
+}
+
+"A book."
+type Book {
+  id: ID!
+  title: String!
+  series: String
+  related(
+    "Number of books."
+    first: Int = 2
+  ): [Book!]!
+  sequel: Book
+  rating: Float!
+}
+
+input Filter {
+  titleHas: String!
+  ids: [Int!]
+  limit: Int = 10
+}
+`
+	if s := testSchema(t).String(); s != expected {
+		t.Errorf("expected schema:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+// TestNewSchema tests rejecting invalid schemas.
+func TestNewSchema(t *testing.T) {
+	for _, query := range []*Object{
+		{Name: "Query"},
+		{Name: "Query", Fields: []*Field{{Name: "a", Type: String}, {Name: "a", Type: Int}}},
+		{Name: "Query", Fields: []*Field{{Name: "__a", Type: String}}},
+		{Name: "Query", Fields: []*Field{{Name: "a"}}},
+		{Name: "Query", Fields: []*Field{{Name: "a", Type: &InputObject{Name: "A", Fields: []*Argument{{Name: "a", Type: Int}}}}}},
+		{Name: "Query", Fields: []*Field{{Name: "a", Type: String, Args: []*Argument{{Name: "a", Type: &Object{Name: "A"}}}}}},
+		{Name: "Query", Fields: []*Field{{Name: "a", Type: &Object{Name: "String", Fields: []*Field{{Name: "a", Type: Int}}}}}},
+		{Name: "Query", Fields: []*Field{
+			{Name: "a", Type: &Object{Name: "A", Fields: []*Field{{Name: "a", Type: Int}}}},
+			{Name: "b", Type: &Object{Name: "A", Fields: []*Field{{Name: "a", Type: Int}}}},
+		}},
+	} {
+		if _, err := NewSchema(query); err == nil {
+			t.Errorf("%+v: expected schema to be invalid", *query)
+		}
+	}
+}
+
+// encode returns a response encoded as JSON.
+func encode(t *testing.T, response *Response) string {
+	t.Helper()
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("failed to encode response: %s", err.Error())
+	}
+	return string(encoded)
+}
@@ -0,0 +1,295 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of tokens.
+const (
+	eof = iota
+	punctuator
+	name
+	intValue
+	floatValue
+	stringValue
+)
+
+// bom is the byte order mark, which is ignored in documents.
+const bom = "\uFEFF"
+
+// token is a lexical token of a GraphQL document.
+type token struct {
+	kind  int
+	value string   // Text of the token, or value of a string.
+	loc   Location // Location of the token's first character.
+}
+
+// lexer splits a GraphQL document into tokens.
+type lexer struct {
+	source string
+	pos    int // Offset of the next character.
+	line   int // Line of the next character, starting at 1.
+	start  int // Offset of the first character of the current line.
+}
+
+// newLexer returns a lexer of source.
+func newLexer(source string) *lexer {
+	return &lexer{
+		source: source,
+		line:   1,
+	}
+}
+
+// next returns the next token, or an error if the document is invalid.
+func (l *lexer) next() (*token, error) {
+	l.skipIgnored()
+	t := &token{loc: l.location()}
+	if l.pos >= len(l.source) {
+		t.kind = eof
+		return t, nil
+	}
+
+	c := l.source[l.pos]
+	switch {
+	case strings.IndexByte("!$&()=:@[]{}|", c) >= 0:
+		l.pos++
+		t.kind, t.value = punctuator, string(c)
+	case c == '.':
+		if !strings.HasPrefix(l.source[l.pos:], "...") {
+			return nil, l.errorf(t.loc, "unexpected %q", c)
+		}
+		l.pos += 3
+		t.kind, t.value = punctuator, "..."
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.source) && (l.source[l.pos] == '_' || isLetter(l.source[l.pos]) || isDigit(l.source[l.pos])) {
+			l.pos++
+		}
+		t.kind, t.value = name, l.source[start:l.pos]
+	case c == '-' || isDigit(c):
+		return l.number(t)
+	case c == '"':
+		if strings.HasPrefix(l.source[l.pos:], `"""`) {
+			return l.blockString(t)
+		}
+		return l.string(t)
+	default:
+		r, _ := utf8.DecodeRuneInString(l.source[l.pos:])
+		return nil, l.errorf(t.loc, "unexpected %q", r)
+	}
+	return t, nil
+}
+
+// skipIgnored skips white space, line terminators, commas and comments.
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.source) {
+		switch c := l.source[l.pos]; c {
+		case ' ', '\t', ',':
+			l.pos++
+		case '\n', '\r':
+			l.newLine()
+		case '#':
+			for l.pos < len(l.source) && l.source[l.pos] != '\n' && l.source[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			if strings.HasPrefix(l.source[l.pos:], bom) { // Allowed at any position.
+				l.pos += len(bom)
+				continue
+			}
+			return
+		}
+	}
+}
+
+// newLine skips a line terminator, i.e. "\n", "\r" or "\r\n".
+func (l *lexer) newLine() {
+	if strings.HasPrefix(l.source[l.pos:], "\r\n") {
+		l.pos++
+	}
+	l.pos++
+	l.line++
+	l.start = l.pos
+}
+
+// number reads an int or a float.
+func (l *lexer) number(t *token) (*token, error) {
+	start := l.pos
+	if l.source[l.pos] == '-' {
+		l.pos++
+	}
+
+	if l.pos < len(l.source) && l.source[l.pos] == '0' {
+		l.pos++
+		if l.pos < len(l.source) && isDigit(l.source[l.pos]) {
+			return nil, l.errorf(t.loc, "invalid number, unexpected digit after 0")
+		}
+	} else if !l.digits() {
+		return nil, l.errorf(t.loc, "invalid number %q", l.source[start:l.pos])
+	}
+
+	t.kind = intValue
+	if l.pos < len(l.source) && l.source[l.pos] == '.' {
+		l.pos++
+		if !l.digits() {
+			return nil, l.errorf(t.loc, "invalid number %q", l.source[start:l.pos])
+		}
+		t.kind = floatValue
+	}
+	if l.pos < len(l.source) && (l.source[l.pos] == 'e' || l.source[l.pos] == 'E') {
+		l.pos++
+		if l.pos < len(l.source) && (l.source[l.pos] == '+' || l.source[l.pos] == '-') {
+			l.pos++
+		}
+		if !l.digits() {
+			return nil, l.errorf(t.loc, "invalid number %q", l.source[start:l.pos])
+		}
+		t.kind = floatValue
+	}
+
+	if l.pos < len(l.source) && (l.source[l.pos] == '.' || l.source[l.pos] == '_' || isLetter(l.source[l.pos])) {
+		return nil, l.errorf(t.loc, "invalid number, unexpected %q", l.source[l.pos])
+	}
+	t.value = l.source[start:l.pos]
+	return t, nil
+}
+
+// digits reads a sequence of digits, and returns false if there are none.
+func (l *lexer) digits() bool {
+	start := l.pos
+	for l.pos < len(l.source) && isDigit(l.source[l.pos]) {
+		l.pos++
+	}
+	return l.pos > start
+}
+
+// string reads a string in double quotes, replacing its escape sequences.
+func (l *lexer) string(t *token) (*token, error) {
+	l.pos++
+	value := new(strings.Builder)
+	for {
+		if l.pos >= len(l.source) || l.source[l.pos] == '\n' || l.source[l.pos] == '\r' {
+			return nil, l.errorf(t.loc, "unterminated string")
+		}
+
+		c := l.source[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			t.kind, t.value = stringValue, value.String()
+			return t, nil
+		case c == '\\':
+			if l.pos+1 >= len(l.source) {
+				return nil, l.errorf(t.loc, "unterminated string")
+			}
+
+			escaped := l.source[l.pos+1]
+			if i := strings.IndexByte(`"\/bfnrt`, escaped); i >= 0 {
+				value.WriteByte("\"\\/\b\f\n\r\t"[i])
+				l.pos += 2
+				continue
+			}
+			if escaped != 'u' || l.pos+6 > len(l.source) {
+				return nil, l.errorf(l.location(), "invalid escape sequence")
+			}
+			code, err := strconv.ParseUint(l.source[l.pos+2:l.pos+6], 16, 16)
+			if err != nil {
+				return nil, l.errorf(l.location(), "invalid escape sequence %q", l.source[l.pos:l.pos+6])
+			}
+			value.WriteRune(rune(code))
+			l.pos += 6
+		default:
+			r, size := utf8.DecodeRuneInString(l.source[l.pos:])
+			value.WriteRune(r)
+			l.pos += size
+		}
+	}
+}
+
+// blockString reads a string in triple quotes, removing its common
+// indentation, and blank leading and trailing lines.
+func (l *lexer) blockString(t *token) (*token, error) {
+	l.pos += 3
+	raw := new(strings.Builder)
+	for {
+		if l.pos >= len(l.source) {
+			return nil, l.errorf(t.loc, "unterminated string")
+		}
+
+		switch rest := l.source[l.pos:]; {
+		case strings.HasPrefix(rest, `"""`):
+			l.pos += 3
+			t.kind, t.value = stringValue, blockStringValue(raw.String())
+			return t, nil
+		case strings.HasPrefix(rest, `\"""`):
+			raw.WriteString(`"""`)
+			l.pos += 4
+		case rest[0] == '\n' || rest[0] == '\r':
+			raw.WriteByte('\n')
+			l.newLine()
+		default:
+			raw.WriteByte(rest[0])
+			l.pos++
+		}
+	}
+}
+
+// blockStringValue returns the value of a block string with given raw content,
+// whose line terminators are "\n".
+func blockStringValue(raw string) string {
+	lines := strings.Split(raw, "\n")
+
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) < indent {
+				lines[i] = ""
+			} else {
+				lines[i] = lines[i][indent:]
+			}
+		}
+	}
+
+	for len(lines) != 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) != 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// location returns the location of the next character.
+func (l *lexer) location() Location {
+	return Location{
+		Line:   l.line,
+		Column: utf8.RuneCountInString(l.source[l.start:l.pos]) + 1,
+	}
+}
+
+// errorf returns a syntax error at given location.
+func (l *lexer) errorf(loc Location, format string, a ...interface{}) error {
+	return &Error{
+		Message:   "Syntax error: " + fmt.Sprintf(format, a...) + ".",
+		Locations: []Location{loc},
+	}
+}
+
+// isLetter returns true if c is an ASCII letter.
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// isDigit returns true if c is a decimal digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
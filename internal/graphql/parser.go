@@ -0,0 +1,536 @@
+package graphql
+
+import "fmt"
+
+// document is a parsed GraphQL document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is an operation definition, e.g. a query.
+type operation struct {
+	kind       string // Either "query", "mutation", or "subscription".
+	name       string // Empty if the operation is anonymous.
+	variables  []*variableDefinition
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+// variableDefinition defines a variable of an operation.
+type variableDefinition struct {
+	name         string
+	typ          *typeRef
+	defaultValue *value // nil if the variable has no default value.
+	loc          Location
+}
+
+// typeRef refers to a type, e.g. "[String!]".
+type typeRef struct {
+	name    string   // Name of a named type, empty if the type is a list.
+	elem    *typeRef // Type of a list's elements, nil if the type is named.
+	nonNull bool
+}
+
+// String returns the type as written in documents.
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// selection is a *field, a *fragmentSpread, or an *inlineFragment.
+type selection interface{}
+
+// field is a selection of a field.
+type field struct {
+	alias      string // Key of the field in the response, its name if empty.
+	name       string
+	arguments  []*argument
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+// key returns the key of the field in the response.
+func (f *field) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// fragmentSpread is a selection of a named fragment.
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+// inlineFragment is a selection of a fragment defined in place.
+type inlineFragment struct {
+	typeCondition string // Empty if the fragment has no type condition.
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+// fragment is a fragment definition.
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+// directive is a directive, e.g. "@include(if: $details)".
+type directive struct {
+	name      string
+	arguments []*argument
+	loc       Location
+}
+
+// argument is an argument of a field or a directive.
+type argument struct {
+	name  string
+	value *value
+	loc   Location
+}
+
+// Kinds of values.
+const (
+	variableKind = iota
+	intKind
+	floatKind
+	stringKind
+	booleanKind
+	nullKind
+	enumKind
+	listKind
+	objectKind
+)
+
+// value is a literal value, or a variable.
+type value struct {
+	kind   int
+	raw    string         // Name of a variable or an enum value, text of a number, or value of a string or a boolean.
+	list   []*value       // Elements of a list.
+	fields []*objectField // Fields of an object.
+	loc    Location
+}
+
+// objectField is a field of an object value.
+type objectField struct {
+	name  string
+	value *value
+	loc   Location
+}
+
+// parser parses GraphQL documents.
+type parser struct {
+	lexer *lexer
+	token *token // Next token.
+}
+
+// parse parses a GraphQL document.
+func parse(source string) (_ *document, err error) {
+	p := &parser{lexer: newLexer(source)}
+	if p.token, err = p.lexer.next(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for {
+		switch {
+		case p.peek(punctuator, "{"), p.peek(name, "query"), p.peek(name, "mutation"), p.peek(name, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peek(name, "fragment"):
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[f.name]; ok {
+				return nil, &Error{
+					Message:   fmt.Sprintf("There can be only one fragment named %q.", f.name),
+					Locations: []Location{f.loc},
+				}
+			}
+			doc.fragments[f.name] = f
+		case p.token.kind == eof && len(doc.operations)+len(doc.fragments) != 0:
+			return doc, nil
+		default:
+			return nil, p.unexpected()
+		}
+	}
+}
+
+// operation parses an operation definition.
+func (p *parser) operation() (_ *operation, err error) {
+	op := &operation{kind: "query", loc: p.token.loc}
+	if p.peek(punctuator, "{") {
+		op.selections, err = p.selectionSet()
+		return op, err
+	}
+
+	op.kind = p.token.value
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == name {
+		op.name = p.token.value
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek(punctuator, "(") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.peek(punctuator, ")") {
+			definition, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.variables = append(op.variables, definition)
+		}
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if op.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	op.selections, err = p.selectionSet()
+	return op, err
+}
+
+// variableDefinition parses a definition of a variable, e.g. "$id: Int = 1".
+func (p *parser) variableDefinition() (_ *variableDefinition, err error) {
+	definition := &variableDefinition{loc: p.token.loc}
+	if definition.name, err = p.variable(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(punctuator, ":"); err != nil {
+		return nil, err
+	}
+	if definition.typ, err = p.typeRef(); err != nil {
+		return nil, err
+	}
+
+	if p.peek(punctuator, "=") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if definition.defaultValue, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = p.directives(); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// variable parses a variable, e.g. "$id", and returns its name.
+func (p *parser) variable() (string, error) {
+	if err := p.expect(punctuator, "$"); err != nil {
+		return "", err
+	}
+	return p.name()
+}
+
+// typeRef parses a reference to a type.
+func (p *parser) typeRef() (t *typeRef, err error) {
+	t = new(typeRef)
+	if p.peek(punctuator, "[") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if t.elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(punctuator, "]"); err != nil {
+			return nil, err
+		}
+	} else if t.name, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	if p.peek(punctuator, "!") {
+		t.nonNull = true
+		err = p.advance()
+	}
+	return t, err
+}
+
+// selectionSet parses a selection set, e.g. "{ id title }".
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect(punctuator, "{"); err != nil {
+		return nil, err
+	}
+
+	selections := make([]selection, 0)
+	for !p.peek(punctuator, "}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, p.unexpected()
+	}
+	return selections, p.advance()
+}
+
+// selection parses a field, a fragment spread, or an inline fragment.
+func (p *parser) selection() (_ selection, err error) {
+	if !p.peek(punctuator, "...") {
+		return p.field()
+	}
+
+	loc := p.token.loc
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == name && p.token.value != "on" {
+		spread := &fragmentSpread{loc: loc}
+		if spread.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		spread.directives, err = p.directives()
+		return spread, err
+	}
+
+	inline := &inlineFragment{loc: loc}
+	if p.peek(name, "on") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if inline.typeCondition, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if inline.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	inline.selections, err = p.selectionSet()
+	return inline, err
+}
+
+// field parses a field, e.g. "similar: similar(limit: 3) { id }".
+func (p *parser) field() (_ *field, err error) {
+	f := &field{loc: p.token.loc}
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peek(punctuator, ":") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		f.alias = f.name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.arguments, err = p.arguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek(punctuator, "{") {
+		f.selections, err = p.selectionSet()
+	}
+	return f, err
+}
+
+// arguments parses arguments in parentheses, if any. Variables aren't allowed in
+// constant arguments.
+func (p *parser) arguments(constant bool) (_ []*argument, err error) {
+	if !p.peek(punctuator, "(") {
+		return nil, nil
+	}
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+
+	arguments := make([]*argument, 0)
+	for !p.peek(punctuator, ")") {
+		a := &argument{loc: p.token.loc}
+		if a.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(punctuator, ":"); err != nil {
+			return nil, err
+		}
+		if a.value, err = p.value(constant); err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, a)
+	}
+	if len(arguments) == 0 {
+		return nil, p.unexpected()
+	}
+	return arguments, p.advance()
+}
+
+// directives parses directives, if any.
+func (p *parser) directives() (_ []*directive, err error) {
+	var directives []*directive
+	for p.peek(punctuator, "@") {
+		d := &directive{loc: p.token.loc}
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.arguments, err = p.arguments(false); err != nil {
+			return nil, err
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// fragment parses a fragment definition.
+func (p *parser) fragment() (_ *fragment, err error) {
+	f := &fragment{loc: p.token.loc}
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	if p.peek(name, "on") {
+		return nil, p.unexpected()
+	}
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(name, "on"); err != nil {
+		return nil, err
+	}
+	if f.typeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	f.selections, err = p.selectionSet()
+	return f, err
+}
+
+// value parses a value. Variables aren't allowed in constant values.
+func (p *parser) value(constant bool) (_ *value, err error) {
+	v := &value{loc: p.token.loc, raw: p.token.value}
+	switch t := p.token; {
+	case t.kind == punctuator && t.value == "$" && !constant:
+		v.kind = variableKind
+		v.raw, err = p.variable()
+		return v, err
+	case t.kind == punctuator && t.value == "[":
+		v.kind = listKind
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		v.list = make([]*value, 0)
+		for !p.peek(punctuator, "]") {
+			elem, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, elem)
+		}
+		return v, p.advance()
+	case t.kind == punctuator && t.value == "{":
+		v.kind = objectKind
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		v.fields = make([]*objectField, 0)
+		for !p.peek(punctuator, "}") {
+			f := &objectField{loc: p.token.loc}
+			if f.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err = p.expect(punctuator, ":"); err != nil {
+				return nil, err
+			}
+			if f.value, err = p.value(constant); err != nil {
+				return nil, err
+			}
+			v.fields = append(v.fields, f)
+		}
+		return v, p.advance()
+	case t.kind == intValue:
+		v.kind = intKind
+	case t.kind == floatValue:
+		v.kind = floatKind
+	case t.kind == stringValue:
+		v.kind = stringKind
+	case t.kind == name && (t.value == "true" || t.value == "false"):
+		v.kind = booleanKind
+	case t.kind == name && t.value == "null":
+		v.kind = nullKind
+	case t.kind == name:
+		v.kind = enumKind
+	default:
+		return nil, p.unexpected()
+	}
+	return v, p.advance()
+}
+
+// name parses a name, and returns it.
+func (p *parser) name() (string, error) {
+	if p.token.kind != name {
+		return "", p.unexpected()
+	}
+	value := p.token.value
+	return value, p.advance()
+}
+
+// peek returns true if the next token is of given kind and value.
+func (p *parser) peek(kind int, value string) bool {
+	return p.token.kind == kind && p.token.value == value
+}
+
+// expect reads the next token, and returns an error unless it's of given kind
+// and value.
+func (p *parser) expect(kind int, value string) error {
+	if !p.peek(kind, value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+// advance reads the next token.
+func (p *parser) advance() (err error) {
+	p.token, err = p.lexer.next()
+	return err
+}
+
+// unexpected returns a syntax error about the next token.
+func (p *parser) unexpected() error {
+	switch p.token.kind {
+	case eof:
+		return p.lexer.errorf(p.token.loc, "unexpected end of document")
+	case stringValue:
+		return p.lexer.errorf(p.token.loc, "unexpected string %q", p.token.value)
+	default:
+		return p.lexer.errorf(p.token.loc, "unexpected %q", p.token.value)
+	}
+}
@@ -0,0 +1,200 @@
+package graphql
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// randomSeeds are documents that are mutated to generate random documents.
+var randomSeeds = []string{
+	`{ book(id: 1) { id title series __typename } }`,
+	`query A { a: book(id: 1) { id } } query B($id: Int!, $first: Int) { b: book(id: $id) { related(first: $first) { id } } }`,
+	`query($id: Int = 3) { book(id: $id) { ...Title ... on Book { id } ... { title } } } fragment Title on Book { title }`,
+	`query($details: Boolean!) { book(id: 1) { id title @include(if: $details) series @skip(if: $details) } }`,
+	`{ search(by: {titleHas: "an", limit: 1, ids: [3]}) { id } echo(value: [1.5e3, -2], id: 7) }`,
+	"{ book(id: 2) { title(format: \"\"\"\n  block \\\"\"\" \"\"\", escaped: \"\\u00e9\\n\") } } # comment",
+	`{ books(first: 3) { related(first: 3) { id sequel { sequel { id } } } } }`,
+}
+
+// randomTokens are inserted into documents by mutate.
+var randomTokens = []string{
+	"{", "}", "(", ")", "[", "]", ":", "!", "=", "$", "@", "...", "|", "&", ",", " ", "\n", "#",
+	`"`, `"""`, `\`, `\u`, "0", "-1", "1.5e", "012", "on", "query", "fragment", "book", "id", "null", "true", "é",
+}
+
+// maxMutated is the maximum number of bytes in a range edited by mutate, small
+// so that mutated documents are often still valid.
+const maxMutated = 8
+
+// mutate returns source with n random edits, each of which inserts a token, or
+// deletes, duplicates or moves to the end a range of bytes.
+func mutate(random *rand.Rand, source string, n int) string {
+	for i := 0; i < n; i++ {
+		at := random.Intn(len(source) + 1)
+		length := len(source) - at
+		if length > maxMutated {
+			length = maxMutated
+		}
+		end := at + random.Intn(length+1)
+		switch random.Intn(4) {
+		case 0:
+			source = source[:at] + randomTokens[random.Intn(len(randomTokens))] + source[at:]
+		case 1:
+			source = source[:at] + source[end:]
+		case 2:
+			source = source[:end] + source[at:end] + source[end:]
+		default:
+			source = source[:at] + source[end:] + source[at:end]
+		}
+	}
+	return source
+}
+
+// TestParseRandom tests that parsing randomly mutated documents either
+// succeeds or fails with a located error, and doesn't panic.
+func TestParseRandom(t *testing.T) {
+	for _, seed := range randomSeeds {
+		if _, err := parse(seed); err != nil {
+			t.Fatalf("%q: failed to parse seed: %s", seed, err.Error())
+		}
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		source := mutate(random, randomSeeds[random.Intn(len(randomSeeds))], 1+random.Intn(4))
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("%q: parsing panicked: %v", source, r)
+				}
+			}()
+
+			doc, err := parse(source)
+			if err == nil {
+				if len(doc.operations)+len(doc.fragments) == 0 {
+					t.Errorf("%q: expected a definition, got an empty document", source)
+				}
+				return
+			}
+
+			gqlErr, ok := err.(*Error)
+			if !ok || gqlErr.Message == "" || len(gqlErr.Locations) != 1 {
+				t.Errorf("%q: expected a located error, got: %s", source, fmt.Sprint(err))
+			}
+		}()
+	}
+}
+
+// TestParse tests parsing valid documents.
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# Books by ID.
+		query Books($id: Int! = 1, $ids: [Int!]) @cached {
+			first: book(id: $id) { ...details }
+			book(id: 2, by: {title: "Dune", tags: ["a" "b"], rated: 4.5, new: true, old: null, sort: PAGES}) {
+				... on Book @include(if: true) { id }
+			}
+		}
+
+		fragment details on Book { id, title }
+	`)
+	if err != nil {
+		t.Fatalf("failed to parse document: %s", err.Error())
+	}
+	if len(doc.operations) != 1 || doc.fragments["details"] == nil {
+		t.Fatalf("expected an operation and a fragment, got: %+v", *doc)
+	}
+
+	op := doc.operations[0]
+	if op.kind != "query" || op.name != "Books" || len(op.directives) != 1 {
+		t.Errorf("expected query Books with a directive, got: %+v", *op)
+	}
+	if len(op.variables) != 2 || op.variables[0].typ.String() != "Int!" || op.variables[0].defaultValue.raw != "1" || op.variables[1].typ.String() != "[Int!]" {
+		t.Errorf("expected variables $id: Int! = 1, and $ids: [Int!], got: %+v, %+v", *op.variables[0], *op.variables[1])
+	}
+
+	first := op.selections[0].(*field)
+	if first.key() != "first" || first.name != "book" || first.arguments[0].value.kind != variableKind {
+		t.Errorf("expected field first: book(id: $id), got: %+v", *first)
+	}
+	if spread := first.selections[0].(*fragmentSpread); spread.name != "details" {
+		t.Errorf("expected spread of details, got: %s", spread.name)
+	}
+
+	second := op.selections[1].(*field)
+	expected := `{title: "Dune", tags: ["a", "b"], rated: 4.5, new: true, old: null, sort: PAGES}`
+	if by := second.arguments[1].value.String(); by != expected {
+		t.Errorf("expected argument: %s, got: %s", expected, by)
+	}
+	if inline := second.selections[0].(*inlineFragment); inline.typeCondition != "Book" || len(inline.directives) != 1 {
+		t.Errorf("expected inline fragment on Book with a directive, got: %+v", *inline)
+	}
+}
+
+// TestParseErrors tests that invalid documents fail to parse with located
+// syntax errors.
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		source  string
+		message string
+		loc     Location
+	}{
+		{source: "", message: "unexpected end of document", loc: Location{Line: 1, Column: 1}},
+		{source: "{ book(id: 1) }\n{", message: "unexpected end of document", loc: Location{Line: 2, Column: 2}},
+		{source: "{ book(id: ) }", message: `unexpected ")"`, loc: Location{Line: 1, Column: 12}},
+		{source: "query($id: Int = $other) { id }", message: `unexpected "$"`, loc: Location{Line: 1, Column: 18}},
+		{source: "{ book { } }", message: `unexpected "}"`, loc: Location{Line: 1, Column: 10}},
+		{source: "{ id }\nfragment on on Book { id }", message: `unexpected "on"`, loc: Location{Line: 2, Column: 10}},
+		{source: "{ book(id: 012) }", message: "invalid number, unexpected digit after 0", loc: Location{Line: 1, Column: 12}},
+		{source: "{ book(id: 1.) }", message: `invalid number "1."`, loc: Location{Line: 1, Column: 12}},
+		{source: "{ book(id: 1x) }", message: `invalid number, unexpected 'x'`, loc: Location{Line: 1, Column: 12}},
+		{source: `{ book(title: "Dune) }`, message: "unterminated string", loc: Location{Line: 1, Column: 15}},
+		{source: `{ book(title: "\q") }`, message: "invalid escape sequence", loc: Location{Line: 1, Column: 16}},
+		{source: "{ book(title: \"é\" id: ?) }", message: `unexpected '?'`, loc: Location{Line: 1, Column: 23}},
+		{source: "{ a .. }", message: `unexpected '.'`, loc: Location{Line: 1, Column: 5}},
+		{source: "{ id }\nfragment a on Book { id }\nfragment a on Book { id }", message: "", loc: Location{Line: 3, Column: 1}},
+	} {
+		_, err := parse(test.source)
+		gqlErr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%q: expected an error, got: %v", test.source, err)
+			continue
+		}
+		if test.message != "" && gqlErr.Message != "Syntax error: "+test.message+"." {
+			t.Errorf("%q: expected message: Syntax error: %s., got: %s", test.source, test.message, gqlErr.Message)
+		}
+		if len(gqlErr.Locations) != 1 || gqlErr.Locations[0] != test.loc {
+			t.Errorf("%q: expected location: %+v, got: %+v", test.source, test.loc, gqlErr.Locations)
+		}
+	}
+}
+
+// TestStrings tests parsing strings and block strings.
+func TestStrings(t *testing.T) {
+	for source, expected := range map[string]string{
+		`"Harry \"Potter\"\n"`:                        "Harry \"Potter\"\n",
+		`"café \/ \\"`:                                `café / \`,
+		`"""raw \n "quoted" \""""""`:                  `raw \n "quoted" """`,
+		"\"\"\"\n    Don't\n      panic.\n\n  \"\"\"": "Don't\n  panic.",
+		bom + `"bom"`:                                 "bom",
+	} {
+		l := newLexer(source)
+		token, err := l.next()
+		if err != nil {
+			t.Errorf("%q: failed to read string: %s", source, err.Error())
+			continue
+		}
+		if token.kind != stringValue || token.value != expected {
+			t.Errorf("%q: expected string: %q, got: %q", source, expected, token.value)
+		}
+		if next, err := l.next(); err != nil || next.kind != eof {
+			t.Errorf("%q: expected end of document after string", source)
+		}
+	}
+
+	if _, err := parse(`{ a(s: """unterminated) }`); err == nil || !strings.Contains(err.Error(), "unterminated string") {
+		t.Errorf("expected an unterminated string error, got: %v", err)
+	}
+}
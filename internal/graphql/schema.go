@@ -0,0 +1,489 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type is a GraphQL type, either a *Scalar, an *Object, an *InputObject, a
+// *List, or a *NonNull.
+type Type interface {
+	String() string // Returns the type as written in documents, e.g. "[Book!]".
+}
+
+// Scalar is a built-in scalar type, i.e. Int, Float, String, Boolean or ID.
+type Scalar struct {
+	Name        string
+	Description string
+
+	parse     func(interface{}) (interface{}, bool) // Coerces an input value, returns false if it's invalid.
+	serialize func(interface{}) (interface{}, bool) // Coerces a resolved value, returns false if it's invalid.
+}
+
+// Built-in scalars. Inputs are coerced into ints, float64s, strings and bools,
+// IDs are strings.
+var (
+	Int = &Scalar{
+		Name:        "Int",
+		Description: "A signed 32-bit integer.",
+		parse:       parseInt,
+		serialize: func(v interface{}) (interface{}, bool) {
+			i, ok := toInt64(v)
+			return i, ok && math.MinInt32 <= i && i <= math.MaxInt32
+		},
+	}
+	Float = &Scalar{
+		Name:        "Float",
+		Description: "A double-precision floating point number.",
+		parse:       parseFloat,
+		serialize: func(v interface{}) (interface{}, bool) {
+			var f float64
+			switch v := v.(type) {
+			case float32:
+				f, _ = strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64) // Shortest float64 that prints the same, e.g. 4.56 rather than 4.559999942779541.
+			case float64:
+				f = v
+			default:
+				i, ok := toInt64(v)
+				if !ok {
+					return nil, false
+				}
+				f = float64(i)
+			}
+			return f, !math.IsNaN(f) && !math.IsInf(f, 0)
+		},
+	}
+	String = &Scalar{
+		Name:        "String",
+		Description: "A UTF-8 string.",
+		parse: func(v interface{}) (interface{}, bool) {
+			s, ok := v.(string)
+			return s, ok
+		},
+		serialize: func(v interface{}) (interface{}, bool) {
+			s, ok := v.(string)
+			return s, ok
+		},
+	}
+	Boolean = &Scalar{
+		Name:        "Boolean",
+		Description: "Either true or false.",
+		parse: func(v interface{}) (interface{}, bool) {
+			b, ok := v.(bool)
+			return b, ok
+		},
+		serialize: func(v interface{}) (interface{}, bool) {
+			b, ok := v.(bool)
+			return b, ok
+		},
+	}
+	ID = &Scalar{
+		Name:        "ID",
+		Description: "A unique identifier, serialized as a string.",
+		parse: func(v interface{}) (interface{}, bool) {
+			if s, ok := v.(string); ok {
+				return s, true
+			}
+			i, ok := parseInt(v)
+			if !ok {
+				return nil, false
+			}
+			return strconv.Itoa(i.(int)), true
+		},
+		serialize: func(v interface{}) (interface{}, bool) {
+			if s, ok := v.(string); ok {
+				return s, true
+			}
+			i, ok := toInt64(v)
+			return strconv.FormatInt(i, 10), ok
+		},
+	}
+)
+
+// String returns the scalar's name.
+func (s *Scalar) String() string {
+	return s.Name
+}
+
+// Object is an object type, whose fields are queried.
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+// String returns the object's name.
+func (o *Object) String() string {
+	return o.Name
+}
+
+// field returns the object's field with given name, or nil if there is none.
+func (o *Object) field(name string) *Field {
+	for _, f := range o.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Field is a field of an object.
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Argument
+
+	// Resolve returns the field's value on source, the value of the object the
+	// field belongs to, which is nil for fields of the query type. args holds
+	// coerced arguments, omitting ones that weren't given and have no default.
+	// If nil, the value is the field of source, a struct or a pointer to one,
+	// whose name matches the field's ignoring case. Errors are reported with
+	// their messages capitalized and ending with a period.
+	Resolve func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+	// Size returns the maximum number of elements of a list field with given
+	// arguments, used to compute the complexity of queries. Lists are assumed to
+	// have a single element if nil.
+	Size func(args map[string]interface{}) int
+}
+
+// Argument is an argument of a field, or a field of an input object.
+type Argument struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     interface{} // Coerced value used if the argument isn't given, none if nil.
+}
+
+// InputObject is an input object type, used as an argument.
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      []*Argument
+}
+
+// String returns the input object's name.
+func (o *InputObject) String() string {
+	return o.Name
+}
+
+// List is a list of values of another type.
+type List struct {
+	OfType Type
+}
+
+// String returns the list as written in documents, e.g. "[Book]".
+func (l *List) String() string {
+	return "[" + l.OfType.String() + "]"
+}
+
+// NonNull is a type whose values can't be null.
+type NonNull struct {
+	OfType Type
+}
+
+// String returns the type as written in documents, e.g. "Book!".
+func (n *NonNull) String() string {
+	return n.OfType.String() + "!"
+}
+
+// Schema is a GraphQL schema, which only supports queries.
+type Schema struct {
+	query *Object
+	types map[string]Type // Named types by name, including built-in scalars.
+	names []string        // Names of types defined by the schema, in order of definition.
+}
+
+// NewSchema returns a schema whose query type is query, and whose types are
+// the ones reachable from it. Returns an error if a type is invalid, or if
+// different types have the same name.
+func NewSchema(query *Object) (*Schema, error) {
+	s := &Schema{
+		query: query,
+		types: make(map[string]Type),
+	}
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		s.types[scalar.Name] = scalar
+	}
+
+	if err := s.define(query); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// define adds a named type, and the types it refers to, to the schema.
+func (s *Schema) define(t Type) error {
+	name := t.String()
+	if defined, ok := s.types[name]; ok {
+		if defined != t {
+			return fmt.Errorf("different types are named %q", name)
+		}
+		return nil
+	}
+	if name == "" || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("invalid type name %q", name)
+	}
+	s.types[name] = t
+	s.names = append(s.names, name)
+
+	switch t := t.(type) {
+	case *Object:
+		if len(t.Fields) == 0 {
+			return fmt.Errorf("type %s has no fields", name)
+		}
+		names := make(map[string]bool)
+		for _, f := range t.Fields {
+			if f.Name == "" || strings.HasPrefix(f.Name, "__") || names[f.Name] {
+				return fmt.Errorf("invalid field name %q of %s", f.Name, name)
+			}
+			names[f.Name] = true
+
+			if f.Type == nil || !output(f.Type) {
+				return fmt.Errorf("field %s.%s has no output type", name, f.Name)
+			}
+			if err := s.define(named(f.Type)); err != nil {
+				return err
+			}
+			if err := s.defineArguments(name+"."+f.Name, f.Args); err != nil {
+				return err
+			}
+		}
+	case *InputObject:
+		if len(t.Fields) == 0 {
+			return fmt.Errorf("input %s has no fields", name)
+		}
+		return s.defineArguments(name, t.Fields)
+	default:
+		return fmt.Errorf("unsupported type %s", name)
+	}
+	return nil
+}
+
+// defineArguments adds the types of arguments of owner to the schema.
+func (s *Schema) defineArguments(owner string, arguments []*Argument) error {
+	names := make(map[string]bool)
+	for _, a := range arguments {
+		if a.Name == "" || strings.HasPrefix(a.Name, "__") || names[a.Name] {
+			return fmt.Errorf("invalid argument name %q of %s", a.Name, owner)
+		}
+		names[a.Name] = true
+
+		if a.Type == nil || !input(a.Type) {
+			return fmt.Errorf("argument %s of %s has no input type", a.Name, owner)
+		}
+		if err := s.define(named(a.Type)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns the schema in GraphQL's schema definition language.
+func (s *Schema) String() string {
+	var b strings.Builder
+	b.WriteString("schema {\n  query: " + s.query.Name + "\n}\n")
+	for _, name := range s.names {
+		b.WriteString("\n")
+		switch t := s.types[name].(type) {
+		case *Object:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("type " + t.Name + " {\n")
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + f.Name)
+				writeArguments(&b, f.Args)
+				b.WriteString(": " + f.Type.String() + "\n")
+			}
+		case *InputObject:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("input " + t.Name + " {\n")
+			for _, a := range t.Fields {
+				writeDescription(&b, "  ", a.Description)
+				b.WriteString("  " + definition(a) + "\n")
+			}
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// writeArguments writes the arguments of a field in parentheses, on separate
+// lines if any of them is described.
+func writeArguments(b *strings.Builder, arguments []*Argument) {
+	if len(arguments) == 0 {
+		return
+	}
+
+	described := false
+	for _, a := range arguments {
+		described = described || a.Description != ""
+	}
+	if !described {
+		written := make([]string, len(arguments))
+		for i, a := range arguments {
+			written[i] = definition(a)
+		}
+		b.WriteString("(" + strings.Join(written, ", ") + ")")
+		return
+	}
+
+	b.WriteString("(\n")
+	for _, a := range arguments {
+		writeDescription(b, "    ", a.Description)
+		b.WriteString("    " + definition(a) + "\n")
+	}
+	b.WriteString("  )")
+}
+
+// definition returns the definition of an argument, e.g. "first: Int = 20".
+func definition(a *Argument) string {
+	if a.Default == nil {
+		return a.Name + ": " + a.Type.String()
+	}
+	return a.Name + ": " + a.Type.String() + " = " + literal(a.Default)
+}
+
+// writeDescription writes a description on its own line, if it isn't empty.
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description != "" {
+		b.WriteString(indent + quote(description) + "\n")
+	}
+}
+
+// literal returns a coerced input value as written in documents.
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	case []interface{}:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			elems[i] = literal(elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			fields[i] = key + ": " + literal(v[key])
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// quote returns s as a string literal. GraphQL's escape sequences are the same
+// as JSON's.
+func quote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// named returns the named type of a possibly wrapped type, e.g. Book of
+// "[Book!]".
+func named(t Type) Type {
+	for {
+		switch wrapper := t.(type) {
+		case *List:
+			t = wrapper.OfType
+		case *NonNull:
+			t = wrapper.OfType
+		default:
+			return t
+		}
+	}
+}
+
+// isList returns true if t is a list, or a non-null list.
+func isList(t Type) bool {
+	if nonNull, ok := t.(*NonNull); ok {
+		t = nonNull.OfType
+	}
+	_, ok := t.(*List)
+	return ok
+}
+
+// output returns true if values of t can be returned by fields.
+func output(t Type) bool {
+	switch named(t).(type) {
+	case *Scalar, *Object:
+		return true
+	default:
+		return false
+	}
+}
+
+// input returns true if values of t can be given as arguments.
+func input(t Type) bool {
+	switch named(t).(type) {
+	case *Scalar, *InputObject:
+		return true
+	default:
+		return false
+	}
+}
+
+// resolveField is the resolver of fields that don't specify one. It returns the
+// field of source, a struct or a pointer to one, with given name ignoring case.
+func resolveField(source interface{}, name string) (interface{}, error) {
+	value := reflect.ValueOf(source)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't resolve field %q of %T", name, source)
+	}
+
+	field := value.FieldByNameFunc(func(field string) bool {
+		return strings.EqualFold(field, name)
+	})
+	if !field.IsValid() {
+		return nil, fmt.Errorf("can't resolve field %q of %T", name, source)
+	}
+	return field.Interface(), nil
+}
+
+// toInt64 returns an integer of any type as an int64.
+func toInt64(v interface{}) (int64, bool) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(value.Uint()), true
+	default:
+		return 0, false
+	}
+}
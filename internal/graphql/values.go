@@ -0,0 +1,300 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Input values are coerced from literals of documents, and from variables
+// decoded from JSON. Numeric literals are represented as json.Numbers, so both
+// are coerced by the same scalar functions.
+
+// enumValue is an enum literal, which no scalar accepts.
+type enumValue string
+
+// parseInt coerces an integer input value, which must fit in 32 bits.
+func parseInt(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		i, err := strconv.ParseInt(string(v), 10, 32)
+		return int(i), err == nil
+	case float64:
+		return int(v), v == math.Trunc(v) && math.MinInt32 <= v && v <= math.MaxInt32
+	default:
+		return nil, false
+	}
+}
+
+// parseFloat coerces a numeric input value.
+func parseFloat(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil && !math.IsInf(f, 0)
+	case float64:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// variable is a variable of the executed operation.
+type variable struct {
+	typ        Type
+	value      interface{} // Coerced value.
+	given      bool        // False if the variable has no value, nor a default one.
+	hasDefault bool
+}
+
+// coerceLiteral coerces a value of a document into a value of type t. Returns
+// false if the value is a variable that wasn't given, in which case the
+// default value of its position is used.
+func coerceLiteral(v *value, t Type, variables map[string]*variable) (interface{}, bool, error) {
+	if v.kind == variableKind {
+		variable, ok := variables[v.raw]
+		if !ok {
+			return nil, false, locatedError(v.loc, "Variable \"$%s\" is not defined.", v.raw)
+		}
+		if !compatible(variable.typ, t, variable.hasDefault) {
+			return nil, false, locatedError(v.loc, "Variable \"$%s\" of type \"%s\" used in position expecting type \"%s\".", v.raw, variable.typ, t)
+		}
+		return variable.value, variable.given, nil
+	}
+
+	if nonNull, ok := t.(*NonNull); ok {
+		if v.kind == nullKind {
+			return nil, false, locatedError(v.loc, "Expected value of type \"%s\", found null.", t)
+		}
+		return coerceLiteral(v, nonNull.OfType, variables)
+	}
+	if v.kind == nullKind {
+		return nil, true, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if v.kind != listKind {
+			elem, _, err := coerceLiteral(v, t.OfType, variables)
+			if err != nil {
+				return nil, false, err
+			}
+			return []interface{}{elem}, true, nil
+		}
+
+		list := make([]interface{}, len(v.list))
+		for i, elem := range v.list {
+			coerced, given, err := coerceLiteral(elem, t.OfType, variables)
+			if err != nil {
+				return nil, false, err
+			}
+			if !given {
+				if _, ok := t.OfType.(*NonNull); ok {
+					return nil, false, locatedError(elem.loc, "Expected value of type \"%s\", found %s.", t.OfType, elem)
+				}
+			}
+			list[i] = coerced
+		}
+		return list, true, nil
+	case *InputObject:
+		if v.kind != objectKind {
+			return nil, false, locatedError(v.loc, "Expected value of type \"%s\", found %s.", t, v)
+		}
+
+		given := make(map[string]*objectField)
+		for _, f := range v.fields {
+			if given[f.name] != nil {
+				return nil, false, locatedError(f.loc, "There can be only one input field named %q.", f.name)
+			}
+			given[f.name] = f
+		}
+		for _, f := range v.fields {
+			if inputField(t, f.name) == nil {
+				return nil, false, locatedError(f.loc, "Field %q is not defined by type \"%s\".", f.name, t)
+			}
+		}
+
+		object := make(map[string]interface{})
+		for _, field := range t.Fields {
+			if f, ok := given[field.Name]; ok {
+				coerced, given, err := coerceLiteral(f.value, field.Type, variables)
+				if err != nil {
+					return nil, false, err
+				}
+				if given {
+					object[field.Name] = coerced
+					continue
+				}
+			}
+			if !defaultField(object, field) {
+				return nil, false, locatedError(v.loc, "Field \"%s.%s\" of required type \"%s\" was not provided.", t.Name, field.Name, field.Type)
+			}
+		}
+		return object, true, nil
+	case *Scalar:
+		coerced, ok := t.parse(scalarLiteral(v))
+		if !ok {
+			return nil, false, locatedError(v.loc, "Expected value of type \"%s\", found %s.", t, v)
+		}
+		return coerced, true, nil
+	default:
+		return nil, false, locatedError(v.loc, "Expected value of type \"%s\", found %s.", t, v)
+	}
+}
+
+// scalarLiteral returns the input value of a literal that's coerced by a scalar.
+func scalarLiteral(v *value) interface{} {
+	switch v.kind {
+	case intKind, floatKind:
+		return json.Number(v.raw)
+	case stringKind:
+		return v.raw
+	case booleanKind:
+		return v.raw == "true"
+	case enumKind:
+		return enumValue(v.raw)
+	default:
+		return v
+	}
+}
+
+// coerceInput coerces a value decoded from JSON into a value of type t.
+func coerceInput(v interface{}, t Type) (interface{}, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("expected non-null value of type \"%s\"", t)
+		}
+		return coerceInput(v, nonNull.OfType)
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		elems, ok := v.([]interface{})
+		if !ok {
+			elem, err := coerceInput(v, t.OfType)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{elem}, nil
+		}
+
+		list := make([]interface{}, len(elems))
+		for i, elem := range elems {
+			coerced, err := coerceInput(elem, t.OfType)
+			if err != nil {
+				return nil, fmt.Errorf("%s at index %d", err.Error(), i)
+			}
+			list[i] = coerced
+		}
+		return list, nil
+	case *InputObject:
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object of type \"%s\"", t)
+		}
+		for name := range fields {
+			if inputField(t, name) == nil {
+				return nil, fmt.Errorf("field %q is not defined by type \"%s\"", name, t)
+			}
+		}
+
+		object := make(map[string]interface{})
+		for _, field := range t.Fields {
+			given, ok := fields[field.Name]
+			if !ok {
+				if !defaultField(object, field) {
+					return nil, fmt.Errorf("field \"%s.%s\" of required type \"%s\" was not provided", t.Name, field.Name, field.Type)
+				}
+				continue
+			}
+
+			coerced, err := coerceInput(given, field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s at field %q", err.Error(), field.Name)
+			}
+			object[field.Name] = coerced
+		}
+		return object, nil
+	case *Scalar:
+		coerced, ok := t.parse(v)
+		if !ok {
+			found, _ := json.Marshal(v)
+			return nil, fmt.Errorf("expected value of type \"%s\", found %s", t, found)
+		}
+		return coerced, nil
+	default:
+		return nil, fmt.Errorf("type \"%s\" isn't an input type", t)
+	}
+}
+
+// defaultField sets a field of an input object that wasn't given to its default
+// value, if any. Returns false if the field is required, and has no default.
+func defaultField(object map[string]interface{}, field *Argument) bool {
+	if field.Default != nil {
+		object[field.Name] = field.Default
+		return true
+	}
+	_, required := field.Type.(*NonNull)
+	return !required
+}
+
+// inputField returns the field of an input object with given name, or nil if
+// there is none.
+func inputField(t *InputObject, name string) *Argument {
+	for _, field := range t.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// compatible returns true if a variable of type variableType can be used where
+// a value of type t is expected. A nullable variable can be used in a non-null
+// position only if it has a default value.
+func compatible(variableType, t Type, hasDefault bool) bool {
+	if nonNull, ok := t.(*NonNull); ok {
+		if variableNonNull, ok := variableType.(*NonNull); ok {
+			return compatible(variableNonNull.OfType, nonNull.OfType, false)
+		}
+		return hasDefault && compatible(variableType, nonNull.OfType, false)
+	}
+	if variableNonNull, ok := variableType.(*NonNull); ok {
+		return compatible(variableNonNull.OfType, t, false)
+	}
+
+	variableList, ok := variableType.(*List)
+	if list, isList := t.(*List); isList {
+		return ok && compatible(variableList.OfType, list.OfType, false)
+	}
+	return !ok && variableType == t
+}
+
+// String returns the value as written in documents.
+func (v *value) String() string {
+	switch v.kind {
+	case variableKind:
+		return "$" + v.raw
+	case stringKind:
+		return quote(v.raw)
+	case listKind:
+		elems := make([]string, len(v.list))
+		for i, elem := range v.list {
+			elems[i] = elem.String()
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case objectKind:
+		fields := make([]string, len(v.fields))
+		for i, f := range v.fields {
+			fields[i] = f.name + ": " + f.value.String()
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return v.raw
+	}
+}
@@ -0,0 +1,118 @@
+// Package logging contains a middleware that logs requests served by a server,
+// and assigns each request an ID that is propagated between servers using the
+// X-Request-ID header.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// Header is the header carrying a request's ID.
+const Header = "X-Request-ID"
+
+// validID matches request IDs that are accepted from clients, other IDs are
+// replaced to keep logs readable.
+var validID = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,128}$`)
+
+// contextKey is the type of keys of values stored in a context by this package.
+type contextKey int
+
+// idContextKey is the context key of a request's ID.
+const idContextKey contextKey = 0
+
+// Middleware is a mux middleware that assigns each request an ID, and logs the
+// request after it's served. The ID is taken from the request's X-Request-ID
+// header if it has a valid one, and is generated otherwise. It's added to the
+// request's context, and to the response's X-Request-ID header. Requests whose
+// handlers panic, e.g. with http.ErrAbortHandler to abort a response, are
+// logged as aborted before the panic continues.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !validID.MatchString(id) {
+			id = newID()
+		}
+		w.Header().Set(Header, id)
+
+		r = r.WithContext(WithRequestID(r.Context(), id))
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		defer func() {
+			route := ""
+			if current := mux.CurrentRoute(r); current != nil {
+				route, _ = current.GetPathTemplate()
+			}
+
+			fields := Fields(r)
+			fields["Route"] = route
+			fields["Status"] = recorder.status
+			fields["Duration"] = time.Since(start).Seconds()
+			fields["Bytes"] = recorder.bytes
+			if err := recover(); err != nil {
+				log.WithFields(fields).Warn("Request aborted.")
+				panic(err)
+			}
+			log.WithFields(fields).Info("Request served.")
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// Fields returns log fields that describe a request: its client's address,
+// method, URL and ID. Used to log errors that occur while serving a request.
+func Fields(r *http.Request) log.Fields {
+	return log.Fields{
+		"Address":   r.RemoteAddr,
+		"Method":    r.Method,
+		"URL":       r.URL.String(),
+		"RequestID": RequestID(r.Context()),
+	}
+}
+
+// WithRequestID returns a copy of ctx that carries given request ID. Requests
+// made using the context should carry the ID in their X-Request-ID header.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idContextKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(idContextKey).(string)
+	return id
+}
+
+// newID generates a random request ID.
+func newID() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
+
+// responseRecorder is a ResponseWriter that records the status code and the
+// size of the response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int // Status code written, 200 if WriteHeader wasn't called.
+	bytes  int // Number of bytes of the body written.
+}
+
+// WriteHeader records the status code and writes it.
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written and writes them.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// TestMiddleware tests assigning IDs to requests, and logging them.
+func TestMiddleware(t *testing.T) {
+	var output bytes.Buffer
+	log.SetFormatter(new(log.JSONFormatter))
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/book/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if RequestID(r.Context()) != w.Header().Get(Header) {
+			t.Errorf("request's context doesn't carry its ID")
+		}
+		http.Error(w, "Not found.", http.StatusNotFound)
+	}).Methods("GET")
+
+	for i, test := range []struct {
+		id       string // Sent in X-Request-ID header.
+		expected string // Expected ID, any generated ID if empty.
+	}{
+		{id: "", expected: ""},
+		{id: "frontend-1234", expected: "frontend-1234"},
+		{id: "invalid id\n", expected: ""},
+	} {
+		output.Reset()
+
+		request := httptest.NewRequest("GET", "/book/7", nil)
+		if test.id != "" {
+			request.Header.Set(Header, test.id)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		id := recorder.Header().Get(Header)
+		if (test.expected == "" && len(id) != 32) || (test.expected != "" && id != test.expected) {
+			t.Errorf("test %d: incorrect request ID: %q", i, id)
+		}
+
+		var entry map[string]interface{}
+		if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+			t.Fatalf("test %d: expected one JSON line, got: %s", i, output.String())
+		}
+
+		for field, expected := range map[string]interface{}{
+			"RequestID": id,
+			"Method":    "GET",
+			"Route":     "/book/{id}",
+			"URL":       "/book/7",
+			"Status":    float64(http.StatusNotFound),
+			"Bytes":     float64(len("Not found.\n")),
+			"Address":   request.RemoteAddr,
+		} {
+			if entry[field] != expected {
+				t.Errorf("test %d: expected %s: %v, got: %v", i, field, expected, entry[field])
+			}
+		}
+		if _, ok := entry["Duration"].(float64); !ok {
+			t.Errorf("test %d: missing duration: %s", i, output.String())
+		}
+		if strings.Count(output.String(), "\n") != 1 {
+			t.Errorf("test %d: expected one line, got: %s", i, output.String())
+		}
+	}
+}
+
+// TestMiddlewareAborted tests logging requests whose responses are aborted.
+func TestMiddlewareAborted(t *testing.T) {
+	var output bytes.Buffer
+	log.SetFormatter(new(log.JSONFormatter))
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[1,"))
+		panic(http.ErrAbortHandler)
+	}))
+
+	func() {
+		defer func() {
+			if err := recover(); err != http.ErrAbortHandler {
+				t.Errorf("expected the handler's panic to continue, got: %v", err)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/books", nil))
+	}()
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("expected one JSON line, got: %s", output.String())
+	}
+	if entry["msg"] != "Request aborted." || entry["level"] != "warning" || entry["Bytes"] != float64(len("[1,")) {
+		t.Errorf("expected an aborted request to be logged, got: %s", output.String())
+	}
+}
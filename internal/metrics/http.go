@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// HTTP holds metrics of requests served by a server.
+type HTTP struct {
+	requests  *Counter   // Requests by route, method and status code.
+	durations *Histogram // Durations of requests by route and method.
+}
+
+// NewHTTP registers metrics of requests served by a server, with names that
+// start with given namespace, and returns them.
+func NewHTTP(registry *Registry, namespace string) *HTTP {
+	return &HTTP{
+		requests: registry.Counter(
+			namespace+"_requests_total",
+			"Number of requests served, by route, method and status code.",
+			"route", "method", "status",
+		),
+		durations: registry.Histogram(
+			namespace+"_request_duration_seconds",
+			"Durations of requests in seconds, by route and method.",
+			DefaultBuckets,
+			"route", "method",
+		),
+	}
+}
+
+// Middleware is a mux middleware that records requests. Requests are labeled
+// by the path template of the route they match, e.g. "/book/{id}", to limit
+// the number of labels.
+func (h *HTTP) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		recorder := &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		h.durations.Observe(time.Since(start).Seconds(), route, r.Method)
+		h.requests.Inc(route, r.Method, strconv.Itoa(recorder.Status))
+	})
+}
+
+// StatusRecorder is a ResponseWriter that records the status code of the
+// response.
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status int // Status code written, 200 if WriteHeader wasn't called.
+}
+
+// WriteHeader records the status code and writes it.
+func (s *StatusRecorder) WriteHeader(status int) {
+	s.Status = status
+	s.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,286 @@
+// Package metrics implements counters, histograms and gauges that are exposed
+// in Prometheus' text format, to be scraped from a server's /metrics endpoint.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are upper bounds of histogram buckets suited for durations
+// in seconds of requests and queries.
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry is a set of metrics that are written together.
+type Registry struct {
+	mutex   sync.Mutex
+	metrics []metric // In order of registration.
+}
+
+// metric is a registered metric.
+type metric interface {
+	// write writes the metric's help, type and samples in text format.
+	write(w io.Writer)
+}
+
+// NewRegistry returns a new, empty registry.
+func NewRegistry() *Registry {
+	return new(Registry)
+}
+
+// Counter registers and returns a new counter with given name, help text and
+// label names.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		desc:   desc{name: name, help: help, labels: labels},
+		values: make(map[string]*counterValue),
+	}
+	r.register(c)
+	return c
+}
+
+// Histogram registers and returns a new histogram with given name, help text,
+// bucket upper bounds and label names. Buckets must be sorted.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+	r.register(h)
+	return h
+}
+
+// GaugeFunc registers a gauge with given name and help text whose value is
+// computed by fn when metrics are written.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(&gaugeFunc{
+		desc: desc{name: name, help: help},
+		fn:   fn,
+	})
+}
+
+// register adds a metric to the registry.
+func (r *Registry) register(m metric) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes all metrics of the registry in text format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mutex.Unlock()
+
+	buffered := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(buffered)
+	}
+	return buffered.Flush()
+}
+
+// ServeHTTP writes all metrics of the registry as a response.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
+
+// desc describes a metric.
+type desc struct {
+	name   string
+	help   string
+	labels []string // Names of labels.
+}
+
+// header writes the metric's help and type.
+func (d *desc) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, kind)
+}
+
+// key returns a key identifying given label values, and panics if the number
+// of values doesn't match the number of labels.
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs formats label names and given values, along with extra pairs,
+// e.g. {route="/books",le="0.5"}. Returns an empty string if there are none.
+func (d *desc) labelPairs(values []string, extra ...string) string {
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+	for i, value := range values {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", d.labels[i], escape(value)))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extra[i], escape(extra[i+1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a metric whose value only increases, e.g. number of requests. A
+// counter has a separate value for each combination of label values.
+type Counter struct {
+	desc
+	mutex  sync.Mutex
+	values map[string]*counterValue // By key of label values.
+}
+
+// counterValue is a counter's value for a combination of label values.
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// Inc increments the counter's value for given label values by 1.
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add adds v, which must not be negative, to the counter's value for given
+// label values.
+func (c *Counter) Add(v float64, labels ...string) {
+	key := c.key(labels)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	value, ok := c.values[key]
+	if !ok {
+		value = &counterValue{labels: append([]string(nil), labels...)}
+		c.values[key] = value
+	}
+	value.value += v
+}
+
+// write writes the counter in text format, ordered by label values.
+func (c *Counter) write(w io.Writer) {
+	c.header(w, "counter")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(value.labels), formatFloat(value.value))
+	}
+}
+
+// Histogram is a metric that counts observed values in buckets, e.g. durations
+// of requests. A histogram has separate buckets for each combination of label
+// values.
+type Histogram struct {
+	desc
+	buckets []float64 // Upper bounds of buckets.
+
+	mutex  sync.Mutex
+	values map[string]*histogramValue // By key of label values.
+}
+
+// histogramValue is a histogram's buckets for a combination of label values.
+type histogramValue struct {
+	labels []string
+	counts []uint64 // Count of observations in each bucket, not cumulative.
+	count  uint64   // Count of all observations.
+	sum    float64  // Sum of all observations.
+}
+
+// Observe adds a value to the histogram's buckets for given label values.
+func (h *Histogram) Observe(v float64, labels ...string) {
+	key := h.key(labels)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	value, ok := h.values[key]
+	if !ok {
+		value = &histogramValue{
+			labels: append([]string(nil), labels...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = value
+	}
+
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		value.counts[i]++
+	}
+	value.count++
+	value.sum += v
+}
+
+// write writes the histogram in text format, ordered by label values.
+func (h *Histogram) write(w io.Writer) {
+	h.header(w, "histogram")
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := h.values[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(value.labels, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(value.labels, "le", "+Inf"), value.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(value.labels), formatFloat(value.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(value.labels), value.count)
+	}
+}
+
+// gaugeFunc is a gauge whose value is computed when it's written.
+type gaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// write writes the gauge in text format.
+func (g *gaugeFunc) write(w io.Writer) {
+	g.header(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+// escape escapes a label value.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats a sample value.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
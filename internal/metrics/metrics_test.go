@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestWrite tests writing metrics in text format.
+func TestWrite(t *testing.T) {
+	registry := NewRegistry()
+
+	counter := registry.Counter("test_total", "Number of tests.", "name")
+	counter.Inc("b")
+	counter.Add(2.5, "a")
+	counter.Inc("b")
+	counter.Inc("quote\"d")
+
+	histogram := registry.Histogram("test_seconds", "Durations\nof tests.", []float64{0.1, 1}, "name")
+	histogram.Observe(0.05, "a")
+	histogram.Observe(0.1, "a")
+	histogram.Observe(0.5, "a")
+	histogram.Observe(5, "a")
+
+	registry.GaugeFunc("test_gauge", "A gauge.", func() float64 { return 3 })
+
+	var buffer bytes.Buffer
+	if err := registry.Write(&buffer); err != nil {
+		t.Fatalf("failed to write metrics: %s", err.Error())
+	}
+
+	expected := strings.Join([]string{
+		"# HELP test_total Number of tests.",
+		"# TYPE test_total counter",
+		`test_total{name="a"} 2.5`,
+		`test_total{name="b"} 2`,
+		`test_total{name="quote\"d"} 1`,
+		`# HELP test_seconds Durations\nof tests.`,
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{name="a",le="0.1"} 2`,
+		`test_seconds_bucket{name="a",le="1"} 3`,
+		`test_seconds_bucket{name="a",le="+Inf"} 4`,
+		`test_seconds_sum{name="a"} 5.65`,
+		`test_seconds_count{name="a"} 4`,
+		"# HELP test_gauge A gauge.",
+		"# TYPE test_gauge gauge",
+		"test_gauge 3",
+		"",
+	}, "\n")
+	if buffer.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buffer.String())
+	}
+}
+
+// TestLabelCount tests that using the wrong number of label values panics.
+func TestLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+
+	NewRegistry().Counter("test_total", "Number of tests.", "name").Inc()
+}
+
+// TestMiddleware tests recording requests by route.
+func TestMiddleware(t *testing.T) {
+	registry := NewRegistry()
+	httpMetrics := NewHTTP(registry, "test")
+
+	router := mux.NewRouter()
+	router.Use(httpMetrics.Middleware)
+	router.HandleFunc("/book/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "0" {
+			http.Error(w, "Not found.", http.StatusNotFound)
+		}
+	}).Methods("GET")
+	router.Handle("/metrics", registry).Methods("GET")
+
+	for _, url := range []string{"/book/1", "/book/2", "/book/0"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("incorrect content type: %s", recorder.Header().Get("Content-Type"))
+	}
+
+	for _, line := range []string{
+		`test_requests_total{route="/book/{id}",method="GET",status="200"} 2`,
+		`test_requests_total{route="/book/{id}",method="GET",status="404"} 1`,
+		`test_request_duration_seconds_count{route="/book/{id}",method="GET"} 3`,
+	} {
+		if !strings.Contains(recorder.Body.String(), line+"\n") {
+			t.Errorf("expected metrics to contain: %s, got:\n%s", line, recorder.Body.String())
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package testhelper
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Certificates holds paths of PEM files of a certificate authority, and of
+// server and client certificates signed by it, to be used in testing. Server
+// certificates are valid for localhost and 127.0.0.1.
+type Certificates struct {
+	CAFile         string
+	ServerCertFile string
+	ServerKeyFile  string
+	ClientCertFile string
+	ClientKeyFile  string
+
+	ca    *x509.Certificate
+	caKey *ecdsa.PrivateKey
+}
+
+// NewCertificates generates a certificate authority, and server and client
+// certificates in a temporary directory. It also returns a function that
+// removes the directory, and should be defered immediately after return.
+func NewCertificates(tb testing.TB) (*Certificates, func()) {
+	tb.Helper()
+	dir, err := ioutil.TempDir("", "bfr-certs")
+	if err != nil {
+		tb.Fatalf("failed to create a temporary directory: %s", err.Error())
+	}
+
+	certs := &Certificates{
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		ServerCertFile: filepath.Join(dir, "server.pem"),
+		ServerKeyFile:  filepath.Join(dir, "server-key.pem"),
+		ClientCertFile: filepath.Join(dir, "client.pem"),
+		ClientKeyFile:  filepath.Join(dir, "client-key.pem"),
+	}
+
+	certs.caKey = newKey(tb)
+	template := newTemplate(tb, "bfr test CA")
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &certs.caKey.PublicKey, certs.caKey)
+	if err != nil {
+		tb.Fatalf("failed to create a CA certificate: %s", err.Error())
+	}
+	certs.ca, err = x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatalf("failed to parse the CA certificate: %s", err.Error())
+	}
+	writePEM(tb, certs.CAFile, "CERTIFICATE", der)
+
+	certs.RenewServer(tb, "localhost")
+	certs.sign(tb, "client", x509.ExtKeyUsageClientAuth, certs.ClientCertFile, certs.ClientKeyFile)
+
+	return certs, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// RenewServer replaces the server's certificate and key with a new certificate
+// with given common name.
+func (c *Certificates) RenewServer(tb testing.TB, commonName string) {
+	tb.Helper()
+	c.sign(tb, commonName, x509.ExtKeyUsageServerAuth, c.ServerCertFile, c.ServerKeyFile)
+}
+
+// sign writes a certificate with given common name and usage, signed by the
+// certificate authority, and its key to given files.
+func (c *Certificates) sign(tb testing.TB, commonName string, usage x509.ExtKeyUsage, certFile, keyFile string) {
+	tb.Helper()
+	key := newKey(tb)
+	template := newTemplate(tb, commonName)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	if usage == x509.ExtKeyUsageServerAuth {
+		template.DNSNames = []string{"localhost"}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, c.ca, &key.PublicKey, c.caKey)
+	if err != nil {
+		tb.Fatalf("failed to create a certificate: %s", err.Error())
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		tb.Fatalf("failed to encode a key: %s", err.Error())
+	}
+
+	writePEM(tb, certFile, "CERTIFICATE", der)
+	writePEM(tb, keyFile, "EC PRIVATE KEY", keyDER)
+}
+
+// newKey returns a new private key.
+func newKey(tb testing.TB) *ecdsa.PrivateKey {
+	tb.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatalf("failed to generate a key: %s", err.Error())
+	}
+	return key
+}
+
+// newTemplate returns a template of a certificate with given common name, valid
+// for a day, and a random serial number.
+func newTemplate(tb testing.TB, commonName string) *x509.Certificate {
+	tb.Helper()
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		tb.Fatalf("failed to generate a serial number: %s", err.Error())
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+}
+
+// writePEM writes a PEM block of given type to a file.
+func writePEM(tb testing.TB, file, blockType string, der []byte) {
+	tb.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		tb.Fatalf("failed to write %s: %s", file, err.Error())
+	}
+}
@@ -2,9 +2,12 @@
 package testhelper
 
 import (
+	"bufio"
 	"database/sql"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3" // Used for sql package.
@@ -39,3 +42,55 @@ func SearchIn(t *testing.T) (*sql.DB, string, func()) {
 
 	return datastore, config.BookTable, deferFn
 }
+
+// Synthetic returns a datastore of given number of generated books, and a table
+// name to use for constructing a SearchIn to be used in benchmarks. It also
+// returns a function that frees resources, and should be defered immediately
+// after return.
+func Synthetic(tb testing.TB, books int) (*sql.DB, string, func()) {
+	tb.Helper()
+	dir, err := ioutil.TempDir("", "bfr")
+	if err != nil {
+		tb.Fatalf("failed to create directory: %s", err.Error())
+	}
+
+	dataset, err := os.Create(filepath.Join(dir, "books.csv"))
+	if err != nil {
+		tb.Fatalf("failed to create dataset: %s", err.Error())
+	}
+
+	w := bufio.NewWriter(dataset)
+	for i := 1; i <= books; i++ {
+		fmt.Fprintf(
+			w,
+			"%d,Book %d (Series %d  #%d),Author %d,%.2f,%010d,978%010d,eng,%d,%d,%d\n",
+			i, i, i/10, i%10, i%1000, 1+float64(i%400)/100, i, i, 100+i%900, i%100000, i%1000,
+		)
+	}
+	if err := w.Flush(); err != nil {
+		tb.Fatalf("failed to write dataset: %s", err.Error())
+	}
+	dataset.Close()
+
+	config := &datastore.Config{
+		Driver:    "sqlite3",
+		Dir:       dir + "/",
+		Datastore: "synthetic.db",
+		BookTable: "books",
+	}
+	if err := datastore.New(dataset.Name(), config, true); err != nil {
+		tb.Fatalf("couldn't load datastore: %s.", err.Error())
+	}
+
+	db, err := datastore.Open(config)
+	if err != nil {
+		tb.Fatalf("failed to open database: %s", err.Error())
+	}
+
+	deferFn := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+
+	return db, config.BookTable, deferFn
+}
@@ -0,0 +1,56 @@
+// Package titles contains functions that extract information encoded in
+// books' titles. It is shared by the datastore's importer, and by functions
+// that modify books.
+package titles
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// seriesPattern matches a series' name and volume number(s) at the end of a
+// title, e.g. "(Harry Potter  #6)", "(Discworld, #5)" or "(Dune #1-3)".
+var seriesPattern = regexp.MustCompile(`\(([^()#]+?)[\s,]*#\s*(\d+(?:\.\d+)?(?:\s*-\s*\d+(?:\.\d+)?)?)\)\s*$`)
+
+// Series returns the name of the series a book belongs to, and the book's
+// volume number(s) in the series, e.g. "6" or "1-5". Both are empty if the
+// title doesn't specify a series.
+func Series(title string) (name, index string) {
+	match := seriesPattern.FindStringSubmatch(title)
+	if match == nil {
+		return "", ""
+	}
+
+	return strings.Join(strings.Fields(match[1]), " "), strings.Join(strings.Fields(match[2]), "")
+}
+
+// trailingParentheses matches parenthesized parts at the end of a title, which
+// usually name a series or an edition, e.g. "(Harry Potter  #6)".
+var trailingParentheses = regexp.MustCompile(`(\s*\([^()]*\))+\s*$`)
+
+// WorkKey returns a key that is shared by all editions of the same work. The
+// key consists of the title without series, edition or subtitle, and the first
+// of the authors (which are separated by a '-'), both normalized.
+func WorkKey(title, authors string) string {
+	title = trailingParentheses.ReplaceAllString(title, "")
+	if i := strings.Index(title, ":"); i > 0 {
+		title = title[:i]
+	}
+
+	return fmt.Sprintf("%s|%s", normalize(title), normalize(strings.Split(authors, "-")[0]))
+}
+
+// normalize returns a lower case version of s without punctuation, and with
+// words separated by single spaces.
+func normalize(s string) string {
+	s = strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.IsSpace(c) {
+			return unicode.ToLower(c)
+		}
+		return -1
+	}, s)
+
+	return strings.Join(strings.Fields(s), " ")
+}
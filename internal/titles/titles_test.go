@@ -0,0 +1,66 @@
+package titles
+
+import (
+	"testing"
+)
+
+// Test extracting series from titles.
+func TestSeries(t *testing.T) {
+	for title, series := range map[string][2]string{
+		"Harry Potter and the Half-Blood Prince (Harry Potter  #6)":                                         {"Harry Potter", "6"},
+		"Harry Potter Boxed Set  Books 1-5 (Harry Potter  #1-5)":                                            {"Harry Potter", "1-5"},
+		"The Ultimate Hitchhiker's Guide (Hitchhiker's Guide to the Galaxy #1-5)":                           {"Hitchhiker's Guide to the Galaxy", "1-5"},
+		"Guards! Guards! (Discworld, #8)":                                                                   {"Discworld", "8"},
+		"The Gunslinger (The Dark Tower  #1)":                                                               {"The Dark Tower", "1"},
+		"Dreams of Gods & Monsters (Daughter of Smoke & Bone #3.5)":                                         {"Daughter of Smoke & Bone", "3.5"},
+		"The Ultimate Hitchhiker's Guide to the Galaxy":                                                     {"", ""},
+		"Bill Bryson's African Diary":                                                                       {"", ""},
+		"The Complete Stories (Everyman's Library)":                                                         {"", ""},
+		"\"Unauthorized Harry Potter Book Seven News: \"\"Half-Blood Prince\"\" Analysis and Speculation\"": {"", ""},
+		"Harry Potter and the Order of the Phoenix (Harry Potter  #5) (Harry Potter Collection)":            {"", ""},
+	} {
+		name, index := Series(title)
+		if name != series[0] || index != series[1] {
+			t.Errorf("incorrect series of \"%s\", expected: %q, got: %q", title, series, [2]string{name, index})
+		}
+	}
+}
+
+// Test generating keys of works.
+func TestWorkKey(t *testing.T) {
+	for _, test := range []struct {
+		title   string
+		authors string
+		key     string
+	}{
+		{
+			title:   "The Hitchhiker's Guide to the Galaxy (Hitchhiker's Guide to the Galaxy  #1)",
+			authors: "Douglas Adams-Stephen Fry",
+			key:     "the hitchhikers guide to the galaxy|douglas adams",
+		},
+		{
+			title:   "The Ultimate Hitchhiker's Guide: Five Complete Novels and One Story (Hitchhiker's Guide to the Galaxy  #1-5)",
+			authors: "Douglas Adams",
+			key:     "the ultimate hitchhikers guide|douglas adams",
+		},
+		{
+			title:   "Pride and Prejudice (Penguin Classics) (Collector's Edition)",
+			authors: "Jane Austen-Tony Tanner",
+			key:     "pride and prejudice|jane austen",
+		},
+		{
+			title:   "Harry Potter and the Half-Blood Prince (Harry Potter  #6)",
+			authors: "J.K. Rowling-Mary GrandPré",
+			key:     "harry potter and the halfblood prince|jk rowling",
+		},
+		{
+			title:   ": A Story",
+			authors: "",
+			key:     "a story|",
+		},
+	} {
+		if key := WorkKey(test.title, test.authors); key != test.key {
+			t.Errorf("incorrect key of \"%s\", expected: %q, got: %q", test.title, test.key, key)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package books
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxBatch is the maximum number of IDs and ISBNs that can be looked up at once
+// using BatchGet.
+const MaxBatch = 1000
+
+// ErrBatchTooLarge is returned when more than MaxBatch IDs and ISBNs are looked
+// up at once.
+var ErrBatchTooLarge = fmt.Errorf("can't look up more than %d books at once", MaxBatch)
+
+// Batch holds books found by BatchGet, and IDs and ISBNs that weren't found.
+type Batch struct {
+	Books        []*Book  // Books found, each listed once, in order of the first ID or ISBN that matched it.
+	MissingIDs   []int    // IDs that no book has.
+	MissingISBNs []string // ISBNs that no book has.
+}
+
+// BatchGet looks up books that have any of given IDs, or any of given ISBNs, 10 or
+// 13 digits, in table and database specified in given SearchIn using a single query.
+// Returns ErrBatchTooLarge if more than MaxBatch IDs and ISBNs are given.
+func BatchGet(searchIn *SearchIn, ids []int, isbns []string) (*Batch, error) {
+	defer searchIn.observe("batch", time.Now())
+
+	if len(ids)+len(isbns) > MaxBatch {
+		return nil, ErrBatchTooLarge
+	}
+
+	batch := &Batch{
+		Books:        make([]*Book, 0),
+		MissingIDs:   make([]int, 0),
+		MissingISBNs: make([]string, 0),
+	}
+	if len(ids)+len(isbns) == 0 {
+		return batch, nil
+	}
+
+	conditions := make([]string, 0)
+	parameters := make(queryParameters, 0, len(ids)+2*len(isbns))
+	if len(ids) != 0 {
+		conditions = append(conditions, fmt.Sprintf("id in (%s)", placeholders(len(ids))))
+		for _, id := range ids {
+			parameters = append(parameters, id)
+		}
+	}
+	if len(isbns) != 0 {
+		conditions = append(
+			conditions,
+			fmt.Sprintf("isbn in (%s) or isbn13 in (%s)", placeholders(len(isbns)), placeholders(len(isbns))),
+		)
+		for i := 0; i < 2; i++ {
+			for _, isbn := range isbns {
+				parameters = append(parameters, isbn)
+			}
+		}
+	}
+
+	query := fmt.Sprintf(
+		"select %s from %s where %s;",
+		bookColumns(searchIn),
+		searchIn.BookTable,
+		strings.Join(conditions, " or "),
+	)
+	rows, err := searchIn.Datastore.Query(query, parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*Book)
+	byISBN := make(map[string]*Book)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		byID[book.ID] = book
+		byISBN[book.ISBN] = book
+		byISBN[book.ISBN13] = book
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	listed := make(map[int]bool)
+	list := func(book *Book) {
+		if !listed[book.ID] {
+			listed[book.ID] = true
+			batch.Books = append(batch.Books, book)
+		}
+	}
+
+	for _, id := range ids {
+		if book, ok := byID[id]; ok {
+			list(book)
+		} else {
+			batch.MissingIDs = append(batch.MissingIDs, id)
+		}
+	}
+	for _, isbn := range isbns {
+		if book, ok := byISBN[isbn]; ok && isbn != "" {
+			list(book)
+		} else {
+			batch.MissingISBNs = append(batch.MissingISBNs, isbn)
+		}
+	}
+
+	return batch, nil
+}
+
+// placeholders returns a list of n comma separated SQL parameter placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
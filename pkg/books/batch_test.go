@@ -0,0 +1,85 @@
+package books
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// Test looking up books by IDs and ISBNs.
+func TestBatchGet(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	for i, test := range []struct {
+		ids          []int
+		isbns        []string
+		books        []int // IDs of books found, in order.
+		missingIDs   []int
+		missingISBNs []string
+	}{
+		{
+			ids:          nil,
+			isbns:        nil,
+			books:        []int{},
+			missingIDs:   []int{},
+			missingISBNs: []string{},
+		},
+		{
+			ids:          []int{3, 1, 40, 3588},
+			isbns:        nil,
+			books:        []int{3, 1, 3588},
+			missingIDs:   []int{40},
+			missingISBNs: []string{},
+		},
+		{
+			ids:          nil,
+			isbns:        []string{"043965548X", "9780767915069", "0000000000", ""},
+			books:        []int{5, 22},
+			missingIDs:   []int{},
+			missingISBNs: []string{"0000000000", ""},
+		},
+		{
+			ids:          []int{14, 2, -1},
+			isbns:        []string{"9780439358071", "517226952", "9999999999999"},
+			books:        []int{14, 2, 12},
+			missingIDs:   []int{-1},
+			missingISBNs: []string{"9999999999999"},
+		},
+	} {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			batch, err := BatchGet(searchIn, test.ids, test.isbns)
+			if err != nil {
+				t.Fatalf("lookup failed: %s", err.Error())
+			}
+
+			ids := make([]int, len(batch.Books))
+			for i, book := range batch.Books {
+				ids[i] = book.ID
+			}
+			if !reflect.DeepEqual(ids, test.books) {
+				t.Errorf("expected books: %v, got: %v", test.books, ids)
+			}
+			if !reflect.DeepEqual(batch.MissingIDs, test.missingIDs) {
+				t.Errorf("expected missing IDs: %v, got: %v", test.missingIDs, batch.MissingIDs)
+			}
+			if !reflect.DeepEqual(batch.MissingISBNs, test.missingISBNs) {
+				t.Errorf("expected missing ISBNs: %v, got: %v", test.missingISBNs, batch.MissingISBNs)
+			}
+		})
+	}
+
+	if _, err := BatchGet(searchIn, make([]int, MaxBatch), []string{"1"}); err != ErrBatchTooLarge {
+		t.Errorf("expected: %v, got: %v", ErrBatchTooLarge, err)
+	}
+	if _, err := BatchGet(searchIn, make([]int, MaxBatch/2), make([]string, MaxBatch/2)); err != nil {
+		t.Errorf("expected a batch of %d to succeed, got: %v", MaxBatch, err)
+	}
+}
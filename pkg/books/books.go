@@ -5,13 +5,20 @@ package books
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3" // Used with sql package.
 )
 
 const titleSearch = true // Used as a parameter when func query is called.
 
+// ErrNotFound is returned when no book has a given ID.
+var ErrNotFound = errors.New("failed to find id")
+
 // Book represents a searchable book object.
 type Book struct {
 	ID            int     // A different number for each book.
@@ -24,6 +31,30 @@ type Book struct {
 	Pages         int     // Number of book's pages.
 	RatingsCount  int     // Number of ratings (out of 5.)
 	ReviewsCount  int     // Number of text reviews.
+	Series        string  // Name of the series the book belongs to, if any.
+	SeriesIndex   string  // Book's volume number(s) in its series, e.g. "6" or "1-5".
+	WorkID        int     // ID shared by all editions of the same work.
+
+	WeightedRating float32 // Average rating weighted by number of ratings (out of 5.)
+}
+
+// AuthorNames returns the names of book's authors, which are separated by a
+// '-' in the dataset.
+func (book *Book) AuthorNames() []string {
+	names := make([]string, 0)
+	for _, author := range strings.Split(book.Authors, "-") {
+		if author = strings.TrimSpace(author); author != "" {
+			names = append(names, author)
+		}
+	}
+	return names
+}
+
+// Work represents a book that is one of possibly many editions of the same work,
+// and holds the number of editions.
+type Work struct {
+	Book
+	Editions int // Number of editions of the work.
 }
 
 // SearchIn contains the database and table's name to search in. Table's
@@ -31,14 +62,31 @@ type Book struct {
 type SearchIn struct {
 	Datastore *sql.DB // Datastore to search in.
 	BookTable string  // Table to search in.
+
+	MetadataTable string // Table containing the datastore's metadata, see datastore.DefaultMetadataTable.
+
+	// Observe, if not nil, is called with the duration of each search and the
+	// shape of its query, e.g. "byID" or "search". Used to collect metrics.
+	Observe func(shape string, duration time.Duration)
+}
+
+// observe reports the duration since start of a query with given shape to
+// searchIn's observer, if any.
+func (searchIn *SearchIn) observe(shape string, start time.Time) {
+	if searchIn.Observe != nil {
+		searchIn.Observe(shape, time.Since(start))
+	}
 }
 
 // SearchBy is a set of parameters to use when searching for books in
 // a datastore.
 // Not all fields have to be specifed, a search can be performed using
 // only a sub-set of the fields. To ignore a string field when searching,
-// leave it empty, to ignore a number set it to < 0.
+// leave it empty, to ignore a number set it to < 0. WeightedRatingFloor is
+// ignored if it's 0 or less, so a zero SearchBy doesn't filter by it.
 // For floor/ceil values floor is exclusive, ceil is inclusive.
+// Results are sorted by the field named in SortBy, or are returned in
+// datastore's order if SortBy is empty.
 type SearchBy struct {
 	TitleHas string // A sub-string that must exist in the title.
 
@@ -47,69 +95,153 @@ type SearchBy struct {
 
 	ISBN   string // 10 digit ISBN.
 	ISBN13 string // 13 digit ISBN.
+	Series string // Name of the series the book belongs to, ignoring case.
+
+	RatingCeil          float32 // Rating must be less than or equal.
+	RatingFloor         float32 // Rating must be higher than.
+	WeightedRatingFloor float32 // Weighted rating must be higher than. Ignored if <= 0.
+	PagesCeil           int     // Number of pages must be less than or equal.
+	PagesFloor          int     // Number of pages must be higher than.
+	RatingsCountCeil    int     // Number of ratings must be less than or equal.
+	RatingsCountFloor   int     // Number of ratings must be higher than.
+	ReviewsCountCeil    int     // Number of reviews must be less than or equal.
+	ReviewsCountFloor   int     // Number of reviews must be higher than.
 
-	RatingCeil        float32 // Rating must be less than or equal.
-	RatingFloor       float32 // Rating must be higher than.
-	PagesCeil         int     // Number of pages must be less than or equal.
-	PagesFloor        int     // Number of pages must be higher than.
-	RatingsCountCeil  int     // Number of ratings must be less than or equal.
-	RatingsCountFloor int     // Number of ratings must be higher than.
-	ReviewsCountCeil  int     // Number of reviews must be less than or equal.
-	ReviewsCountFloor int     // Number of reviews must be higher than.
+	SortBy     string // Name of a Book field to sort by, e.g. "WeightedRating".
+	Descending bool   // Sort in descending order. Ignored if SortBy is empty.
+}
+
+// NewSearchBy returns a SearchBy with all parameters set to be ignored, which
+// matches all books, to be filled with parameters to search by.
+func NewSearchBy() *SearchBy {
+	return &SearchBy{
+		RatingCeil:        -1,
+		RatingFloor:       -1,
+		PagesCeil:         -1,
+		PagesFloor:        -1,
+		RatingsCountCeil:  -1,
+		RatingsCountFloor: -1,
+		ReviewsCountCeil:  -1,
+		ReviewsCountFloor: -1,
+	}
+}
+
+// Validate checks that SearchBy's parameters are usable in a search, and
+// returns an error if not.
+func (by *SearchBy) Validate() error {
+	if _, ok := sortColumns[by.SortBy]; !ok && by.SortBy != "" {
+		return fmt.Errorf("can't sort by \"%s\"", by.SortBy)
+	}
+
+	return nil
+}
+
+// SortFields returns the names of Book fields that search results can be
+// sorted by, in alphabetical order.
+func SortFields() []string {
+	fields := make([]string, 0, len(sortColumns))
+	for field := range sortColumns {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+	return fields
 }
 
 // SearchByID searchs for an ID in table and database specified in SearchIn, and
-// returns a Book, if any is found, and an error otherwise.
+// returns a Book, if any is found, and ErrNotFound otherwise.
 func SearchByID(searchIn *SearchIn, id int) (*Book, error) {
-	search := fmt.Sprintf("select * from %s where id = ?;", searchIn.BookTable)
+	defer searchIn.observe("byID", time.Now())
+
+	search := fmt.Sprintf("select %s from %s where id = ?;", bookColumns(searchIn), searchIn.BookTable)
 	rows, err := searchIn.Datastore.Query(search, id)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	if rows.Next() {
-		book := new(Book)
-		rows.Scan(
-			&book.ID,
-			&book.Title,
-			&book.Authors,
-			&book.AverageRating,
-			&book.ISBN,
-			&book.ISBN13,
-			&book.LanguageCode,
-			&book.Pages,
-			&book.RatingsCount,
-			&book.ReviewsCount,
-		)
-		return book, nil
+		return scanBook(rows)
 	}
-	return nil, fmt.Errorf("failed to find id")
+	return nil, ErrNotFound
 }
 
 // SearchByTitle searchs in table and database specified in given SearchIn, and returns
 // a list of books that match the given title.
 func SearchByTitle(searchIn *SearchIn, title string) ([]*Book, error) {
-	search := fmt.Sprintf("select * from %s where title = ?;", searchIn.BookTable)
+	defer searchIn.observe("byTitle", time.Now())
+
+	search := fmt.Sprintf("select %s from %s where title = ?;", bookColumns(searchIn), searchIn.BookTable)
 	rows, err := searchIn.Datastore.Query(search, title)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	books := make([]*Book, 0)
 	for rows.Next() {
-		book := new(Book)
-		rows.Scan(
-			&book.ID,
-			&book.Title,
-			&book.Authors,
-			&book.AverageRating,
-			&book.ISBN,
-			&book.ISBN13,
-			&book.LanguageCode,
-			&book.Pages,
-			&book.RatingsCount,
-			&book.ReviewsCount,
-		)
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		books = append(books, book)
+	}
+
+	return books, nil
+}
+
+// SearchBySeries searchs in table and database specified in given SearchIn, and returns
+// a list of books in the given series (ignoring case), in reading order.
+func SearchBySeries(searchIn *SearchIn, series string) ([]*Book, error) {
+	defer searchIn.observe("bySeries", time.Now())
+
+	search := fmt.Sprintf(
+		"select %s from %s where series = ? collate nocase order by cast(seriesIndex as real), seriesIndex, id;",
+		bookColumns(searchIn),
+		searchIn.BookTable,
+	)
+	rows, err := searchIn.Datastore.Query(search, series)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := make([]*Book, 0)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		books = append(books, book)
+	}
+
+	return books, nil
+}
+
+// SearchEditions searchs in table and database specified in given SearchIn, and returns
+// a list of all editions of the work with the given ID, most rated first.
+func SearchEditions(searchIn *SearchIn, workID int) ([]*Book, error) {
+	defer searchIn.observe("editions", time.Now())
+
+	search := fmt.Sprintf(
+		"select %s from %s where workID = ? order by ratingsCount desc, id;",
+		bookColumns(searchIn),
+		searchIn.BookTable,
+	)
+	rows, err := searchIn.Datastore.Query(search, workID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := make([]*Book, 0)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
 
 		books = append(books, book)
 	}
@@ -120,42 +252,117 @@ func SearchByTitle(searchIn *SearchIn, title string) ([]*Book, error) {
 // Search searchs in table and database specified in given SearchIn, and returns
 // a list of books that match the parameters given in SearchBy.
 func Search(searchIn *SearchIn, searchBy *SearchBy) ([]*Book, error) {
+	defer searchIn.observe("search", time.Now())
+
+	books := make([]*Book, 0)
+	err := searchEach(searchIn, searchBy, func(book *Book) error {
+		books = append(books, book)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// SearchEach works similar to Search but calls fn with each matching book as it's
+// read from the datastore, instead of returning a list, so that results don't have
+// to be held in memory. Stops and returns the error if fn returns one.
+func SearchEach(searchIn *SearchIn, searchBy *SearchBy, fn func(*Book) error) error {
+	defer searchIn.observe("stream", time.Now())
+	return searchEach(searchIn, searchBy, fn)
+}
+
+// searchEach implements SearchEach without observing the query.
+func searchEach(searchIn *SearchIn, searchBy *SearchBy, fn func(*Book) error) error {
+	if err := searchBy.Validate(); err != nil {
+		return err
+	}
+
 	query, parameters := query(searchIn, searchBy, !titleSearch)
 	rows, err := searchIn.Datastore.Query(query, parameters...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(book); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// SearchWorks works similar to Search but groups editions of the same work, and
+// returns one representative edition, the most rated one, per work along with the
+// number of editions that match the parameters given in SearchBy.
+func SearchWorks(searchIn *SearchIn, searchBy *SearchBy) ([]*Work, error) {
+	defer searchIn.observe("works", time.Now())
+
+	if err := searchBy.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, parameters := worksQuery(searchIn, searchBy)
+	rows, err := searchIn.Datastore.Query(query, parameters...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	books := make([]*Book, 0)
+	works := make([]*Work, 0)
 	for rows.Next() {
-		book := new(Book)
-		rows.Scan(
-			&book.ID,
-			&book.Title,
-			&book.Authors,
-			&book.AverageRating,
-			&book.ISBN,
-			&book.ISBN13,
-			&book.LanguageCode,
-			&book.Pages,
-			&book.RatingsCount,
-			&book.ReviewsCount,
+		work := new(Work)
+		err := rows.Scan(
+			&work.ID,
+			&work.Title,
+			&work.Authors,
+			&work.AverageRating,
+			&work.ISBN,
+			&work.ISBN13,
+			&work.LanguageCode,
+			&work.Pages,
+			&work.RatingsCount,
+			&work.ReviewsCount,
+			&work.Series,
+			&work.SeriesIndex,
+			&work.WorkID,
+			&work.WeightedRating,
+			&work.Editions,
 		)
+		if err != nil {
+			return nil, err
+		}
 
-		books = append(books, book)
+		works = append(works, work)
 	}
 
-	return books, nil
+	return works, nil
 }
 
 // SearchForTitles works similar to Search but returns a list of titles (strings)
 // instead of books. Titles can be then used to search for a specific book.
 func SearchForTitles(searchIn *SearchIn, searchBy *SearchBy) ([]string, error) {
+	defer searchIn.observe("titles", time.Now())
+
+	if err := searchBy.Validate(); err != nil {
+		return nil, err
+	}
+
 	query, parameters := query(searchIn, searchBy, titleSearch)
 	rows, err := searchIn.Datastore.Query(query, parameters...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	titles := make([]string, 0)
 	for rows.Next() {
@@ -165,3 +372,30 @@ func SearchForTitles(searchIn *SearchIn, searchBy *SearchBy) ([]string, error) {
 
 	return titles, nil
 }
+
+// scanBook scans the current row of rows into a new Book. Rows must be
+// selected using bookColumns.
+func scanBook(rows *sql.Rows) (*Book, error) {
+	book := new(Book)
+	err := rows.Scan(
+		&book.ID,
+		&book.Title,
+		&book.Authors,
+		&book.AverageRating,
+		&book.ISBN,
+		&book.ISBN13,
+		&book.LanguageCode,
+		&book.Pages,
+		&book.RatingsCount,
+		&book.ReviewsCount,
+		&book.Series,
+		&book.SeriesIndex,
+		&book.WorkID,
+		&book.WeightedRating,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
@@ -1,8 +1,12 @@
 package books
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"runtime"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
@@ -20,28 +24,36 @@ func TestSearchByID(t *testing.T) {
 
 	for id, book := range map[int]*Book{
 		4: &Book{
-			ID:            4,
-			Title:         "Harry Potter and the Chamber of Secrets (Harry Potter  #2)",
-			Authors:       "J.K. Rowling",
-			AverageRating: 4.41,
-			ISBN:          "439554896",
-			ISBN13:        "9780439554893",
-			LanguageCode:  "eng",
-			Pages:         352,
-			RatingsCount:  6267,
-			ReviewsCount:  272,
+			ID:             4,
+			Title:          "Harry Potter and the Chamber of Secrets (Harry Potter  #2)",
+			Authors:        "J.K. Rowling",
+			AverageRating:  4.41,
+			ISBN:           "439554896",
+			ISBN13:         "9780439554893",
+			LanguageCode:   "eng",
+			Pages:          352,
+			RatingsCount:   6267,
+			ReviewsCount:   272,
+			Series:         "Harry Potter",
+			SeriesIndex:    "2",
+			WorkID:         4,
+			WeightedRating: 4.3901553,
 		},
 		14: &Book{
-			ID:            14,
-			Title:         "The Hitchhiker's Guide to the Galaxy (Hitchhiker's Guide to the Galaxy  #1)",
-			Authors:       "Douglas Adams",
-			AverageRating: 4.22,
-			ISBN:          "1400052920",
-			ISBN13:        "9781400052929",
-			LanguageCode:  "eng",
-			Pages:         215,
-			RatingsCount:  4416,
-			ReviewsCount:  408,
+			ID:             14,
+			Title:          "The Hitchhiker's Guide to the Galaxy (Hitchhiker's Guide to the Galaxy  #1)",
+			Authors:        "Douglas Adams",
+			AverageRating:  4.22,
+			ISBN:           "1400052920",
+			ISBN13:         "9781400052929",
+			LanguageCode:   "eng",
+			Pages:          215,
+			RatingsCount:   4416,
+			ReviewsCount:   408,
+			Series:         "Hitchhiker's Guide to the Galaxy",
+			SeriesIndex:    "1",
+			WorkID:         14,
+			WeightedRating: 4.2284546,
 		},
 		30: nil,
 	} {
@@ -77,29 +89,35 @@ func TestSearchByTitle(t *testing.T) {
 
 	for name, book := range map[string]*Book{
 		"Harry Potter and the Chamber of Secrets (Harry Potter  #2)": &Book{
-			ID:            4,
-			Title:         "Harry Potter and the Chamber of Secrets (Harry Potter  #2)",
-			Authors:       "J.K. Rowling",
-			AverageRating: 4.41,
-			ISBN:          "439554896",
-			ISBN13:        "9780439554893",
-			LanguageCode:  "eng",
-			Pages:         352,
-			RatingsCount:  6267,
-			ReviewsCount:  272,
+			ID:             4,
+			Title:          "Harry Potter and the Chamber of Secrets (Harry Potter  #2)",
+			Authors:        "J.K. Rowling",
+			AverageRating:  4.41,
+			ISBN:           "439554896",
+			ISBN13:         "9780439554893",
+			LanguageCode:   "eng",
+			Pages:          352,
+			RatingsCount:   6267,
+			ReviewsCount:   272,
+			Series:         "Harry Potter",
+			SeriesIndex:    "2",
+			WorkID:         4,
+			WeightedRating: 4.3901553,
 		},
 
 		"A Short History of Nearly Everything": &Book{
-			ID:            21,
-			Title:         "A Short History of Nearly Everything",
-			Authors:       "Bill Bryson-William Roberts",
-			AverageRating: 4.2,
-			ISBN:          "076790818X",
-			ISBN13:        "9780767908184",
-			LanguageCode:  "eng",
-			Pages:         544,
-			RatingsCount:  228522,
-			ReviewsCount:  8840,
+			ID:             21,
+			Title:          "A Short History of Nearly Everything",
+			Authors:        "Bill Bryson-William Roberts",
+			AverageRating:  4.2,
+			ISBN:           "076790818X",
+			ISBN13:         "9780767908184",
+			LanguageCode:   "eng",
+			Pages:          544,
+			RatingsCount:   228522,
+			ReviewsCount:   8840,
+			WorkID:         21,
+			WeightedRating: 4.200287,
 		},
 	} {
 		t.Run(
@@ -122,6 +140,162 @@ func TestSearchByTitle(t *testing.T) {
 	}
 }
 
+// Test searching for books in a series.
+func TestSearchBySeries(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	for series, ids := range map[string][]int{
+		"Harry Potter":                     {3, 8, 10, 4, 5, 2, 1},
+		"hitchhiker's guide to the galaxy": {14, 16, 12, 18},
+		"Discworld":                        {},
+	} {
+		t.Run(
+			fmt.Sprintf("series: %s", series),
+			func(*testing.T) {
+				result, err := SearchBySeries(searchIn, series)
+				if err != nil {
+					t.Fatalf("search failed: %s", err.Error())
+				}
+
+				if len(result) != len(ids) {
+					t.Fatalf("expected: %d search results, got: %d", len(ids), len(result))
+				}
+
+				for i, book := range result {
+					if book.ID != ids[i] {
+						t.Errorf("expected id: %d at %d, got: %d", ids[i], i, book.ID)
+					}
+				}
+			},
+		)
+	}
+}
+
+// Test searching for editions of a work.
+func TestSearchEditions(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	for workID, ids := range map[int][]int{
+		14: {14, 16},
+		12: {12, 18},
+		13: {13},
+		16: {},
+	} {
+		t.Run(
+			fmt.Sprintf("work: %d", workID),
+			func(*testing.T) {
+				result, err := SearchEditions(searchIn, workID)
+				if err != nil {
+					t.Fatalf("search failed: %s", err.Error())
+				}
+
+				if len(result) != len(ids) {
+					t.Fatalf("expected: %d search results, got: %d", len(ids), len(result))
+				}
+
+				for i, book := range result {
+					if book.ID != ids[i] || book.WorkID != workID {
+						t.Errorf("expected id: %d of work %d at %d, got: %d of work %d", ids[i], workID, i, book.ID, book.WorkID)
+					}
+				}
+			},
+		)
+	}
+}
+
+// Test searching for works using a SearchBy.
+func TestSearchWorks(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	for i, test := range []struct {
+		searchBy *SearchBy
+		ids      []int
+		editions []int
+	}{
+		{
+			searchBy: &SearchBy{
+				TitleHas:          "",
+				Authors:           []string{"Douglas Adams"},
+				LanguageCode:      nil,
+				ISBN:              "",
+				ISBN13:            "",
+				RatingCeil:        -1,
+				RatingFloor:       -1,
+				PagesCeil:         -1,
+				PagesFloor:        -1,
+				RatingsCountCeil:  -1,
+				RatingsCountFloor: -1,
+				ReviewsCountCeil:  -1,
+				ReviewsCountFloor: -1,
+				SortBy:            "RatingsCount",
+				Descending:        true,
+			},
+			ids:      []int{13, 14, 12},
+			editions: []int{1, 2, 2},
+		},
+		{
+			searchBy: &SearchBy{
+				TitleHas:          "",
+				Authors:           []string{"Douglas Adams"},
+				LanguageCode:      []string{"en-US"},
+				ISBN:              "",
+				ISBN13:            "",
+				RatingCeil:        -1,
+				RatingFloor:       -1,
+				PagesCeil:         -1,
+				PagesFloor:        -1,
+				RatingsCountCeil:  -1,
+				RatingsCountFloor: -1,
+				ReviewsCountCeil:  -1,
+				ReviewsCountFloor: -1,
+			},
+			ids:      []int{18},
+			editions: []int{1},
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("test: %d", i),
+			func(*testing.T) {
+				result, err := SearchWorks(searchIn, test.searchBy)
+				if err != nil {
+					t.Fatalf("search failed: %s", err.Error())
+				}
+
+				if len(result) != len(test.ids) {
+					t.Fatalf("expected: %d search results, got: %d", len(test.ids), len(result))
+				}
+
+				for i, work := range result {
+					if work.ID != test.ids[i] || work.Editions != test.editions[i] {
+						t.Errorf(
+							"expected id: %d with %d editions at %d, got: %d with %d editions",
+							test.ids[i], test.editions[i], i, work.ID, work.Editions,
+						)
+					}
+				}
+			},
+		)
+	}
+}
+
 // Test searching using a SearchBy.
 func TestSearch(t *testing.T) {
 	datastore, bookTable, deferFn := testhelper.SearchIn(t)
@@ -150,16 +324,20 @@ func TestSearch(t *testing.T) {
 			ReviewsCountFloor: -1,
 		}: []*Book{
 			&Book{
-				ID:            4,
-				Title:         "Harry Potter and the Chamber of Secrets (Harry Potter  #2)",
-				Authors:       "J.K. Rowling",
-				AverageRating: 4.41,
-				ISBN:          "439554896",
-				ISBN13:        "9780439554893",
-				LanguageCode:  "eng",
-				Pages:         352,
-				RatingsCount:  6267,
-				ReviewsCount:  272,
+				ID:             4,
+				Title:          "Harry Potter and the Chamber of Secrets (Harry Potter  #2)",
+				Authors:        "J.K. Rowling",
+				AverageRating:  4.41,
+				ISBN:           "439554896",
+				ISBN13:         "9780439554893",
+				LanguageCode:   "eng",
+				Pages:          352,
+				RatingsCount:   6267,
+				ReviewsCount:   272,
+				Series:         "Harry Potter",
+				SeriesIndex:    "2",
+				WorkID:         4,
+				WeightedRating: 4.3901553,
 			},
 		},
 
@@ -179,29 +357,37 @@ func TestSearch(t *testing.T) {
 			ReviewsCountFloor: -1,
 		}: []*Book{
 			&Book{
-				ID:            8,
-				Title:         "Harry Potter Boxed Set  Books 1-5 (Harry Potter  #1-5)",
-				Authors:       "J.K. Rowling-Mary GrandPré",
-				AverageRating: 4.78,
-				ISBN:          "439682584",
-				ISBN13:        "9780439682589",
-				LanguageCode:  "eng",
-				Pages:         2690,
-				RatingsCount:  38872,
-				ReviewsCount:  154,
+				ID:             8,
+				Title:          "Harry Potter Boxed Set  Books 1-5 (Harry Potter  #1-5)",
+				Authors:        "J.K. Rowling-Mary GrandPré",
+				AverageRating:  4.78,
+				ISBN:           "439682584",
+				ISBN13:         "9780439682589",
+				LanguageCode:   "eng",
+				Pages:          2690,
+				RatingsCount:   38872,
+				ReviewsCount:   154,
+				Series:         "Harry Potter",
+				SeriesIndex:    "1-5",
+				WorkID:         8,
+				WeightedRating: 4.7671037,
 			},
 
 			&Book{
-				ID:            10,
-				Title:         "Harry Potter Collection (Harry Potter  #1-6)",
-				Authors:       "J.K. Rowling",
-				AverageRating: 4.73,
-				ISBN:          "439827604",
-				ISBN13:        "9780439827607",
-				LanguageCode:  "eng",
-				Pages:         3342,
-				RatingsCount:  27410,
-				ReviewsCount:  820,
+				ID:             10,
+				Title:          "Harry Potter Collection (Harry Potter  #1-6)",
+				Authors:        "J.K. Rowling",
+				AverageRating:  4.73,
+				ISBN:           "439827604",
+				ISBN13:         "9780439827607",
+				LanguageCode:   "eng",
+				Pages:          3342,
+				RatingsCount:   27410,
+				ReviewsCount:   820,
+				Series:         "Harry Potter",
+				SeriesIndex:    "1-6",
+				WorkID:         10,
+				WeightedRating: 4.7136602,
 			},
 		},
 
@@ -221,29 +407,33 @@ func TestSearch(t *testing.T) {
 			ReviewsCountFloor: 2000,
 		}: []*Book{
 			&Book{
-				ID:            24,
-				Title:         "In a Sunburned Country",
-				Authors:       "Bill Bryson",
-				AverageRating: 4.07,
-				ISBN:          "767903862",
-				ISBN13:        "9780767903868",
-				LanguageCode:  "eng",
-				Pages:         335,
-				RatingsCount:  68213,
-				ReviewsCount:  4077,
+				ID:             24,
+				Title:          "In a Sunburned Country",
+				Authors:        "Bill Bryson",
+				AverageRating:  4.07,
+				ISBN:           "767903862",
+				ISBN13:         "9780767903868",
+				LanguageCode:   "eng",
+				Pages:          335,
+				RatingsCount:   68213,
+				ReviewsCount:   4077,
+				WorkID:         24,
+				WeightedRating: 4.072829,
 			},
 
 			&Book{
-				ID:            25,
-				Title:         "I'm a Stranger Here Myself: Notes on Returning to America After Twenty Years Away",
-				Authors:       "Bill Bryson",
-				AverageRating: 3.9,
-				ISBN:          "076790382X",
-				ISBN13:        "9780767903820",
-				LanguageCode:  "eng",
-				Pages:         304,
-				RatingsCount:  47490,
-				ReviewsCount:  2153,
+				ID:             25,
+				Title:          "I'm a Stranger Here Myself: Notes on Returning to America After Twenty Years Away",
+				Authors:        "Bill Bryson",
+				AverageRating:  3.9,
+				ISBN:           "076790382X",
+				ISBN13:         "9780767903820",
+				LanguageCode:   "eng",
+				Pages:          304,
+				RatingsCount:   47490,
+				ReviewsCount:   2153,
+				WorkID:         25,
+				WeightedRating: 3.9075437,
 			},
 		},
 
@@ -262,6 +452,93 @@ func TestSearch(t *testing.T) {
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
 		}: []*Book{},
+
+		&SearchBy{
+			TitleHas:            "",
+			Authors:             nil,
+			LanguageCode:        nil,
+			ISBN:                "",
+			ISBN13:              "",
+			RatingCeil:          -1,
+			RatingFloor:         -1,
+			WeightedRatingFloor: 4.5,
+			PagesCeil:           -1,
+			PagesFloor:          -1,
+			RatingsCountCeil:    -1,
+			RatingsCountFloor:   -1,
+			ReviewsCountCeil:    -1,
+			ReviewsCountFloor:   -1,
+			SortBy:              "WeightedRating",
+			Descending:          true,
+		}: []*Book{
+			&Book{
+				ID:             8,
+				Title:          "Harry Potter Boxed Set  Books 1-5 (Harry Potter  #1-5)",
+				Authors:        "J.K. Rowling-Mary GrandPré",
+				AverageRating:  4.78,
+				ISBN:           "439682584",
+				ISBN13:         "9780439682589",
+				LanguageCode:   "eng",
+				Pages:          2690,
+				RatingsCount:   38872,
+				ReviewsCount:   154,
+				Series:         "Harry Potter",
+				SeriesIndex:    "1-5",
+				WorkID:         8,
+				WeightedRating: 4.7671037,
+			},
+
+			&Book{
+				ID:             10,
+				Title:          "Harry Potter Collection (Harry Potter  #1-6)",
+				Authors:        "J.K. Rowling",
+				AverageRating:  4.73,
+				ISBN:           "439827604",
+				ISBN13:         "9780439827607",
+				LanguageCode:   "eng",
+				Pages:          3342,
+				RatingsCount:   27410,
+				ReviewsCount:   820,
+				Series:         "Harry Potter",
+				SeriesIndex:    "1-6",
+				WorkID:         10,
+				WeightedRating: 4.7136602,
+			},
+
+			&Book{
+				ID:             1,
+				Title:          "Harry Potter and the Half-Blood Prince (Harry Potter  #6)",
+				Authors:        "J.K. Rowling-Mary GrandPré",
+				AverageRating:  4.56,
+				ISBN:           "439785960",
+				ISBN13:         "9780439785969",
+				LanguageCode:   "eng",
+				Pages:          652,
+				RatingsCount:   1944099,
+				ReviewsCount:   26249,
+				Series:         "Harry Potter",
+				SeriesIndex:    "6",
+				WorkID:         1,
+				WeightedRating: 4.559849,
+			},
+
+			&Book{
+				ID:             5,
+				Title:          "Harry Potter and the Prisoner of Azkaban (Harry Potter  #3)",
+				Authors:        "J.K. Rowling-Mary GrandPré",
+				AverageRating:  4.55,
+				ISBN:           "043965548X",
+				ISBN13:         "9780439655484",
+				LanguageCode:   "eng",
+				Pages:          435,
+				RatingsCount:   2149872,
+				ReviewsCount:   33964,
+				Series:         "Harry Potter",
+				SeriesIndex:    "3",
+				WorkID:         5,
+				WeightedRating: 4.5498676,
+			},
+		},
 	} {
 		t.Run(
 			fmt.Sprintf("test: %d", i),
@@ -392,3 +669,208 @@ func TestSearchForTitles(t *testing.T) {
 		i++
 	}
 }
+
+// Test searching with a callback.
+func TestSearchEach(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+	searchBy := &SearchBy{
+		Authors:           []string{"Rowling"},
+		RatingCeil:        -1,
+		RatingFloor:       -1,
+		PagesCeil:         -1,
+		PagesFloor:        -1,
+		RatingsCountCeil:  -1,
+		RatingsCountFloor: -1,
+		ReviewsCountCeil:  -1,
+		ReviewsCountFloor: -1,
+		SortBy:            "ID",
+	}
+
+	expected, err := Search(searchIn, searchBy)
+	if err != nil {
+		t.Fatalf("search failed: %s", err.Error())
+	}
+
+	result := make([]*Book, 0)
+	err = SearchEach(searchIn, searchBy, func(book *Book) error {
+		result = append(result, book)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("search failed: %s", err.Error())
+	}
+	if len(expected) == 0 || !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected: %v, got: %v", expected, result)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = SearchEach(searchIn, searchBy, func(book *Book) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("expected search to stop after 1 call with %v, got: %d calls with %v", stop, calls, err)
+	}
+
+	searchBy.SortBy = "Publisher"
+	err = SearchEach(searchIn, searchBy, func(book *Book) error {
+		t.Errorf("unexpected book %d", book.ID)
+		return nil
+	})
+	if err == nil {
+		t.Errorf("expected an error sorting by %s", searchBy.SortBy)
+	}
+}
+
+// Test observing durations of queries.
+func TestObserve(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	shapes := make([]string, 0)
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+		Observe: func(shape string, duration time.Duration) {
+			if duration <= 0 {
+				t.Errorf("invalid duration of %s: %s", shape, duration)
+			}
+			shapes = append(shapes, shape)
+		},
+	}
+
+	if _, err := SearchByTitle(searchIn, "Notes from a Small Island"); err != nil {
+		t.Fatalf("search failed: %s", err.Error())
+	}
+	if _, err := Similar(searchIn, 1, 5); err != nil {
+		t.Fatalf("search failed: %s", err.Error())
+	}
+
+	if expected := []string{"byTitle", "byID", "similar"}; !reflect.DeepEqual(shapes, expected) {
+		t.Errorf("expected shapes: %v, got: %v", expected, shapes)
+	}
+}
+
+// Test listing fields that results can be sorted by.
+func TestSortFields(t *testing.T) {
+	expected := []string{"AverageRating", "ID", "Pages", "RatingsCount", "ReviewsCount", "Title", "WeightedRating"}
+	if fields := SortFields(); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected: %v, got: %v", expected, fields)
+	}
+}
+
+// Test splitting names of a book's authors.
+func TestAuthorNames(t *testing.T) {
+	for authors, expected := range map[string][]string{
+		"Douglas Adams":                  {"Douglas Adams"},
+		"J.K. Rowling-Mary GrandPré":     {"J.K. Rowling", "Mary GrandPré"},
+		"Neil Gaiman - Terry Pratchett-": {"Neil Gaiman", "Terry Pratchett"},
+		"":                               {},
+	} {
+		book := &Book{Authors: authors}
+		if names := book.AuthorNames(); !reflect.DeepEqual(names, expected) {
+			t.Errorf("%q: expected: %v, got: %v", authors, expected, names)
+		}
+	}
+}
+
+// Number of books in the synthetic datastore searched by BenchmarkSearch, and
+// number used with -short.
+const (
+	syntheticBooks      = 1000000
+	shortSyntheticBooks = 10000
+)
+
+// Benchmark searching for all books of a large datastore, either collecting them
+// using Search or visiting them using SearchEach, and report the peak heap
+// allocated during a search.
+func BenchmarkSearch(b *testing.B) {
+	n := syntheticBooks
+	if testing.Short() {
+		n = shortSyntheticBooks
+	}
+
+	datastore, bookTable, deferFn := testhelper.Synthetic(b, n)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+	searchBy := &SearchBy{
+		RatingCeil:        -1,
+		RatingFloor:       -1,
+		PagesCeil:         -1,
+		PagesFloor:        -1,
+		RatingsCountCeil:  -1,
+		RatingsCountFloor: -1,
+		ReviewsCountCeil:  -1,
+		ReviewsCountFloor: -1,
+	}
+
+	b.Run("Search", func(b *testing.B) {
+		benchmarkPeakHeap(b, n, func(sample func()) error {
+			books, err := Search(searchIn, searchBy)
+			sample()
+			runtime.KeepAlive(books)
+			if len(books) != n {
+				return fmt.Errorf("expected %d books, got: %d", n, len(books))
+			}
+			return err
+		})
+	})
+
+	b.Run("SearchEach", func(b *testing.B) {
+		benchmarkPeakHeap(b, n, func(sample func()) error {
+			count := 0
+			err := SearchEach(searchIn, searchBy, func(book *Book) error {
+				count++
+				if count%(n/10) == 0 {
+					sample()
+				}
+				return nil
+			})
+			if count != n {
+				return fmt.Errorf("expected %d books, got: %d", n, count)
+			}
+			return err
+		})
+	})
+}
+
+// benchmarkPeakHeap runs search b.N times, and reports the largest growth of
+// the live heap, over the heap before each search, sampled by search.
+func benchmarkPeakHeap(b *testing.B, n int, search func(sample func()) error) {
+	var stats runtime.MemStats
+	peak := uint64(0)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		base := stats.HeapAlloc
+		b.StartTimer()
+
+		err := search(func() {
+			b.StopTimer()
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > base && stats.HeapAlloc-base > peak {
+				peak = stats.HeapAlloc - base
+			}
+			b.StartTimer()
+		})
+		if err != nil {
+			b.Fatalf("search failed: %s", err.Error())
+		}
+	}
+
+	b.ReportMetric(float64(peak), "peak-heap-B")
+	b.ReportMetric(float64(peak)/float64(n), "peak-heap-B/book")
+}
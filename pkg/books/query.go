@@ -17,11 +17,41 @@ type queryConstructor func(*SearchBy) (bool, string, queryParameters)
 // and is used as a return value of query builders.
 type queryParameters []interface{}
 
+// priorRatings is the number of ratings at the dataset's mean rating that are
+// added to every book's own ratings when computing its weighted rating. A book
+// needs a comparable number of ratings before its average outweighs the mean.
+const priorRatings = 1000
+
+// sortColumns maps names of fields that search results can be sorted by to
+// columns of the books' table. WeightedRating isn't stored in the table and
+// is computed using weightedRating.
+var sortColumns = map[string]string{
+	"ID":             "id",
+	"Title":          "title",
+	"AverageRating":  "averageRating",
+	"WeightedRating": "weightedRating",
+	"Pages":          "pages",
+	"RatingsCount":   "ratingsCount",
+	"ReviewsCount":   "reviewsCount",
+}
+
 // query generates a SQL select query based on fields specified in SearchBy.
 // Returns a prepared statement, and a list of parameters to use with the prepared
 // statement when executing. If titles is true, then select statement only selects
 // books' titles.
 func query(searchIn *SearchIn, searchBy *SearchBy, titles bool) (string, queryParameters) {
+	queryParts, fields := conditions(searchIn, searchBy)
+	order := orderBy(searchIn, searchBy)
+	if titles {
+		return buildQuery(queryParts, searchIn, "title", order), fields
+	}
+
+	return buildQuery(queryParts, searchIn, bookColumns(searchIn), order), fields
+}
+
+// conditions runs all queryConstructors on given SearchBy and returns a list
+// of conditions to use in a where clause, and a list of their parameters.
+func conditions(searchIn *SearchIn, searchBy *SearchBy) ([]string, queryParameters) {
 	queryParts := make([]string, 0)
 	fields := make(queryParameters, 0)
 	for _, fn := range []queryConstructor{
@@ -30,8 +60,10 @@ func query(searchIn *SearchIn, searchBy *SearchBy, titles bool) (string, queryPa
 		languageCode,
 		isbn,
 		isbn13,
+		series,
 		ratingCeil,
 		ratingFloor,
+		weightedRatingFloor(searchIn),
 		pagesCeil,
 		pagesFloor,
 		ratingsCountCeil,
@@ -45,20 +77,15 @@ func query(searchIn *SearchIn, searchBy *SearchBy, titles bool) (string, queryPa
 		}
 	}
 
-	return buildQuery(queryParts, searchIn, titles), fields
+	return queryParts, fields
 }
 
 // buildQuery builds a sql select query using given a list of search string.
-// If titles is true, then buildQuery returns a sql statement that selects
-// titles only, otherwise a statement that selects all columns is returned.
-func buildQuery(queryParts []string, searchIn *SearchIn, titles bool) string {
+// selection is the list of columns to select, e.g. "*" or "title", and order
+// is an order by clause, or an empty string if results are not sorted.
+func buildQuery(queryParts []string, searchIn *SearchIn, selection, order string) string {
 	builder := new(strings.Builder)
-
-	if titles {
-		builder.WriteString(fmt.Sprintf("select title from %s", searchIn.BookTable))
-	} else {
-		builder.WriteString(fmt.Sprintf("select * from %s", searchIn.BookTable))
-	}
+	builder.WriteString(fmt.Sprintf("select %s from %s", selection, searchIn.BookTable))
 
 	if len(queryParts) != 0 {
 		builder.WriteString(" where ")
@@ -70,11 +97,88 @@ func buildQuery(queryParts []string, searchIn *SearchIn, titles bool) string {
 		}
 	}
 
+	if order != "" {
+		builder.WriteByte(' ')
+		builder.WriteString(order)
+	}
+
 	builder.WriteByte(';')
 
 	return builder.String()
 }
 
+// columns is the list of columns of the books' table that are scanned into
+// a Book, in order.
+const columns = "id, title, authors, averageRating, isbn, isbn13, languageCode, pages, " +
+	"ratingsCount, reviewsCount, series, seriesIndex, workID"
+
+// bookColumns returns the list of columns to select to scan a Book, which
+// are the table's columns followed by the computed weighted rating.
+func bookColumns(searchIn *SearchIn) string {
+	return fmt.Sprintf("%s, %s as weightedRating", columns, weightedRating(searchIn))
+}
+
+// worksQuery generates a SQL select query similar to query's, but that selects
+// a single edition, the most rated, of each work, followed by the number of
+// editions of the work that match given SearchBy.
+func worksQuery(searchIn *SearchIn, searchBy *SearchBy) (string, queryParameters) {
+	queryParts, fields := conditions(searchIn, searchBy)
+	editions := buildQuery(
+		queryParts,
+		searchIn,
+		fmt.Sprintf(
+			"%s, count(*) over (partition by workID) as editions, "+
+				"row_number() over (partition by workID order by ratingsCount desc, id) as edition",
+			bookColumns(searchIn),
+		),
+		"",
+	)
+
+	builder := new(strings.Builder)
+	builder.WriteString(fmt.Sprintf(
+		"select %s, weightedRating, editions from (%s) where edition = 1",
+		columns,
+		strings.TrimSuffix(editions, ";"),
+	))
+	if order := orderBy(searchIn, searchBy); order != "" {
+		builder.WriteByte(' ')
+		builder.WriteString(order)
+	}
+	builder.WriteByte(';')
+
+	return builder.String(), fields
+}
+
+// weightedRating returns a SQL expression that computes a book's Bayesian
+// weighted rating, which is its average rating pulled towards the mean rating
+// of all books by priorRatings ratings.
+func weightedRating(searchIn *SearchIn) string {
+	return fmt.Sprintf(
+		"((ratingsCount * averageRating + %d * (select avg(averageRating) from %s)) / (ratingsCount + %d))",
+		priorRatings,
+		searchIn.BookTable,
+		priorRatings,
+	)
+}
+
+// orderBy returns an order by clause based on SearchBy.SortBy, or an empty
+// string if SortBy is empty or invalid.
+func orderBy(searchIn *SearchIn, searchBy *SearchBy) string {
+	column, ok := sortColumns[searchBy.SortBy]
+	if !ok {
+		return ""
+	}
+
+	if searchBy.SortBy == "WeightedRating" {
+		column = weightedRating(searchIn)
+	}
+
+	if searchBy.Descending {
+		return fmt.Sprintf("order by %s desc, id", column)
+	}
+	return fmt.Sprintf("order by %s, id", column)
+}
+
 // newParameters creates and returns a new queryParameters using given
 // element i.
 func newParameters(i interface{}) queryParameters {
@@ -160,6 +264,16 @@ func isbn13(by *SearchBy) (bool, string, queryParameters) {
 	return false, "", nil
 }
 
+// series is the queryConstructor responsible for the SearchBy.Series
+// parameter.
+func series(by *SearchBy) (bool, string, queryParameters) {
+	if by.Series != "" {
+		return true, "series = ? collate nocase", newParameters(by.Series)
+	}
+
+	return false, "", nil
+}
+
 // ratingCeil is the queryConstructor responsible for the SearchBy.RatingCeil
 // parameter.
 func ratingCeil(by *SearchBy) (bool, string, queryParameters) {
@@ -180,6 +294,18 @@ func ratingFloor(by *SearchBy) (bool, string, queryParameters) {
 	return false, "", nil
 }
 
+// weightedRatingFloor returns the queryConstructor responsible for the
+// SearchBy.WeightedRatingFloor parameter.
+func weightedRatingFloor(searchIn *SearchIn) queryConstructor {
+	return func(by *SearchBy) (bool, string, queryParameters) {
+		if by.WeightedRatingFloor > 0 {
+			return true, fmt.Sprintf("%s > ?", weightedRating(searchIn)), newParameters(by.WeightedRatingFloor)
+		}
+
+		return false, "", nil
+	}
+}
+
 // pagesCeil is the queryConstructor responsible for the SearchBy.PagesCeil
 // parameter.
 func pagesCeil(by *SearchBy) (bool, string, queryParameters) {
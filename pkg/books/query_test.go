@@ -4,6 +4,13 @@ import (
 	"testing"
 )
 
+// weighted is the expression used to compute a weighted rating.
+const weighted = "((ratingsCount * averageRating + 1000 * (select avg(averageRating) from books)) / (ratingsCount + 1000))"
+
+// selectBooks is the beginning of a query that selects books.
+const selectBooks = "select id, title, authors, averageRating, isbn, isbn13, languageCode, pages, " +
+	"ratingsCount, reviewsCount, series, seriesIndex, workID, " + weighted + " as weightedRating from books"
+
 // Test query generation based on SearchBy.
 func TestQuery(t *testing.T) {
 	searchIn := &SearchIn{
@@ -25,7 +32,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where title like ?;",
+		}: selectBooks + " where title like ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -41,7 +48,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where (authors like ? or authors like ? or authors like ?);",
+		}: selectBooks + " where (authors like ? or authors like ? or authors like ?);",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -57,7 +64,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where (languageCode like ? or languageCode like ? or languageCode like ?);",
+		}: selectBooks + " where (languageCode like ? or languageCode like ? or languageCode like ?);",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -73,7 +80,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where isbn = ?;",
+		}: selectBooks + " where isbn = ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -89,7 +96,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where isbn13 = ?;",
+		}: selectBooks + " where isbn13 = ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -105,7 +112,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where averageRating <= ?;",
+		}: selectBooks + " where averageRating <= ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -121,7 +128,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where averageRating > ?;",
+		}: selectBooks + " where averageRating > ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -137,7 +144,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where pages <= ?;",
+		}: selectBooks + " where pages <= ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -153,7 +160,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where pages > ?;",
+		}: selectBooks + " where pages > ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -169,7 +176,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where ratingsCount <= ?;",
+		}: selectBooks + " where ratingsCount <= ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -185,7 +192,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: 50,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where ratingsCount > ?;",
+		}: selectBooks + " where ratingsCount > ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -201,7 +208,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  100,
 			ReviewsCountFloor: -1,
-		}: "select * from books where reviewsCount <= ?;",
+		}: selectBooks + " where reviewsCount <= ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -217,7 +224,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: 50,
-		}: "select * from books where reviewsCount > ?;",
+		}: selectBooks + " where reviewsCount > ?;",
 
 		&SearchBy{
 			TitleHas:          "aaa",
@@ -233,7 +240,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where title like ? and (languageCode like ? or languageCode like ?);",
+		}: selectBooks + " where title like ? and (languageCode like ? or languageCode like ?);",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -249,7 +256,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  200,
 			ReviewsCountFloor: -1,
-		}: "select * from books where isbn13 = ? and pages > ? and reviewsCount <= ?;",
+		}: selectBooks + " where isbn13 = ? and pages > ? and reviewsCount <= ?;",
 
 		&SearchBy{
 			TitleHas:          "aaa",
@@ -265,7 +272,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books where title like ? and isbn = ? and averageRating <= ? and ratingsCount <= ?;",
+		}: selectBooks + " where title like ? and isbn = ? and averageRating <= ? and ratingsCount <= ?;",
 
 		&SearchBy{
 			TitleHas:          "",
@@ -281,7 +288,7 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: -1,
 			ReviewsCountCeil:  -1,
 			ReviewsCountFloor: -1,
-		}: "select * from books;",
+		}: selectBooks + ";",
 
 		&SearchBy{
 			TitleHas:          "aaa",
@@ -297,12 +304,82 @@ func TestQuery(t *testing.T) {
 			RatingsCountFloor: 500,
 			ReviewsCountCeil:  1000,
 			ReviewsCountFloor: 500,
-		}: "select * from books where title like ? and (authors like ? or authors like ? or authors like ?) and (languageCode like ? or languageCode like ?) and " +
+		}: selectBooks + " where title like ? and (authors like ? or authors like ? or authors like ?) and (languageCode like ? or languageCode like ?) and " +
 			"isbn = ? and isbn13 = ? and " +
 			"averageRating <= ? and averageRating > ? and " +
 			"pages <= ? and pages > ? and " +
 			"ratingsCount <= ? and ratingsCount > ? and " +
 			"reviewsCount <= ? and reviewsCount > ?;",
+
+		&SearchBy{
+			TitleHas:            "",
+			Authors:             nil,
+			LanguageCode:        nil,
+			ISBN:                "",
+			ISBN13:              "",
+			RatingCeil:          -1,
+			RatingFloor:         -1,
+			WeightedRatingFloor: 4,
+			PagesCeil:           -1,
+			PagesFloor:          -1,
+			RatingsCountCeil:    -1,
+			RatingsCountFloor:   -1,
+			ReviewsCountCeil:    -1,
+			ReviewsCountFloor:   -1,
+		}: selectBooks + " where " + weighted + " > ?;",
+
+		&SearchBy{
+			TitleHas:          "",
+			Authors:           nil,
+			LanguageCode:      nil,
+			ISBN:              "",
+			ISBN13:            "",
+			Series:            "Harry Potter",
+			RatingCeil:        -1,
+			RatingFloor:       -1,
+			PagesCeil:         -1,
+			PagesFloor:        -1,
+			RatingsCountCeil:  -1,
+			RatingsCountFloor: -1,
+			ReviewsCountCeil:  -1,
+			ReviewsCountFloor: -1,
+		}: selectBooks + " where series = ? collate nocase;",
+
+		&SearchBy{
+			TitleHas:          "",
+			Authors:           nil,
+			LanguageCode:      nil,
+			ISBN:              "",
+			ISBN13:            "",
+			RatingCeil:        -1,
+			RatingFloor:       -1,
+			PagesCeil:         100,
+			PagesFloor:        -1,
+			RatingsCountCeil:  -1,
+			RatingsCountFloor: -1,
+			ReviewsCountCeil:  -1,
+			ReviewsCountFloor: -1,
+			SortBy:            "Pages",
+			Descending:        true,
+		}: selectBooks + " where pages <= ? order by pages desc, id;",
+
+		&SearchBy{
+			TitleHas:          "",
+			Authors:           nil,
+			LanguageCode:      nil,
+			ISBN:              "",
+			ISBN13:            "",
+			RatingCeil:        -1,
+			RatingFloor:       -1,
+			PagesCeil:         -1,
+			PagesFloor:        -1,
+			RatingsCountCeil:  -1,
+			RatingsCountFloor: -1,
+			ReviewsCountCeil:  -1,
+			ReviewsCountFloor: -1,
+			SortBy:            "WeightedRating",
+			Descending:        false,
+		}: selectBooks + " order by " + weighted + ", id;",
 	} {
 		if q, _ := query(searchIn, s, false); q != sq {
 			t.Errorf("Expected \"%s\", Found \"%s\".", sq, q)
@@ -0,0 +1,191 @@
+package books
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Weights of each component of the similarity score, they add up to 1.
+const (
+	authorsWeight = 0.4
+	titleWeight   = 0.3
+	ratingWeight  = 0.15
+	pagesWeight   = 0.15
+)
+
+// maxTitleWords is the maximum number of title words used to find candidates
+// for similar books.
+const maxTitleWords = 5
+
+// stopWords are common words that are ignored when comparing titles.
+var stopWords = map[string]bool{
+	"the":   true,
+	"and":   true,
+	"for":   true,
+	"with":  true,
+	"from":  true,
+	"into":  true,
+	"book":  true,
+	"books": true,
+}
+
+// Similar searchs in table and database specified in given SearchIn for books
+// similar to the book with the given id, and returns at most limit books sorted
+// by similarity, most similar first. Books are considered similar if they share
+// authors or title words (including series' names), and are scored using
+// Similarity.
+func Similar(searchIn *SearchIn, id, limit int) ([]*Book, error) {
+	defer searchIn.observe("similar", time.Now())
+
+	book, err := SearchByID(searchIn, id)
+	if err != nil {
+		return nil, err
+	}
+
+	queryParts, parameters := candidates(book)
+	if queryParts == nil {
+		return make([]*Book, 0), nil
+	}
+
+	query := buildQuery(queryParts, searchIn, bookColumns(searchIn), "")
+	rows, err := searchIn.Datastore.Query(query, parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	similar := make([]*Book, 0)
+	scores := make(map[*Book]float64)
+	for rows.Next() {
+		candidate, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		similar = append(similar, candidate)
+		scores[candidate] = Similarity(book, candidate)
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		a, b := similar[i], similar[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if a.RatingsCount != b.RatingsCount {
+			return a.RatingsCount > b.RatingsCount
+		}
+		return a.ID < b.ID
+	})
+
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
+
+// Similarity returns a score between 0 and 1 that indicates how similar two
+// books are, 1 being most similar. The score is a weighted sum of the overlap
+// of the books' authors, the overlap of words in their titles, and closeness
+// of their ratings and lengths.
+func Similarity(a, b *Book) float64 {
+	return authorsWeight*overlap(authorSet(a), authorSet(b)) +
+		titleWeight*overlap(wordSet(a.Title), wordSet(b.Title)) +
+		ratingWeight*(1-math.Abs(float64(a.AverageRating-b.AverageRating))/5) +
+		pagesWeight*ratio(a.Pages, b.Pages)
+}
+
+// candidates returns conditions, and their parameters, that select books
+// which share an author or a title word with given book, excluding the
+// book itself. Returns nil if book has no authors or title words to match.
+func candidates(book *Book) ([]string, queryParameters) {
+	parts := make([]string, 0)
+	parameters := make(queryParameters, 0)
+	for author := range authorSet(book) {
+		parts = append(parts, "authors like ?")
+		parameters = append(parameters, fmt.Sprintf("%%%s%%", author))
+	}
+
+	for _, word := range titleWords(book.Title, maxTitleWords) {
+		parts = append(parts, "title like ?")
+		parameters = append(parameters, fmt.Sprintf("%%%s%%", word))
+	}
+
+	if len(parts) == 0 {
+		return nil, nil
+	}
+
+	return []string{"id != ?", fmt.Sprintf("(%s)", strings.Join(parts, " or "))},
+		append(newParameters(book.ID), parameters...)
+}
+
+// authorSet returns a set of lower case names of book's authors.
+func authorSet(book *Book) map[string]bool {
+	set := make(map[string]bool)
+	for _, author := range book.AuthorNames() {
+		set[strings.ToLower(author)] = true
+	}
+	return set
+}
+
+// wordSet returns a set of lower case words in given title, ignoring stop
+// words and words shorter than 3 characters.
+func wordSet(title string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range titleWords(title, -1) {
+		set[word] = true
+	}
+	return set
+}
+
+// titleWords returns at most n unique words of given title, in order, that
+// are used in comparisons. If n < 0 all words are returned.
+func titleWords(title string, n int) []string {
+	seen := make(map[string]bool)
+	words := make([]string, 0)
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}) {
+		if len(word) < 3 || stopWords[word] || seen[word] {
+			continue
+		}
+		if n >= 0 && len(words) == n {
+			break
+		}
+
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// overlap returns the Jaccard index of two sets, which is the size of their
+// intersection divided by the size of their union.
+func overlap(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+
+	common := 0
+	for k := range a {
+		if b[k] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// ratio returns the ratio of the smaller of two numbers to the larger, or 0
+// if either is not positive.
+func ratio(a, b int) float64 {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	if a > b {
+		a, b = b, a
+	}
+	return float64(a) / float64(b)
+}
@@ -0,0 +1,82 @@
+package books
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// Test searching for books similar to a book.
+func TestSimilar(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	for _, test := range []struct {
+		id    int
+		limit int
+		ids   []int
+	}{
+		{id: 14, limit: 4, ids: []int{13, 18, 12, 16}},
+		{id: 22, limit: 2, ids: []int{23, 25}},
+		{id: 3588, limit: 10, ids: []int{}},
+	} {
+		t.Run(
+			fmt.Sprintf("id: %d", test.id),
+			func(*testing.T) {
+				result, err := Similar(searchIn, test.id, test.limit)
+				if err != nil {
+					t.Fatalf("search failed: %s", err.Error())
+				}
+
+				if len(result) != len(test.ids) {
+					t.Fatalf("expected: %d search results, got: %d", len(test.ids), len(result))
+				}
+
+				for i, book := range result {
+					if book.ID != test.ids[i] {
+						t.Errorf("expected id: %d at %d, got: %d", test.ids[i], i, book.ID)
+					}
+				}
+			},
+		)
+	}
+
+	if _, err := Similar(searchIn, 30, 10); err == nil {
+		t.Errorf("expected error for missing id")
+	}
+}
+
+// Test scoring similarity of books.
+func TestSimilarity(t *testing.T) {
+	for i, test := range []struct {
+		a, b  *Book
+		score float64
+	}{
+		{
+			a:     &Book{Title: "A Tale", Authors: "X-Y", AverageRating: 4, Pages: 100},
+			b:     &Book{Title: "A Tale", Authors: "Y-X", AverageRating: 4, Pages: 100},
+			score: 1,
+		},
+		{
+			a:     &Book{Title: "First Tale", Authors: "X", AverageRating: 5, Pages: 100},
+			b:     &Book{Title: "Second Story", Authors: "Y", AverageRating: 0, Pages: 0},
+			score: 0,
+		},
+		{
+			a:     &Book{Title: "Tale of Two", Authors: "X", AverageRating: 4, Pages: 100},
+			b:     &Book{Title: "Tale", Authors: "X-Y", AverageRating: 3, Pages: 50},
+			score: 0.4*0.5 + 0.3*0.5 + 0.15*0.8 + 0.15*0.5,
+		},
+	} {
+		if score := Similarity(test.a, test.b); math.Abs(score-test.score) > 1e-6 {
+			t.Errorf("test %d: expected score: %f, got: %f", i, test.score, score)
+		}
+	}
+}
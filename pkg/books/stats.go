@@ -0,0 +1,94 @@
+package books
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// statsColumns are the columns of the books' table that are summarized by
+// Stats, in the order of Statistics' summaries.
+var statsColumns = []string{"averageRating", "pages", "ratingsCount", "reviewsCount"}
+
+// Statistics holds aggregate statistics of a set of books.
+type Statistics struct {
+	Count         int      // Number of books in the set.
+	AverageRating *Summary // Summary of books' average ratings.
+	Pages         *Summary // Summary of books' number of pages.
+	RatingsCount  *Summary // Summary of books' number of ratings.
+	ReviewsCount  *Summary // Summary of books' number of text reviews.
+}
+
+// Summary summarizes the values of a single numeric field. All values are
+// zero if the summarized set is empty.
+type Summary struct {
+	Min    float64 // Smallest value.
+	Max    float64 // Largest value.
+	Mean   float64 // Arithmetic mean of values.
+	Median float64 // Middle value, or mean of the two middle values.
+}
+
+// Stats searchs in table and database specified in given SearchIn, and returns
+// aggregate statistics of the set of books that match the parameters given in
+// SearchBy. Statistics are computed by the datastore, so books aren't loaded
+// into memory.
+func Stats(searchIn *SearchIn, searchBy *SearchBy) (*Statistics, error) {
+	defer searchIn.observe("stats", time.Now())
+
+	if err := searchBy.Validate(); err != nil {
+		return nil, err
+	}
+
+	queryParts, parameters := conditions(searchIn, searchBy)
+
+	aggregates := make([]string, 0, 3*len(statsColumns)+1)
+	aggregates = append(aggregates, "count(*)")
+	for _, column := range statsColumns {
+		aggregates = append(
+			aggregates,
+			fmt.Sprintf("coalesce(min(%s), 0)", column),
+			fmt.Sprintf("coalesce(max(%s), 0)", column),
+			fmt.Sprintf("coalesce(avg(%s), 0)", column),
+		)
+	}
+
+	summaries := make([]*Summary, len(statsColumns))
+	stats := new(Statistics)
+	destinations := []interface{}{&stats.Count}
+	for i := range summaries {
+		summaries[i] = new(Summary)
+		destinations = append(destinations, &summaries[i].Min, &summaries[i].Max, &summaries[i].Mean)
+	}
+
+	query := buildQuery(queryParts, searchIn, strings.Join(aggregates, ", "), "")
+	if err := searchIn.Datastore.QueryRow(query, parameters...).Scan(destinations...); err != nil {
+		return nil, err
+	}
+
+	for i, column := range statsColumns {
+		median, err := median(searchIn, queryParts, parameters, column, stats.Count)
+		if err != nil {
+			return nil, err
+		}
+		summaries[i].Median = median
+	}
+
+	stats.AverageRating, stats.Pages, stats.RatingsCount, stats.ReviewsCount = summaries[0], summaries[1], summaries[2], summaries[3]
+	return stats, nil
+}
+
+// median returns the median of given column among the count books that match
+// given conditions, by selecting only the middle value, or the two middle
+// values, of the sorted column. Returns 0 if count is 0.
+func median(searchIn *SearchIn, queryParts []string, parameters queryParameters, column string, count int) (float64, error) {
+	if count == 0 {
+		return 0, nil
+	}
+
+	middle := fmt.Sprintf("order by %s limit %d offset %d", column, 2-count%2, (count-1)/2)
+	values := strings.TrimSuffix(buildQuery(queryParts, searchIn, column, middle), ";")
+
+	var median float64
+	err := searchIn.Datastore.QueryRow(fmt.Sprintf("select avg(%s) from (%s);", column, values), parameters...).Scan(&median)
+	return median, err
+}
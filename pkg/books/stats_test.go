@@ -0,0 +1,132 @@
+package books
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// Test computing statistics of a set of books.
+func TestStats(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	for i, test := range []struct {
+		searchBy *SearchBy
+		stats    *Statistics
+	}{
+		{
+			searchBy: &SearchBy{
+				TitleHas:          "",
+				Authors:           []string{"Bill Bryson"},
+				LanguageCode:      nil,
+				ISBN:              "",
+				ISBN13:            "",
+				RatingCeil:        -1,
+				RatingFloor:       -1,
+				PagesCeil:         -1,
+				PagesFloor:        -1,
+				RatingsCountCeil:  -1,
+				RatingsCountFloor: -1,
+				ReviewsCountCeil:  -1,
+				ReviewsCountFloor: -1,
+			},
+			stats: &Statistics{
+				Count:         5,
+				AverageRating: &Summary{Min: 3.43, Max: 4.2, Mean: 3.896, Median: 3.9},
+				Pages:         &Summary{Min: 55, Max: 544, Mean: 298.8, Median: 304},
+				RatingsCount:  &Summary{Min: 2020, Max: 228522, Mean: 70647.6, Median: 47490},
+				ReviewsCount:  &Summary{Min: 124, Max: 8840, Mean: 3132.8, Median: 2153},
+			},
+		},
+		{
+			searchBy: &SearchBy{
+				TitleHas:          "",
+				Authors:           nil,
+				LanguageCode:      nil,
+				ISBN:              "",
+				ISBN13:            "",
+				RatingCeil:        -1,
+				RatingFloor:       4.7,
+				PagesCeil:         -1,
+				PagesFloor:        -1,
+				RatingsCountCeil:  -1,
+				RatingsCountFloor: -1,
+				ReviewsCountCeil:  -1,
+				ReviewsCountFloor: -1,
+			},
+			stats: &Statistics{
+				Count:         2,
+				AverageRating: &Summary{Min: 4.73, Max: 4.78, Mean: 4.755, Median: 4.755},
+				Pages:         &Summary{Min: 2690, Max: 3342, Mean: 3016, Median: 3016},
+				RatingsCount:  &Summary{Min: 27410, Max: 38872, Mean: 33141, Median: 33141},
+				ReviewsCount:  &Summary{Min: 154, Max: 820, Mean: 487, Median: 487},
+			},
+		},
+		{
+			searchBy: &SearchBy{
+				TitleHas:          "",
+				Authors:           nil,
+				LanguageCode:      []string{"fre"},
+				ISBN:              "",
+				ISBN13:            "",
+				RatingCeil:        -1,
+				RatingFloor:       -1,
+				PagesCeil:         -1,
+				PagesFloor:        -1,
+				RatingsCountCeil:  -1,
+				RatingsCountFloor: -1,
+				ReviewsCountCeil:  -1,
+				ReviewsCountFloor: -1,
+			},
+			stats: &Statistics{
+				Count:         0,
+				AverageRating: new(Summary),
+				Pages:         new(Summary),
+				RatingsCount:  new(Summary),
+				ReviewsCount:  new(Summary),
+			},
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("test: %d", i),
+			func(*testing.T) {
+				result, err := Stats(searchIn, test.searchBy)
+				if err != nil {
+					t.Fatalf("stats failed: %s", err.Error())
+				}
+
+				if result.Count != test.stats.Count {
+					t.Fatalf("expected count: %d, got: %d", test.stats.Count, result.Count)
+				}
+
+				for name, pair := range map[string][2]*Summary{
+					"AverageRating": {result.AverageRating, test.stats.AverageRating},
+					"Pages":         {result.Pages, test.stats.Pages},
+					"RatingsCount":  {result.RatingsCount, test.stats.RatingsCount},
+					"ReviewsCount":  {result.ReviewsCount, test.stats.ReviewsCount},
+				} {
+					if !equalSummaries(pair[0], pair[1]) {
+						t.Errorf("incorrect %s summary, expected: %v, got: %v", name, *pair[1], *pair[0])
+					}
+				}
+			},
+		)
+	}
+}
+
+// equalSummaries returns true if a and b are approximately equal.
+func equalSummaries(a, b *Summary) bool {
+	const epsilon = 1e-6
+	return math.Abs(a.Min-b.Min) < epsilon &&
+		math.Abs(a.Max-b.Max) < epsilon &&
+		math.Abs(a.Mean-b.Mean) < epsilon &&
+		math.Abs(a.Median-b.Median) < epsilon
+}
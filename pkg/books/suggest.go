@@ -0,0 +1,133 @@
+package books
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Suggestion is a completion of a prefix.
+type Suggestion struct {
+	Text         string // Completed title, or author's name.
+	RatingsCount int    // Total number of ratings of books with this title, or by this author.
+}
+
+// PrefixIndex is an in-memory index of books' titles and authors, used to find
+// completions of prefixes ranked by popularity. A PrefixIndex is a snapshot of
+// the datastore, and should be rebuilt after the datastore is modified.
+type PrefixIndex struct {
+	titles  []*indexEntry // Sorted by key.
+	authors []*indexEntry // Sorted by key.
+}
+
+// indexEntry is a suggestion and the key it's found by.
+type indexEntry struct {
+	key string // Lower case text.
+	*Suggestion
+}
+
+// NewPrefixIndex creates a PrefixIndex of all books in table and database specified
+// in given SearchIn.
+func NewPrefixIndex(searchIn *SearchIn) (*PrefixIndex, error) {
+	defer searchIn.observe("prefixIndex", time.Now())
+
+	search := fmt.Sprintf("select title, authors, ratingsCount from %s;", searchIn.BookTable)
+	rows, err := searchIn.Datastore.Query(search)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	titles := make(map[string]*indexEntry)
+	authors := make(map[string]*indexEntry)
+	for rows.Next() {
+		var title, authorList string
+		var ratingsCount int
+		if err := rows.Scan(&title, &authorList, &ratingsCount); err != nil {
+			return nil, err
+		}
+
+		addToIndex(titles, title, ratingsCount)
+		for _, author := range strings.Split(authorList, "-") {
+			addToIndex(authors, strings.TrimSpace(author), ratingsCount)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &PrefixIndex{
+		titles:  sortedEntries(titles),
+		authors: sortedEntries(authors),
+	}, nil
+}
+
+// SuggestTitles returns at most n titles that start with the given prefix,
+// ignoring case, most rated first.
+func (p *PrefixIndex) SuggestTitles(prefix string, n int) []*Suggestion {
+	return suggest(p.titles, prefix, n)
+}
+
+// SuggestAuthors returns at most n authors whose names start with the given
+// prefix, ignoring case, most rated first.
+func (p *PrefixIndex) SuggestAuthors(prefix string, n int) []*Suggestion {
+	return suggest(p.authors, prefix, n)
+}
+
+// suggest finds entries whose keys start with prefix in given sorted entries,
+// and returns the n most rated.
+func suggest(entries []*indexEntry, prefix string, n int) []*Suggestion {
+	prefix = strings.ToLower(prefix)
+	start := sort.Search(len(entries), func(i int) bool {
+		return entries[i].key >= prefix
+	})
+
+	suggestions := make([]*Suggestion, 0)
+	for i := start; i < len(entries) && strings.HasPrefix(entries[i].key, prefix); i++ {
+		suggestions = append(suggestions, entries[i].Suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].RatingsCount > suggestions[j].RatingsCount
+	})
+	if len(suggestions) > n {
+		suggestions = suggestions[:n]
+	}
+	return suggestions
+}
+
+// addToIndex adds text's ratings count to its entry in index, creating the
+// entry if it doesn't exist. Empty text is ignored.
+func addToIndex(index map[string]*indexEntry, text string, ratingsCount int) {
+	if text == "" {
+		return
+	}
+
+	key := strings.ToLower(text)
+	if entry, ok := index[key]; ok {
+		entry.RatingsCount += ratingsCount
+		return
+	}
+
+	index[key] = &indexEntry{
+		key: key,
+		Suggestion: &Suggestion{
+			Text:         text,
+			RatingsCount: ratingsCount,
+		},
+	}
+}
+
+// sortedEntries returns the entries of index sorted by key.
+func sortedEntries(index map[string]*indexEntry) []*indexEntry {
+	entries := make([]*indexEntry, 0, len(index))
+	for _, entry := range index {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return entries
+}
@@ -0,0 +1,86 @@
+package books
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// Test suggesting completions of titles and authors.
+func TestPrefixIndex(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	index, err := NewPrefixIndex(&SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	})
+	if err != nil {
+		t.Fatalf("failed to create index: %s", err.Error())
+	}
+
+	for i, test := range []struct {
+		suggest     func(string, int) []*Suggestion
+		prefix      string
+		n           int
+		suggestions []Suggestion
+	}{
+		{
+			suggest: index.SuggestTitles,
+			prefix:  "Harry Potter and",
+			n:       3,
+			suggestions: []Suggestion{
+				{"Harry Potter and the Sorcerer's Stone (Harry Potter  #1)", 5629932},
+				{"Harry Potter and the Prisoner of Azkaban (Harry Potter  #3)", 2149872},
+				{"Harry Potter and the Order of the Phoenix (Harry Potter  #5)", 1996446},
+			},
+		},
+		{
+			suggest: index.SuggestTitles,
+			prefix:  "the hitch",
+			n:       10,
+			suggestions: []Suggestion{
+				{"The Hitchhiker's Guide to the Galaxy (Hitchhiker's Guide to the Galaxy  #1)", 5638},
+			},
+		},
+		{
+			suggest:     index.SuggestTitles,
+			prefix:      "Nothing",
+			n:           10,
+			suggestions: []Suggestion{},
+		},
+		{
+			suggest: index.SuggestAuthors,
+			prefix:  "bi",
+			n:       10,
+			suggestions: []Suggestion{
+				{"Bill Bryson", 353238},
+			},
+		},
+		{
+			suggest: index.SuggestAuthors,
+			prefix:  "J",
+			n:       10,
+			suggestions: []Suggestion{
+				{"J.K. Rowling", 11792898},
+			},
+		},
+	} {
+		t.Run(
+			fmt.Sprintf("test: %d", i),
+			func(*testing.T) {
+				result := test.suggest(test.prefix, test.n)
+				if len(result) != len(test.suggestions) {
+					t.Fatalf("expected: %d suggestions, got: %d", len(test.suggestions), len(result))
+				}
+
+				for i, suggestion := range result {
+					if *suggestion != test.suggestions[i] {
+						t.Errorf("expected: %v at %d, got: %v", test.suggestions[i], i, *suggestion)
+					}
+				}
+			},
+		)
+	}
+}
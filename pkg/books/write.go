@@ -0,0 +1,263 @@
+package books
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/datastore"
+	"github.com/sudo-sturbia/bfr/v2/internal/titles"
+)
+
+// ErrExists is returned when inserting a book with an ID that is taken.
+var ErrExists = errors.New("id already exists")
+
+// Patterns that valid book fields must match.
+var (
+	isbnPattern         = regexp.MustCompile(`^[0-9]{0,9}[0-9Xx]$`)
+	isbn13Pattern       = regexp.MustCompile(`^[0-9]{13}$`)
+	languageCodePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z]{2})?$`)
+)
+
+// ValidationError is returned when a book has an invalid field.
+type ValidationError struct {
+	Field   string // Name of the invalid field.
+	Message string // Description of the problem.
+}
+
+// Error returns a description of the invalid field.
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", err.Field, err.Message)
+}
+
+// Validate checks that a book's fields can be stored, and returns a
+// *ValidationError describing the first invalid field, if any. Fields that
+// are computed from other fields (Series, SeriesIndex, WorkID and
+// WeightedRating) are not checked.
+func (book *Book) Validate() error {
+	switch {
+	case book.ID < 0:
+		return &ValidationError{"ID", "must not be negative"}
+	case strings.TrimSpace(book.Title) == "":
+		return &ValidationError{"Title", "must not be empty"}
+	case strings.TrimSpace(book.Authors) == "":
+		return &ValidationError{"Authors", "must not be empty"}
+	case book.AverageRating < 0 || book.AverageRating > 5:
+		return &ValidationError{"AverageRating", "must be between 0 and 5"}
+	case !isbnPattern.MatchString(book.ISBN):
+		return &ValidationError{"ISBN", "must be at most 10 digits, the last of which may be an X"}
+	case !isbn13Pattern.MatchString(book.ISBN13):
+		return &ValidationError{"ISBN13", "must be 13 digits"}
+	case !languageCodePattern.MatchString(book.LanguageCode):
+		return &ValidationError{"LanguageCode", "must be a language code, e.g. \"eng\" or \"en-US\""}
+	case book.Pages < 0:
+		return &ValidationError{"Pages", "must not be negative"}
+	case book.RatingsCount < 0:
+		return &ValidationError{"RatingsCount", "must not be negative"}
+	case book.ReviewsCount < 0:
+		return &ValidationError{"ReviewsCount", "must not be negative"}
+	}
+
+	return nil
+}
+
+// Insert validates and adds a book to table and database specified in given
+// SearchIn, and returns the stored book. If book's ID is 0, a new ID is
+// assigned, otherwise ErrExists is returned if the ID is taken. The book's
+// series and work are computed from its title and authors.
+func Insert(searchIn *SearchIn, book *Book) (*Book, error) {
+	defer searchIn.observe("insert", time.Now())
+
+	if err := book.Validate(); err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err := inTransaction(searchIn, func(tx *sql.Tx) error {
+		if book.ID != 0 {
+			var exists int
+			err := tx.QueryRow(
+				fmt.Sprintf("select count(*) from %s where id = ?;", searchIn.BookTable),
+				book.ID,
+			).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if exists != 0 {
+				return ErrExists
+			}
+		}
+
+		series, seriesIndex := titles.Series(book.Title)
+		key := titles.WorkKey(book.Title, book.Authors)
+		result, err := tx.Exec(
+			fmt.Sprintf(
+				"insert into %s (%s, workKey) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, null, ?);",
+				searchIn.BookTable,
+				columns,
+			),
+			nullIfZero(book.ID),
+			book.Title,
+			book.Authors,
+			book.AverageRating,
+			book.ISBN,
+			book.ISBN13,
+			book.LanguageCode,
+			book.Pages,
+			book.RatingsCount,
+			book.ReviewsCount,
+			series,
+			seriesIndex,
+			key,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		return clusterWorks(tx, searchIn, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return SearchByID(searchIn, int(id))
+}
+
+// Update validates and replaces the book with the same ID in table and database
+// specified in given SearchIn, and returns the stored book. ErrNotFound is
+// returned if no book has the ID. The book's series and work are computed from
+// its title and authors.
+func Update(searchIn *SearchIn, book *Book) (*Book, error) {
+	defer searchIn.observe("update", time.Now())
+
+	if err := book.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := inTransaction(searchIn, func(tx *sql.Tx) error {
+		oldKey, err := workKey(tx, searchIn, book.ID)
+		if err != nil {
+			return err
+		}
+
+		series, seriesIndex := titles.Series(book.Title)
+		newKey := titles.WorkKey(book.Title, book.Authors)
+		_, err = tx.Exec(
+			fmt.Sprintf(
+				"update %s set title = ?, authors = ?, averageRating = ?, isbn = ?, isbn13 = ?, "+
+					"languageCode = ?, pages = ?, ratingsCount = ?, reviewsCount = ?, "+
+					"series = ?, seriesIndex = ?, workKey = ? where id = ?;",
+				searchIn.BookTable,
+			),
+			book.Title,
+			book.Authors,
+			book.AverageRating,
+			book.ISBN,
+			book.ISBN13,
+			book.LanguageCode,
+			book.Pages,
+			book.RatingsCount,
+			book.ReviewsCount,
+			series,
+			seriesIndex,
+			newKey,
+			book.ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		return clusterWorks(tx, searchIn, oldKey, newKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return SearchByID(searchIn, book.ID)
+}
+
+// Delete removes the book with the given ID from table and database specified
+// in given SearchIn. ErrNotFound is returned if no book has the ID.
+func Delete(searchIn *SearchIn, id int) error {
+	defer searchIn.observe("delete", time.Now())
+
+	return inTransaction(searchIn, func(tx *sql.Tx) error {
+		key, err := workKey(tx, searchIn, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(fmt.Sprintf("delete from %s where id = ?;", searchIn.BookTable), id)
+		if err != nil {
+			return err
+		}
+
+		return clusterWorks(tx, searchIn, key)
+	})
+}
+
+// inTransaction runs fn in a transaction, which is committed if fn succeeds
+// and rolled back otherwise. Committed transactions are recorded in the
+// datastore's metadata, see datastore.Touch.
+func inTransaction(searchIn *SearchIn, fn func(*sql.Tx) error) error {
+	tx, err := searchIn.Datastore.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := datastore.Touch(tx, searchIn.MetadataTable); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// workKey returns the work key of the book with the given ID, or ErrNotFound.
+func workKey(tx *sql.Tx, searchIn *SearchIn, id int) (string, error) {
+	var key string
+	err := tx.QueryRow(fmt.Sprintf("select workKey from %s where id = ?;", searchIn.BookTable), id).Scan(&key)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return key, err
+}
+
+// clusterWorks sets the work ID of all editions that have one of the given
+// work keys to the smallest ID among editions with the same key.
+func clusterWorks(tx *sql.Tx, searchIn *SearchIn, keys ...string) error {
+	for _, key := range keys {
+		_, err := tx.Exec(
+			fmt.Sprintf(
+				"update %s set workID = (select min(id) from %s where workKey = ?) where workKey = ?;",
+				searchIn.BookTable,
+				searchIn.BookTable,
+			),
+			key,
+			key,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullIfZero returns nil if id is 0, and id otherwise. Inserting a null ID
+// makes the datastore assign a new one.
+func nullIfZero(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,174 @@
+package books
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+)
+
+// Test validating books' fields.
+func TestValidate(t *testing.T) {
+	valid := func() *Book {
+		return &Book{
+			Title:         "A Title",
+			Authors:       "An Author",
+			AverageRating: 4,
+			ISBN:          "043965548X",
+			ISBN13:        "9780439655484",
+			LanguageCode:  "en-US",
+		}
+	}
+
+	for field, modify := range map[string]func(*Book){
+		"":              func(*Book) {},
+		"ID":            func(b *Book) { b.ID = -1 },
+		"Title":         func(b *Book) { b.Title = " " },
+		"Authors":       func(b *Book) { b.Authors = "" },
+		"AverageRating": func(b *Book) { b.AverageRating = 5.5 },
+		"ISBN":          func(b *Book) { b.ISBN = "X123" },
+		"ISBN13":        func(b *Book) { b.ISBN13 = "978043965548" },
+		"LanguageCode":  func(b *Book) { b.LanguageCode = "english" },
+		"Pages":         func(b *Book) { b.Pages = -1 },
+		"RatingsCount":  func(b *Book) { b.RatingsCount = -1 },
+		"ReviewsCount":  func(b *Book) { b.ReviewsCount = -1 },
+	} {
+		book := valid()
+		modify(book)
+
+		err := book.Validate()
+		if field == "" {
+			if err != nil {
+				t.Errorf("expected valid book, got: %s", err.Error())
+			}
+			continue
+		}
+
+		validationErr, ok := err.(*ValidationError)
+		if !ok || validationErr.Field != field {
+			t.Errorf("expected invalid %s, got: %v", field, err)
+		}
+	}
+}
+
+// Test inserting, updating, and deleting books.
+func TestWrite(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	// Insert a new edition of an existing work.
+	book, err := Insert(searchIn, &Book{
+		Title:         "The Hitchhiker's Guide to the Galaxy (Hitchhiker's Guide to the Galaxy  #1)",
+		Authors:       "Douglas Adams",
+		AverageRating: 4.2,
+		ISBN:          "345391802",
+		ISBN13:        "9780345391803",
+		LanguageCode:  "eng",
+		Pages:         224,
+		RatingsCount:  100,
+		ReviewsCount:  10,
+	})
+	if err != nil {
+		t.Fatalf("insert failed: %s", err.Error())
+	}
+	if book.ID != 3589 || book.WorkID != 14 || book.Series != "Hitchhiker's Guide to the Galaxy" || book.SeriesIndex != "1" {
+		t.Errorf("incorrect inserted book: %v", *book)
+	}
+
+	if _, err := Insert(searchIn, &Book{
+		ID:           book.ID,
+		Title:        "Taken",
+		Authors:      "Someone",
+		ISBN:         "1",
+		ISBN13:       "1234567890123",
+		LanguageCode: "eng",
+	}); err != ErrExists {
+		t.Errorf("expected: %v, got: %v", ErrExists, err)
+	}
+
+	// Rename the first edition of a work, so that it belongs to another work.
+	book14, err := SearchByID(searchIn, 14)
+	if err != nil {
+		t.Fatalf("search failed: %s", err.Error())
+	}
+	book14.Title = "So Long, and Thanks for All the Fish (Hitchhiker's Guide to the Galaxy  #4)"
+	book14, err = Update(searchIn, book14)
+	if err != nil {
+		t.Fatalf("update failed: %s", err.Error())
+	}
+	if book14.WorkID != 14 || book14.SeriesIndex != "4" {
+		t.Errorf("incorrect updated book: %v", *book14)
+	}
+
+	for id, workID := range map[int]int{14: 14, 16: 16, book.ID: 16} {
+		edition, err := SearchByID(searchIn, id)
+		if err != nil {
+			t.Fatalf("search failed: %s", err.Error())
+		}
+		if edition.WorkID != workID {
+			t.Errorf("expected work: %d of %d, got: %d", workID, id, edition.WorkID)
+		}
+	}
+
+	if _, err := Update(searchIn, &Book{
+		ID:           30,
+		Title:        "Missing",
+		Authors:      "Someone",
+		ISBN:         "1",
+		ISBN13:       "1234567890123",
+		LanguageCode: "eng",
+	}); err != ErrNotFound {
+		t.Errorf("expected: %v, got: %v", ErrNotFound, err)
+	}
+
+	// Delete the first edition of a work.
+	if err := Delete(searchIn, 16); err != nil {
+		t.Fatalf("delete failed: %s", err.Error())
+	}
+	if _, err := SearchByID(searchIn, 16); err != ErrNotFound {
+		t.Errorf("expected: %v, got: %v", ErrNotFound, err)
+	}
+	if edition, err := SearchByID(searchIn, book.ID); err != nil || edition.WorkID != book.ID {
+		t.Errorf("expected work: %d of %d, got: %v (%v)", book.ID, book.ID, edition, err)
+	}
+
+	if err := Delete(searchIn, 16); err != ErrNotFound {
+		t.Errorf("expected: %v, got: %v", ErrNotFound, err)
+	}
+
+	// Only successful writes are recorded.
+	var revision string
+	if err := datastore.QueryRow("select value from metadata where key = 'revision';").Scan(&revision); err != nil {
+		t.Fatalf("failed to read revision: %s", err.Error())
+	}
+	if revision != "3" {
+		t.Errorf("expected revision: 3, got: %s", revision)
+	}
+}
+
+// Test that invalid books aren't written.
+func TestWriteInvalid(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	searchIn := &SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}
+
+	for i, write := range []func(*SearchIn, *Book) (*Book, error){Insert, Update} {
+		t.Run(
+			fmt.Sprintf("test: %d", i),
+			func(*testing.T) {
+				if _, err := write(searchIn, &Book{ID: 1}); err == nil {
+					t.Errorf("expected error")
+				}
+			},
+		)
+	}
+}
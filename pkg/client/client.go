@@ -0,0 +1,297 @@
+// Package client implements a client of bfr's API, used to search for books
+// from Go programs.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// Client makes requests to bfr's API. A Client is safe for concurrent use.
+type Client struct {
+	baseURL    string       // URL of the API, without a trailing slash.
+	httpClient *http.Client // Client used to make requests.
+	cfg        Config
+}
+
+// Config holds client's configuration options.
+type Config struct {
+	HTTPClient *http.Client // Client used to make requests, http.DefaultClient if nil.
+	Key        string       // API key sent with requests, none is sent if empty.
+
+	Timeout time.Duration // Maximum duration of each attempt of a request, no limit if 0.
+	Retries int           // Number of times a request is retried if it fails temporarily.
+	Backoff time.Duration // Time to wait before the first retry, doubled before each following one.
+
+	Prepare func(*http.Request)                                   // Called before each attempt is made, e.g. to add headers, ignored if nil.
+	Observe func(endpoint, status string, duration time.Duration) // Called after each attempt with its endpoint, e.g. "/v1/book/{id}", and status code, or "error" if it failed, ignored if nil.
+}
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int           // Status code of the response.
+	Message    string        // Message describing why the request failed.
+	RequestID  string        // ID of the request, used to find it in the API's logs.
+	RetryAfter time.Duration // Time to wait before retrying the request, 0 if unspecified.
+}
+
+// Error returns the status and message of the response.
+func (e *Error) Error() string {
+	return fmt.Sprintf("API responded with %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Temporary returns true if the request may succeed if retried, i.e. if it was
+// rate limited, or the API or a proxy in front of it is unavailable.
+func (e *Error) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// New returns a client of the API at baseURL, e.g. "http://localhost:6060".
+// cfg may be nil to use defaults.
+func New(baseURL string, cfg *Config) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %s", err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	if cfg != nil {
+		c.cfg = *cfg
+	}
+	if c.cfg.HTTPClient != nil {
+		c.httpClient = c.cfg.HTTPClient
+	}
+	return c, nil
+}
+
+// Search returns books that match all parameters of by, see books.Search.
+// Parameters are ignored as described by books.SearchBy, e.g. use
+// books.NewSearchBy to ignore numbers that aren't set.
+func (c *Client) Search(ctx context.Context, by *books.SearchBy) ([]*books.Book, error) {
+	var found []*books.Book
+	err := c.get(ctx, "/v1/books", "/v1/books", Query(by), &found)
+	return found, err
+}
+
+// Book returns the book with given ID.
+func (c *Client) Book(ctx context.Context, id int) (*books.Book, error) {
+	var book *books.Book
+	err := c.get(ctx, "/v1/book/{id}", fmt.Sprintf("/v1/book/%d", id), nil, &book)
+	return book, err
+}
+
+// ByTitle returns books with given title.
+func (c *Client) ByTitle(ctx context.Context, title string) ([]*books.Book, error) {
+	var found []*books.Book
+	err := c.get(ctx, "/v1/books/{title}", "/v1/books/"+url.PathEscape(title), nil, &found)
+	return found, err
+}
+
+// Similar returns at most limit books similar to the book with given ID, most
+// similar first. The API's default number of books is returned if limit is 0.
+func (c *Client) Similar(ctx context.Context, id int, limit int) ([]*books.Book, error) {
+	query := make(url.Values)
+	if limit != 0 {
+		query.Set("Limit", strconv.Itoa(limit))
+	}
+
+	var similar []*books.Book
+	err := c.get(ctx, "/v1/book/{id}/similar", fmt.Sprintf("/v1/book/%d/similar", id), query, &similar)
+	return similar, err
+}
+
+// Suggest returns at most limit completions of prefix of the given field,
+// either "title" or "author". The API's defaults are used for an empty field,
+// and a limit of 0.
+func (c *Client) Suggest(ctx context.Context, prefix, field string, limit int) ([]*books.Suggestion, error) {
+	query := make(url.Values)
+	query.Set("prefix", prefix)
+	if field != "" {
+		query.Set("field", field)
+	}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var suggestions []*books.Suggestion
+	err := c.get(ctx, "/v1/suggest", "/v1/suggest", query, &suggestions)
+	return suggestions, err
+}
+
+// Health returns an error if the API isn't healthy.
+func (c *Client) Health(ctx context.Context) error {
+	return c.get(ctx, "/healthz", "/healthz", nil, nil)
+}
+
+// Query returns query parameters of a search by given parameters. Empty
+// strings and lists, negative numbers, a WeightedRatingFloor of 0, and false
+// flags are ignored.
+func Query(by *books.SearchBy) url.Values {
+	query := make(url.Values)
+	value := reflect.ValueOf(by).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name, field := value.Type().Field(i).Name, value.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			if field.String() != "" {
+				query.Set(name, field.String())
+			}
+		case reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				query.Add(name, field.Index(j).String())
+			}
+		case reflect.Float32, reflect.Float64:
+			if field.Float() > 0 || field.Float() == 0 && name != "WeightedRatingFloor" {
+				query.Set(name, strconv.FormatFloat(field.Float(), 'f', -1, field.Type().Bits()))
+			}
+		case reflect.Int:
+			if field.Int() >= 0 {
+				query.Set(name, strconv.FormatInt(field.Int(), 10))
+			}
+		case reflect.Bool:
+			if field.Bool() {
+				query.Set(name, "true")
+			}
+		}
+	}
+	return query
+}
+
+// get makes a GET request to path, and decodes the response's JSON body into v,
+// unless v is nil. Requests that fail temporarily are retried as configured.
+// endpoint is the path's template, passed to Observe.
+func (c *Client) get(ctx context.Context, endpoint, path string, query url.Values, v interface{}) error {
+	target := c.baseURL + path
+	if len(query) != 0 {
+		target += "?" + query.Encode()
+	}
+
+	backoff := c.cfg.Backoff
+	for retries := 0; ; retries++ {
+		body, err := c.attempt(ctx, endpoint, target)
+		if err == nil {
+			if v == nil {
+				return nil
+			}
+			if err := json.Unmarshal(body, v); err != nil {
+				return fmt.Errorf("invalid response of %s: %s", endpoint, err.Error())
+			}
+			return nil
+		}
+
+		if retries >= c.cfg.Retries || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		wait := backoff
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err // Retrying would fail anyway.
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// attempt makes a GET request to target once, and returns the response's body,
+// or an error if the request failed, or the API responded with an error.
+func (c *Client) attempt(ctx context.Context, endpoint, target string) ([]byte, error) {
+	if c.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if c.cfg.Key != "" {
+		request.Header.Set("Authorization", "Bearer "+c.cfg.Key)
+	}
+	if c.cfg.Prepare != nil {
+		c.cfg.Prepare(request)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(request)
+	if c.cfg.Observe != nil {
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(resp.StatusCode)
+		}
+		c.cfg.Observe(endpoint, status, time.Since(start))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s: %s", endpoint, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError(resp, body)
+	}
+	return body, nil
+}
+
+// newError returns the Error of an error response with given body.
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
+
+// retryable returns true if a request that failed with err may succeed if
+// retried.
+func retryable(err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	return true // Failures to connect, or to read a response.
+}
@@ -0,0 +1,223 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sudo-sturbia/bfr/v2/internal/api"
+	"github.com/sudo-sturbia/bfr/v2/internal/testhelper"
+	"github.com/sudo-sturbia/bfr/v2/pkg/books"
+)
+
+// TestClient tests making requests to an API server.
+func TestClient(t *testing.T) {
+	datastore, bookTable, deferFn := testhelper.SearchIn(t)
+	defer deferFn()
+
+	server := httptest.NewServer(api.New(&api.Config{}, &api.SearchIn{
+		Datastore: datastore,
+		BookTable: bookTable,
+	}))
+	defer server.Close()
+
+	var mutex sync.Mutex
+	observed := make(map[string]string)
+	c, err := New(server.URL+"/", &Config{
+		Observe: func(endpoint, status string, duration time.Duration) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			observed[endpoint] = status
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err.Error())
+	}
+	ctx := context.Background()
+
+	book, err := c.Book(ctx, 14)
+	if err != nil {
+		t.Fatalf("failed to get book: %s", err.Error())
+	}
+	if book.ID != 14 || book.Authors != "Douglas Adams" {
+		t.Errorf("expected book 14 by Douglas Adams, got: %+v", *book)
+	}
+
+	titled, err := c.ByTitle(ctx, book.Title)
+	if err != nil {
+		t.Fatalf("failed to search by title: %s", err.Error())
+	}
+	for _, b := range titled {
+		if b.Title != book.Title {
+			t.Errorf("expected books titled %s, got: %s", book.Title, b.Title)
+		}
+	}
+	if len(titled) == 0 {
+		t.Errorf("expected books titled %s", book.Title)
+	}
+
+	by := books.NewSearchBy()
+	by.Authors = []string{"Douglas Adams"}
+	by.RatingFloor = 4.2
+	found, err := c.Search(ctx, by)
+	if err != nil {
+		t.Fatalf("failed to search: %s", err.Error())
+	}
+	if len(found) == 0 {
+		t.Errorf("expected books by Douglas Adams")
+	}
+	for _, b := range found {
+		if !strings.Contains(b.Authors, "Douglas Adams") || b.AverageRating <= 4.2 {
+			t.Errorf("expected books by Douglas Adams rated higher than 4.2, got: %+v", *b)
+		}
+	}
+
+	similar, err := c.Similar(ctx, 14, 3)
+	if err != nil {
+		t.Fatalf("failed to get similar books: %s", err.Error())
+	}
+	if len(similar) != 3 {
+		t.Errorf("expected 3 similar books, got: %d", len(similar))
+	}
+
+	suggestions, err := c.Suggest(ctx, "harry", "title", 0)
+	if err != nil {
+		t.Fatalf("failed to get suggestions: %s", err.Error())
+	}
+	if len(suggestions) == 0 || !strings.HasPrefix(suggestions[0].Text, "Harry") {
+		t.Errorf("expected suggestions of Harry, got: %v", suggestions)
+	}
+
+	if err := c.Health(ctx); err != nil {
+		t.Errorf("expected API to be healthy, got: %s", err.Error())
+	}
+
+	_, err = c.Book(ctx, 30)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an API error, got: %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != "Search failed." || apiErr.RequestID == "" {
+		t.Errorf("expected a described 400 error with a request ID, got: %+v", *apiErr)
+	}
+
+	if observed["/v1/book/{id}"] != "400" || observed["/v1/books"] != "200" {
+		t.Errorf("expected requests to be observed, got: %v", observed)
+	}
+}
+
+// TestQuery tests encoding search parameters.
+func TestQuery(t *testing.T) {
+	by := books.NewSearchBy()
+	if query := Query(by); len(query) != 0 {
+		t.Errorf("expected no parameters, got: %v", query)
+	}
+
+	by.TitleHas = "Harry & Co"
+	by.Authors = []string{"J.K. Rowling", "Douglas Adams"}
+	by.RatingFloor = 4.1
+	by.PagesCeil = 0
+	by.SortBy = "Pages"
+	by.Descending = true
+	expected := url.Values{
+		"TitleHas":    {"Harry & Co"},
+		"Authors":     {"J.K. Rowling", "Douglas Adams"},
+		"RatingFloor": {"4.1"},
+		"PagesCeil":   {"0"},
+		"SortBy":      {"Pages"},
+		"Descending":  {"true"},
+	}
+	if query := Query(by); !reflect.DeepEqual(query, expected) {
+		t.Errorf("expected parameters: %v, got: %v", expected, query)
+	}
+}
+
+// TestRetries tests retrying requests that fail temporarily.
+func TestRetries(t *testing.T) {
+	for _, test := range []struct {
+		statuses []int // Statuses of consecutive responses.
+		retries  int
+		attempts int
+		ok       bool
+	}{
+		{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, retries: 2, attempts: 3, ok: true},
+		{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}, retries: 1, attempts: 2, ok: false},
+		{statuses: []int{http.StatusBadRequest, http.StatusOK}, retries: 2, attempts: 1, ok: false},
+		{statuses: []int{http.StatusBadGateway, http.StatusOK}, retries: 0, attempts: 1, ok: false},
+	} {
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := test.statuses[attempts]
+			attempts++
+			if status != http.StatusOK {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			w.Write([]byte("{}"))
+		}))
+
+		c, err := New(server.URL, &Config{Retries: test.retries, Backoff: time.Millisecond})
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err.Error())
+		}
+		_, err = c.Book(context.Background(), 1)
+		server.Close()
+
+		if (err == nil) != test.ok {
+			t.Errorf("%v: expected success: %t, got error: %v", test.statuses, test.ok, err)
+		}
+		if attempts != test.attempts {
+			t.Errorf("%v: expected %d attempts, got: %d", test.statuses, test.attempts, attempts)
+		}
+	}
+}
+
+// TestTimeout tests that attempts time out, and that retries stop once the
+// request's context is done.
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, &Config{Timeout: 10 * time.Millisecond, Retries: 100, Backoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.Book(ctx, 1); err == nil {
+		t.Errorf("expected request to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected retries to stop when the context is done, took: %s", elapsed)
+	}
+}
+
+// TestNew tests validating base URLs.
+func TestNew(t *testing.T) {
+	for baseURL, ok := range map[string]bool{
+		"http://localhost:6060":  true,
+		"https://books.example/": true,
+		"localhost:6060":         false,
+		"ftp://books.example":    false,
+		"http://%zz":             false,
+	} {
+		if _, err := New(baseURL, nil); (err == nil) != ok {
+			t.Errorf("%s: expected success: %t, got error: %v", baseURL, ok, err)
+		}
+	}
+}